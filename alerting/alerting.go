@@ -0,0 +1,168 @@
+// Package alerting delivers NetView alerts (monitor state changes, new devices) to
+// external destinations such as webhooks.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event types understood by notifiers and usable in per-destination filters.
+const (
+	EventHostDown     = "host_down"     // A monitored host stopped responding
+	EventHostUp       = "host_up"       // A monitored host is reachable again
+	EventNewDevice    = "new_device"    // A device not in the known-device inventory was found
+	EventPortsChanged = "ports_changed" // A host's set of open service ports changed
+	EventARPAnomaly   = "arp_anomaly"   // Suspicious IP/MAC binding: possible ARP spoofing or IP conflict
+	EventRogueDHCP    = "rogue_dhcp"    // A DHCP server not on the allowlist answered a discover
+	EventTest         = "test"          // Sent by the "send test" actions; always accepted
+)
+
+// Event is a single alert. JSON tags match the payload posted by the "json" webhook format.
+type Event struct {
+	Type               string    `json:"type"`
+	Timestamp          time.Time `json:"timestamp"`
+	IPAddress          string    `json:"ipAddress,omitempty"`
+	Hostname           string    `json:"hostname,omitempty"`
+	MACAddress         string    `json:"macAddress,omitempty"`
+	DeviceType         string    `json:"deviceType,omitempty"`
+	State              string    `json:"state,omitempty"`              // New state, e.g. "online" or "offline"
+	PreviousState      string    `json:"previousState,omitempty"`      // State before the transition
+	DurationSeconds    float64   `json:"durationSeconds,omitempty"`    // Time spent in the previous state, if known
+	OpenedPorts        []int     `json:"openedPorts,omitempty"`        // Ports found newly open (ports_changed)
+	ClosedPorts        []int     `json:"closedPorts,omitempty"`        // Ports found newly closed (ports_changed)
+	Anomaly            string    `json:"anomaly,omitempty"`            // Kind of ARP anomaly, e.g. "mac_changed" (arp_anomaly)
+	PreviousMACAddress string    `json:"previousMacAddress,omitempty"` // MAC previously bound to the IP (arp_anomaly)
+	Message            string    `json:"message"`                      // Human readable one-line summary
+}
+
+// HostLabel returns the hostname and IP for display, e.g. "nas.local (192.168.1.20)".
+func (e Event) HostLabel() string {
+	if e.Hostname != "" && e.IPAddress != "" {
+		return fmt.Sprintf("%s (%s)", e.Hostname, e.IPAddress)
+	}
+	if e.Hostname != "" {
+		return e.Hostname
+	}
+	return e.IPAddress
+}
+
+// Title returns a short headline for the event, used as a subject or notification title.
+func (e Event) Title() string {
+	switch e.Type {
+	case EventHostDown:
+		return fmt.Sprintf("%s is offline", e.HostLabel())
+	case EventHostUp:
+		return fmt.Sprintf("%s is back online", e.HostLabel())
+	case EventNewDevice:
+		return fmt.Sprintf("New device detected: %s", e.HostLabel())
+	case EventPortsChanged:
+		return fmt.Sprintf("Open ports changed on %s", e.HostLabel())
+	case EventARPAnomaly:
+		return fmt.Sprintf("ARP anomaly for %s", e.HostLabel())
+	case EventRogueDHCP:
+		return fmt.Sprintf("Rogue DHCP server: %s", e.HostLabel())
+	case EventTest:
+		return "NetView test alert"
+	default:
+		return fmt.Sprintf("NetView alert: %s", e.Type)
+	}
+}
+
+// Notifier delivers events to one destination.
+type Notifier interface {
+	// Name identifies the destination in logs.
+	Name() string
+	// Accepts reports whether the destination wants events of the given type.
+	Accepts(eventType string) bool
+	// Notify delivers the event, retrying as the destination sees fit.
+	Notify(ctx context.Context, event Event) error
+}
+
+// Logger receives diagnostic messages from the dispatcher.
+type Logger func(format string, args ...any)
+
+// Dispatcher fans events out to the configured notifiers. Delivery is asynchronous so a slow
+// destination never blocks the monitor.
+type Dispatcher struct {
+	mu        sync.RWMutex
+	notifiers []Notifier
+	logf      Logger
+	wg        sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher. logf may be nil.
+func NewDispatcher(logf Logger) *Dispatcher {
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	return &Dispatcher{logf: logf}
+}
+
+// SetNotifiers replaces the set of destinations. Replaced notifiers that buffer events
+// (implementing Close) are closed in the background so queued events are not lost.
+func (d *Dispatcher) SetNotifiers(notifiers []Notifier) {
+	d.mu.Lock()
+	old := d.notifiers
+	d.notifiers = append([]Notifier(nil), notifiers...)
+	d.mu.Unlock()
+
+	for _, n := range old {
+		if c, ok := n.(interface{ Close() error }); ok {
+			d.wg.Add(1)
+			go func() {
+				defer d.wg.Done()
+				_ = c.Close()
+			}()
+		}
+	}
+}
+
+// Dispatch sends the event to every notifier that accepts it.
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.Message == "" {
+		event.Message = event.Title()
+	}
+
+	d.mu.RLock()
+	notifiers := d.notifiers
+	d.mu.RUnlock()
+
+	for _, n := range notifiers {
+		if event.Type != EventTest && !n.Accepts(event.Type) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(n Notifier) {
+			defer d.wg.Done()
+			if err := n.Notify(ctx, event); err != nil {
+				d.logf("Alert %s to %s failed: %v", event.Type, n.Name(), err)
+				return
+			}
+			d.logf("Alert %s delivered to %s", event.Type, n.Name())
+		}(n)
+	}
+}
+
+// Wait blocks until all in-flight deliveries have finished.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// acceptsEvent implements the shared per-destination filter: an empty filter accepts everything.
+func acceptsEvent(filter []string, eventType string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if f == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDesktopNotificationsUnsupported is returned on platforms without a native notification backend.
+var ErrDesktopNotificationsUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+const (
+	defaultDesktopHostInterval = 60 * time.Second // Minimum gap between notifications for the same host
+	defaultDesktopMaxPerMinute = 10               // Upper bound across all hosts
+)
+
+// DesktopConfig describes native OS notifications raised directly by the backend.
+type DesktopConfig struct {
+	Enabled             bool        `json:"enabled"`
+	Events              []string    `json:"events,omitempty"`    // Event types to show; empty shows all
+	HostIntervalSeconds int         `json:"hostIntervalSeconds"` // Per-host rate limit; 0 uses the default
+	MaxPerMinute        int         `json:"maxPerMinute"`        // Global rate limit; 0 uses the default
+	DoNotDisturb        DNDSchedule `json:"doNotDisturb"`
+}
+
+// DNDSchedule silences desktop notifications during a daily time window.
+type DNDSchedule struct {
+	Enabled bool   `json:"enabled"`
+	Start   string `json:"start"`          // Local time "HH:MM"
+	End     string `json:"end"`            // Local time "HH:MM"; may be earlier than Start to span midnight
+	Days    []int  `json:"days,omitempty"` // Days the window starts on (0 = Sunday); empty means every day
+}
+
+// ValidateDesktopConfig checks the do-not-disturb schedule and rate limits.
+func ValidateDesktopConfig(config DesktopConfig) error {
+	if config.HostIntervalSeconds < 0 || config.MaxPerMinute < 0 {
+		return errors.New("desktop notifications: rate limits cannot be negative")
+	}
+	if !config.DoNotDisturb.Enabled {
+		return nil
+	}
+	if _, err := parseClock(config.DoNotDisturb.Start); err != nil {
+		return fmt.Errorf("desktop notifications: do-not-disturb start: %w", err)
+	}
+	if _, err := parseClock(config.DoNotDisturb.End); err != nil {
+		return fmt.Errorf("desktop notifications: do-not-disturb end: %w", err)
+	}
+	for _, d := range config.DoNotDisturb.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("desktop notifications: invalid day %d", d)
+		}
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	hour, errH := strconv.Atoi(h)
+	minute, errM := strconv.Atoi(m)
+	if errH != nil || errM != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Active reports whether t falls inside the do-not-disturb window.
+func (s DNDSchedule) Active(t time.Time) bool {
+	if !s.Enabled {
+		return false
+	}
+	start, errS := parseClock(s.Start)
+	end, errE := parseClock(s.End)
+	if errS != nil || errE != nil || start == end {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end && s.onDay(t.Weekday())
+	}
+	// The window spans midnight: the part after midnight belongs to the previous day's window.
+	if minute >= start {
+		return s.onDay(t.Weekday())
+	}
+	if minute < end {
+		return s.onDay((t.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// onDay reports whether the window starts on the given weekday.
+func (s DNDSchedule) onDay(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// DesktopNotifier raises native OS notifications, rate limited and silenced during
+// the do-not-disturb window.
+type DesktopNotifier struct {
+	config DesktopConfig
+	show   func(title, body string, urgent bool) error
+	now    func() time.Time
+
+	mu         sync.Mutex
+	lastByHost map[string]time.Time
+	recent     []time.Time // Notification times within the last minute
+	suppressed int         // Notifications dropped by the rate limits since the last one shown
+}
+
+// NewDesktopNotifier validates the configuration and builds a notifier that uses the
+// platform's notification service.
+func NewDesktopNotifier(config DesktopConfig) (*DesktopNotifier, error) {
+	if err := ValidateDesktopConfig(config); err != nil {
+		return nil, err
+	}
+	return &DesktopNotifier{
+		config:     config,
+		show:       showDesktopNotification,
+		now:        time.Now,
+		lastByHost: make(map[string]time.Time),
+	}, nil
+}
+
+// Name implements Notifier.
+func (d *DesktopNotifier) Name() string {
+	return "desktop"
+}
+
+// Accepts implements Notifier.
+func (d *DesktopNotifier) Accepts(eventType string) bool {
+	return acceptsEvent(d.config.Events, eventType)
+}
+
+// Notify implements Notifier. Notifications silenced by do-not-disturb or dropped by the
+// rate limits are not errors.
+func (d *DesktopNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != EventTest && !d.allow(event) {
+		return nil
+	}
+
+	body := desktopBody(event)
+	d.mu.Lock()
+	if d.suppressed > 0 {
+		body += fmt.Sprintf("\n(%d more alert(s) were rate limited)", d.suppressed)
+		d.suppressed = 0
+	}
+	d.mu.Unlock()
+	return d.show(event.Title(), body, event.Type == EventHostDown)
+}
+
+// allow applies the do-not-disturb schedule and the rate limits, recording the notification if allowed.
+func (d *DesktopNotifier) allow(event Event) bool {
+	now := d.now()
+	if d.config.DoNotDisturb.Active(now) {
+		return false
+	}
+
+	hostInterval := defaultDesktopHostInterval
+	if d.config.HostIntervalSeconds > 0 {
+		hostInterval = time.Duration(d.config.HostIntervalSeconds) * time.Second
+	}
+	maxPerMinute := defaultDesktopMaxPerMinute
+	if d.config.MaxPerMinute > 0 {
+		maxPerMinute = d.config.MaxPerMinute
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Drop notification times older than a minute from the global window
+	kept := d.recent[:0]
+	for _, t := range d.recent {
+		if now.Sub(t) < time.Minute {
+			kept = append(kept, t)
+		}
+	}
+	d.recent = kept
+
+	key := event.IPAddress
+	if last, ok := d.lastByHost[key]; ok && key != "" && now.Sub(last) < hostInterval {
+		d.suppressed++
+		return false
+	}
+	if len(d.recent) >= maxPerMinute {
+		d.suppressed++
+		return false
+	}
+	d.lastByHost[key] = now
+	d.recent = append(d.recent, now)
+	return true
+}
+
+// desktopBody formats the notification text: host, state and how long it was offline,
+// or the ports that changed.
+func desktopBody(event Event) string {
+	if event.Type == EventTest {
+		return event.Message
+	}
+	var lines []string
+	if event.HostLabel() != "" {
+		lines = append(lines, "Host: "+event.HostLabel())
+	}
+	if event.State != "" {
+		lines = append(lines, "State: "+event.State)
+	}
+	if event.Type == EventHostUp && event.DurationSeconds > 0 {
+		offline := time.Duration(event.DurationSeconds * float64(time.Second)).Round(time.Second)
+		lines = append(lines, "Offline for: "+offline.String())
+	}
+	if len(event.OpenedPorts) > 0 {
+		lines = append(lines, "Opened: "+joinPorts(event.OpenedPorts))
+	}
+	if len(event.ClosedPorts) > 0 {
+		lines = append(lines, "Closed: "+joinPorts(event.ClosedPorts))
+	}
+	if len(lines) == 0 {
+		return event.Message
+	}
+	return strings.Join(lines, "\n")
+}
+
+// joinPorts formats a port list as "22, 3389".
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ", ")
+}
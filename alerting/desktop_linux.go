@@ -0,0 +1,50 @@
+//go:build linux
+
+package alerting
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsService = "org.freedesktop.Notifications"
+	notificationsPath    = "/org/freedesktop/Notifications"
+	notificationsMethod  = notificationsService + ".Notify"
+)
+
+// showDesktopNotification raises a notification through the freedesktop notification
+// service on the session D-Bus.
+func showDesktopNotification(title, body string, urgent bool) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("connecting to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	urgency := byte(1) // normal
+	if urgent {
+		urgency = 2 // critical
+	}
+	hints := map[string]dbus.Variant{
+		"urgency":       dbus.MakeVariant(urgency),
+		"desktop-entry": dbus.MakeVariant("netview"),
+	}
+
+	obj := conn.Object(notificationsService, notificationsPath)
+	call := obj.Call(notificationsMethod, 0,
+		"NetView",       // app_name
+		uint32(0),       // replaces_id
+		"network-wired", // app_icon
+		title,           // summary
+		body,            // body
+		[]string{},      // actions
+		hints,           // hints
+		int32(-1),       // expire_timeout: server default
+	)
+	if call.Err != nil {
+		return fmt.Errorf("calling %s: %w", notificationsMethod, call.Err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package alerting
+
+// showDesktopNotification is not implemented on this platform yet.
+func showDesktopNotification(title, body string, urgent bool) error {
+	return ErrDesktopNotificationsUnsupported
+}
@@ -0,0 +1,151 @@
+package alerting
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDNDScheduleActive(t *testing.T) {
+	at := func(day, hour, minute int) time.Time { // October 2026; the 16th is a Friday
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+	}
+	daytime := DNDSchedule{Enabled: true, Start: "09:00", End: "17:30"}
+	overnight := DNDSchedule{Enabled: true, Start: "22:00", End: "07:00"}
+	fridayNight := DNDSchedule{Enabled: true, Start: "22:00", End: "07:00", Days: []int{int(time.Friday)}}
+
+	for _, c := range []struct {
+		name     string
+		schedule DNDSchedule
+		t        time.Time
+		want     bool
+	}{
+		{"before the window", daytime, at(16, 8, 59), false},
+		{"start is inclusive", daytime, at(16, 9, 0), true},
+		{"inside", daytime, at(16, 12, 0), true},
+		{"end is exclusive", daytime, at(16, 17, 30), false},
+		{"disabled", DNDSchedule{Start: "09:00", End: "17:30"}, at(16, 12, 0), false},
+		{"empty window", DNDSchedule{Enabled: true, Start: "09:00", End: "09:00"}, at(16, 9, 0), false},
+		{"unreadable times", DNDSchedule{Enabled: true, Start: "9am", End: "17:30"}, at(16, 12, 0), false},
+
+		{"evening before an overnight window", overnight, at(16, 21, 59), false},
+		{"overnight start", overnight, at(16, 22, 0), true},
+		{"just before midnight", overnight, at(16, 23, 59), true},
+		{"midnight", overnight, at(17, 0, 0), true},
+		{"just before the overnight end", overnight, at(17, 6, 59), true},
+		{"overnight end", overnight, at(17, 7, 0), false},
+		{"afternoon between overnight windows", overnight, at(17, 15, 0), false},
+
+		{"window starting on a listed day", fridayNight, at(16, 23, 0), true},
+		{"after midnight counts for the day the window started", fridayNight, at(17, 3, 0), true},
+		{"morning of a listed day belongs to the day before", fridayNight, at(16, 3, 0), false},
+		{"evening of an unlisted day", fridayNight, at(17, 23, 0), false},
+	} {
+		if got := c.schedule.Active(c.t); got != c.want {
+			t.Errorf("%s: Active(%s) = %v, want %v", c.name, c.t.Format("Mon 15:04"), got, c.want)
+		}
+	}
+}
+
+// shownNotification is one call to the notifier's show function.
+type shownNotification struct {
+	title, body string
+	urgent      bool
+}
+
+// newTestDesktop returns a notifier for config that records what it shows and reads the time from *now.
+func newTestDesktop(t *testing.T, config DesktopConfig, now *time.Time) (*DesktopNotifier, *[]shownNotification) {
+	t.Helper()
+	config.Enabled = true
+	d, err := NewDesktopNotifier(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shown []shownNotification
+	d.show = func(title, body string, urgent bool) error {
+		shown = append(shown, shownNotification{title, body, urgent})
+		return nil
+	}
+	d.now = func() time.Time { return *now }
+	return d, &shown
+}
+
+func TestDesktopRateLimits(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	d, shown := newTestDesktop(t, DesktopConfig{HostIntervalSeconds: 30, MaxPerMinute: 3}, &now)
+	notify := func(ip string) {
+		t.Helper()
+		if err := d.Notify(context.Background(), Event{Type: EventHostDown, IPAddress: ip}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notify("10.0.0.1")
+	notify("10.0.0.1") // Same host within the interval
+	notify("10.0.0.2")
+	notify("10.0.0.3")
+	notify("10.0.0.4") // Over the global limit
+	if len(*shown) != 3 {
+		t.Fatalf("%d notifications shown, want 3", len(*shown))
+	}
+	// The next notification shown reports what was dropped before it
+	if !(*shown)[0].urgent || !strings.HasSuffix((*shown)[1].body, "\n(1 more alert(s) were rate limited)") ||
+		strings.Contains((*shown)[2].body, "rate limited") {
+		t.Errorf("notifications = %+v", *shown)
+	}
+
+	// A minute later the global window has room again
+	now = now.Add(time.Minute)
+	notify("10.0.0.1")
+	if len(*shown) != 4 {
+		t.Fatalf("%d notifications shown after recovery, want 4", len(*shown))
+	}
+	if got := (*shown)[3].body; !strings.HasSuffix(got, "\n(1 more alert(s) were rate limited)") || !strings.HasPrefix(got, "Host: 10.0.0.1") {
+		t.Errorf("recovered notification body = %q", got)
+	}
+	now = now.Add(time.Second)
+	notify("10.0.0.5")
+	if got := (*shown)[4].body; strings.Contains(got, "rate limited") {
+		t.Errorf("suppressed count reported twice: %q", got)
+	}
+
+	// The per-host interval runs from the last notification shown for that host
+	now = now.Add(29 * time.Second)
+	notify("10.0.0.1")
+	now = now.Add(time.Second)
+	notify("10.0.0.1")
+	if len(*shown) != 6 {
+		t.Errorf("%d notifications shown, want the second one after the host interval", len(*shown))
+	}
+
+	// Test notifications bypass both limits and report what was dropped
+	notify("10.0.0.2")
+	notify("10.0.0.3")
+	if err := d.Notify(context.Background(), Event{Type: EventTest, Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*shown) != 7 || (*shown)[6].body != "hello\n(3 more alert(s) were rate limited)" {
+		t.Errorf("test notification not shown: %+v", (*shown)[6:])
+	}
+}
+
+func TestDesktopDoNotDisturb(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.Local)
+	d, shown := newTestDesktop(t, DesktopConfig{DoNotDisturb: DNDSchedule{Enabled: true, Start: "22:00", End: "07:00"}}, &now)
+
+	if err := d.Notify(context.Background(), Event{Type: EventHostDown, IPAddress: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*shown) != 0 {
+		t.Fatalf("shown during do-not-disturb: %+v", *shown)
+	}
+	// Silenced notifications neither count against the host interval nor get reported later
+	now = time.Date(2026, 10, 17, 7, 0, 0, 0, time.Local)
+	if err := d.Notify(context.Background(), Event{Type: EventHostDown, IPAddress: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*shown) != 1 || strings.Contains((*shown)[0].body, "rate limited") {
+		t.Errorf("after do-not-disturb: %+v", *shown)
+	}
+}
@@ -0,0 +1,345 @@
+package alerting
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTP connection security modes.
+const (
+	SMTPSecurityNone     = "none"     // Plain SMTP (auth only allowed to localhost)
+	SMTPSecuritySTARTTLS = "starttls" // Upgrade with STARTTLS, typically port 587
+	SMTPSecurityTLS      = "tls"      // Implicit TLS, typically port 465
+)
+
+const (
+	defaultDigestWindow = 60 * time.Second // Events arriving within this window share one mail
+	smtpTimeout         = 30 * time.Second
+)
+
+// SMTPConfig describes the email destination.
+type SMTPConfig struct {
+	Enabled            bool     `json:"enabled"`
+	Host               string   `json:"host"`
+	Port               int      `json:"port"`     // 0 picks the usual port for Security
+	Security           string   `json:"security"` // One of the SMTPSecurity constants; empty means starttls
+	Username           string   `json:"username,omitempty"`
+	Password           string   `json:"password,omitempty"`
+	From               string   `json:"from"`
+	To                 []string `json:"to"`
+	Events             []string `json:"events,omitempty"`        // Event types to send; empty sends all
+	DigestSeconds      int      `json:"digestSeconds"`           // Batching window; 0 uses the default, negative disables batching
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`      // Accept self-signed server certificates
+	SubjectPrefix      string   `json:"subjectPrefix,omitempty"` // Prepended to every subject, e.g. "[NetView]"
+}
+
+// security returns the configured security mode with the default applied.
+func (c SMTPConfig) security() string {
+	if c.Security == "" {
+		return SMTPSecuritySTARTTLS
+	}
+	return c.Security
+}
+
+// address returns host:port with the default port for the security mode applied.
+func (c SMTPConfig) address() string {
+	port := c.Port
+	if port == 0 {
+		switch c.security() {
+		case SMTPSecurityTLS:
+			port = 465
+		case SMTPSecuritySTARTTLS:
+			port = 587
+		default:
+			port = 25
+		}
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// digestWindow returns how long events are collected before a mail is sent.
+func (c SMTPConfig) digestWindow() time.Duration {
+	switch {
+	case c.DigestSeconds < 0:
+		return 0
+	case c.DigestSeconds == 0:
+		return defaultDigestWindow
+	default:
+		return time.Duration(c.DigestSeconds) * time.Second
+	}
+}
+
+// ValidateSMTPConfig checks that the email destination is usable.
+func ValidateSMTPConfig(config SMTPConfig) error {
+	if strings.TrimSpace(config.Host) == "" {
+		return errors.New("email: SMTP host is required")
+	}
+	if config.Port < 0 || config.Port > 65535 {
+		return fmt.Errorf("email: invalid SMTP port %d", config.Port)
+	}
+	switch config.security() {
+	case SMTPSecurityNone, SMTPSecuritySTARTTLS, SMTPSecurityTLS:
+	default:
+		return fmt.Errorf("email: unknown security mode %q", config.Security)
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return fmt.Errorf("email: invalid sender %q: %w", config.From, err)
+	}
+	if strings.ContainsAny(config.SubjectPrefix, "\r\n") {
+		return errors.New("email: subject prefix must be a single line")
+	}
+	if len(config.To) == 0 {
+		return errors.New("email: at least one recipient is required")
+	}
+	for _, to := range config.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("email: invalid recipient %q: %w", to, err)
+		}
+	}
+	return nil
+}
+
+// EmailNotifier sends alerts by email. Events arriving within the digest window are batched
+// into a single message, so a switch reboot produces one mail rather than forty.
+type EmailNotifier struct {
+	config SMTPConfig
+	logf   Logger
+
+	mu      sync.Mutex
+	pending []Event
+	timer   *time.Timer
+}
+
+// NewEmailNotifier validates the configuration and builds a notifier for it. logf receives
+// delivery errors of batched mails and may be nil.
+func NewEmailNotifier(config SMTPConfig, logf Logger) (*EmailNotifier, error) {
+	if err := ValidateSMTPConfig(config); err != nil {
+		return nil, err
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	return &EmailNotifier{config: config, logf: logf}, nil
+}
+
+// Name implements Notifier.
+func (e *EmailNotifier) Name() string {
+	return "email " + strings.Join(e.config.To, ",")
+}
+
+// Accepts implements Notifier.
+func (e *EmailNotifier) Accepts(eventType string) bool {
+	return acceptsEvent(e.config.Events, eventType)
+}
+
+// Notify implements Notifier. Test events and events with batching disabled are sent right
+// away; everything else is queued until the digest window closes.
+func (e *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	window := e.config.digestWindow()
+	if event.Type == EventTest || window == 0 {
+		return e.send(ctx, []Event{event})
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, event)
+	if e.timer == nil {
+		e.timer = time.AfterFunc(window, e.flushPending)
+	}
+	return nil
+}
+
+// Close sends any queued events immediately. It is called when the notifier is replaced.
+func (e *EmailNotifier) Close() error {
+	e.mu.Lock()
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.mu.Unlock()
+	e.flushPending()
+	return nil
+}
+
+// flushPending sends the queued events as one digest mail.
+func (e *EmailNotifier) flushPending() {
+	e.mu.Lock()
+	events := e.pending
+	e.pending = nil
+	e.timer = nil
+	e.mu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), smtpTimeout)
+	defer cancel()
+	if err := e.send(ctx, events); err != nil {
+		e.logf("Alert digest of %d event(s) to %s failed: %v", len(events), e.Name(), err)
+		return
+	}
+	e.logf("Alert digest of %d event(s) delivered to %s", len(events), e.Name())
+}
+
+// send delivers one mail containing the given events.
+func (e *EmailNotifier) send(ctx context.Context, events []Event) error {
+	subject, body := composeDigest(events)
+	if e.config.SubjectPrefix != "" {
+		subject = e.config.SubjectPrefix + " " + subject
+	}
+	msg, err := buildMessage(e.config, subject, body)
+	if err != nil {
+		return err
+	}
+	return sendMail(ctx, e.config, msg)
+}
+
+// composeDigest returns the subject and plain-text body for a batch of events.
+func composeDigest(events []Event) (string, string) {
+	if len(events) == 1 {
+		ev := events[0]
+		return ev.Title(), fmt.Sprintf("%s\r\n\r\nTime: %s\r\n", ev.Message, ev.Timestamp.Format(time.RFC1123))
+	}
+
+	down, up := 0, 0
+	for _, ev := range events {
+		switch ev.Type {
+		case EventHostDown:
+			down++
+		case EventHostUp:
+			up++
+		}
+	}
+	subject := fmt.Sprintf("NetView: %d alerts (%d offline, %d recovered)", len(events), down, up)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d alerts between %s and %s:\r\n\r\n", len(events),
+		events[0].Timestamp.Format(time.Kitchen), events[len(events)-1].Timestamp.Format(time.Kitchen))
+	for _, ev := range events {
+		fmt.Fprintf(&b, "  %s  %s\r\n", ev.Timestamp.Format("15:04:05"), ev.Message)
+	}
+	return subject, b.String()
+}
+
+// buildMessage renders an RFC 5322 message with the given subject and body.
+func buildMessage(config SMTPConfig, subject, body string) ([]byte, error) {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, err
+	}
+	idBytes := make([]byte, 12)
+	_, _ = rand.Read(idBytes)
+	domain := "netview.local"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mimeHeader(singleLine(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(idBytes), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return []byte(b.String()), nil
+}
+
+// singleLine replaces line breaks with spaces. Subjects include hostnames learned from the
+// network, which must not be able to start a header of their own.
+func singleLine(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
+
+// mimeHeader encodes a header value if it contains non-ASCII characters.
+func mimeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.QEncoding.Encode("UTF-8", s)
+		}
+	}
+	return s
+}
+
+// sendMail connects to the SMTP server using the configured security mode and sends msg.
+func sendMail(ctx context.Context, config SMTPConfig, msg []byte) error {
+	addr := config.address()
+	tlsConfig := &tls.Config{ServerName: config.Host, InsecureSkipVerify: config.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if config.security() == SMTPSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if config.security() == SMTPSecuritySTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+	}
+	if config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("%s does not support authentication", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
+			return fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(config.From)
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("MAIL FROM: %w", err)
+	}
+	for _, to := range config.To {
+		rcpt, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("RCPT TO %s: %w", rcpt.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finishing message: %w", err)
+	}
+	return client.Quit()
+}
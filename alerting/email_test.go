@@ -0,0 +1,251 @@
+package alerting
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpMessage is a mail received by the fake SMTP server.
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer is a minimal plain-text SMTP server that accepts every mail it is given.
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config returns an SMTP configuration that delivers to the server.
+func (s *fakeSMTPServer) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return SMTPConfig{
+		Enabled:  true,
+		Host:     host,
+		Port:     portNumber,
+		Security: SMTPSecurityNone,
+		From:     "NetView <netview@example.com>",
+		To:       []string{"admin@example.com", "Ops <ops@example.com>"},
+	}
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP fake")
+
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			msg = smtpMessage{From: strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")}
+			reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK: queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+// parse reads a received mail back as an RFC 5322 message.
+func (m smtpMessage) parse(t *testing.T) *mail.Message {
+	t.Helper()
+	parsed, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		t.Fatalf("message %q: %v", m.Data, err)
+	}
+	return parsed
+}
+
+func TestEmailDelivery(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	config := server.config()
+	config.SubjectPrefix = "[NetView]"
+	n, err := NewEmailNotifier(config, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), Event{Type: EventTest, Timestamp: time.Now(), Message: "Test alert from NetView"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("%d mails received, want 1", len(messages))
+	}
+	m := messages[0]
+	if m.From != "netview@example.com" || strings.Join(m.To, ",") != "admin@example.com,ops@example.com" {
+		t.Errorf("envelope = %s -> %v", m.From, m.To)
+	}
+	parsed := m.parse(t)
+	if got := parsed.Header.Get("Subject"); !strings.HasPrefix(got, "[NetView] ") {
+		t.Errorf("subject = %q", got)
+	}
+	if got := parsed.Header.Get("To"); got != "admin@example.com, Ops <ops@example.com>" {
+		t.Errorf("To header = %q", got)
+	}
+	if !strings.HasSuffix(parsed.Header.Get("Message-Id"), "@example.com>") {
+		t.Errorf("Message-ID = %q", parsed.Header.Get("Message-Id"))
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	n, err := NewEmailNotifier(server.config(), t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, ev := range []Event{
+		{Type: EventHostDown, IPAddress: "192.168.1.20", Message: "192.168.1.20 is offline"},
+		{Type: EventHostDown, IPAddress: "192.168.1.21", Message: "192.168.1.21 is offline"},
+		{Type: EventHostUp, IPAddress: "192.168.1.20", Message: "192.168.1.20 is back online"},
+	} {
+		ev.Timestamp = start.Add(time.Duration(i) * time.Second)
+		if err := n.Notify(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(server.received()); n != 0 {
+		t.Fatalf("%d mails sent before the digest window closed", n)
+	}
+
+	// Closing the notifier sends what is queued
+	n.Close()
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("%d mails received, want 1 digest", len(messages))
+	}
+	parsed := messages[0].parse(t)
+	if got := parsed.Header.Get("Subject"); got != "NetView: 3 alerts (2 offline, 1 recovered)" {
+		t.Errorf("subject = %q", got)
+	}
+	body := messages[0].Data
+	for _, want := range []string{"12:00:00  192.168.1.20 is offline", "12:00:01  192.168.1.21 is offline", "12:00:02  192.168.1.20 is back online"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("digest body lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestEmailSubjectHeaderInjection(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	config := server.config()
+	config.DigestSeconds = -1
+	n, err := NewEmailNotifier(config, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A hostname announced by a device on the network
+	hostname := "printer\r\nBcc: victim@example.net\r\n"
+	if err := n.Notify(context.Background(), Event{Type: EventNewDevice, IPAddress: "192.168.1.50", Hostname: hostname, Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("%d mails received, want 1", len(messages))
+	}
+	parsed := messages[0].parse(t)
+	if bcc := parsed.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("hostname injected a Bcc header: %q", bcc)
+	}
+	if got := parsed.Header.Get("Subject"); strings.ContainsAny(got, "\r\n") || !strings.Contains(got, "printer Bcc: victim@example.net") {
+		t.Errorf("subject = %q", got)
+	}
+}
+
+func TestValidateSMTPConfig(t *testing.T) {
+	valid := SMTPConfig{Host: "smtp.example.com", From: "netview@example.com", To: []string{"admin@example.com"}}
+	if err := ValidateSMTPConfig(valid); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	for name, change := range map[string]func(*SMTPConfig){
+		"no host":           func(c *SMTPConfig) { c.Host = " " },
+		"bad port":          func(c *SMTPConfig) { c.Port = 70000 },
+		"bad security":      func(c *SMTPConfig) { c.Security = "ssl3" },
+		"bad sender":        func(c *SMTPConfig) { c.From = "netview" },
+		"no recipients":     func(c *SMTPConfig) { c.To = nil },
+		"bad recipient":     func(c *SMTPConfig) { c.To = []string{"admin@example.com", "ops"} },
+		"CR in prefix":      func(c *SMTPConfig) { c.SubjectPrefix = "[NetView]\rBcc: x@example.net" },
+		"newline in prefix": func(c *SMTPConfig) { c.SubjectPrefix = "[NetView]\nBcc: x@example.net" },
+	} {
+		config := valid
+		change(&config)
+		if err := ValidateSMTPConfig(config); err == nil {
+			t.Errorf("%s: config accepted", name)
+		}
+	}
+}
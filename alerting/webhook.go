@@ -0,0 +1,214 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Built-in webhook payload formats.
+const (
+	WebhookFormatJSON    = "json"    // The Event itself, as JSON
+	WebhookFormatSlack   = "slack"   // Slack incoming webhook ({"text": ...})
+	WebhookFormatDiscord = "discord" // Discord webhook ({"content": ...})
+	WebhookFormatTeams   = "teams"   // Microsoft Teams connector MessageCard
+	WebhookFormatCustom  = "custom"  // User supplied text/template in Template
+)
+
+const (
+	defaultWebhookRetries = 3
+	defaultWebhookTimeout = 10 * time.Second
+	webhookInitialBackoff = time.Second
+	webhookMaxBackoff     = 30 * time.Second
+)
+
+// builtinWebhookTemplates are the payload templates for the non-custom formats.
+// They receive the Event as data; the json function JSON-encodes a value.
+var builtinWebhookTemplates = map[string]string{
+	WebhookFormatJSON:    `{{json .}}`,
+	WebhookFormatSlack:   `{"text": {{json .Message}}}`,
+	WebhookFormatDiscord: `{"content": {{json .Message}}}`,
+	WebhookFormatTeams:   `{"@type": "MessageCard", "@context": "https://schema.org/extensions", "summary": {{json .Title}}, "themeColor": {{if eq .Type "host_down"}}"D13438"{{else}}"2EB886"{{end}}, "title": {{json .Title}}, "text": {{json .Message}}}`,
+}
+
+// WebhookConfig describes one webhook destination.
+type WebhookConfig struct {
+	Name       string            `json:"name"`
+	Enabled    bool              `json:"enabled"`
+	URL        string            `json:"url"`
+	Format     string            `json:"format"`               // One of the WebhookFormat constants; empty means json
+	Template   string            `json:"template,omitempty"`   // text/template for the custom format
+	Headers    map[string]string `json:"headers,omitempty"`    // Extra request headers, e.g. Authorization
+	Events     []string          `json:"events,omitempty"`     // Event types to send; empty sends all
+	MaxRetries *int              `json:"maxRetries,omitempty"` // Retries after the first attempt; nil uses the default, 0 disables retries
+}
+
+// WebhookNotifier posts events as JSON to a webhook URL.
+type WebhookNotifier struct {
+	config   WebhookConfig
+	template *template.Template
+	client   *http.Client
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// NewWebhookNotifier validates the configuration and builds a notifier for it.
+// client may be nil to use a client with a sensible timeout.
+func NewWebhookNotifier(config WebhookConfig, client *http.Client) (*WebhookNotifier, error) {
+	if err := ValidateWebhookConfig(config); err != nil {
+		return nil, err
+	}
+	tmpl, err := parseWebhookTemplate(config)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	return &WebhookNotifier{config: config, template: tmpl, client: client, sleep: sleepContext}, nil
+}
+
+// ValidateWebhookConfig checks the URL, format and template of a webhook configuration.
+func ValidateWebhookConfig(config WebhookConfig) error {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook %q: invalid URL %q", config.Name, config.URL)
+	}
+	if config.MaxRetries != nil && *config.MaxRetries < 0 {
+		return fmt.Errorf("webhook %q: retries must not be negative", config.Name)
+	}
+	if _, err := parseWebhookTemplate(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseWebhookTemplate returns the payload template for the configured format.
+func parseWebhookTemplate(config WebhookConfig) (*template.Template, error) {
+	format := config.Format
+	if format == "" {
+		format = WebhookFormatJSON
+	}
+	text, ok := builtinWebhookTemplates[format]
+	if format == WebhookFormatCustom {
+		if strings.TrimSpace(config.Template) == "" {
+			return nil, fmt.Errorf("webhook %q: custom format requires a template", config.Name)
+		}
+		text, ok = config.Template, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("webhook %q: unknown format %q", config.Name, config.Format)
+	}
+	tmpl, err := template.New(format).Funcs(template.FuncMap{"json": templateJSON}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %q: parsing template: %w", config.Name, err)
+	}
+	return tmpl, nil
+}
+
+// templateJSON JSON-encodes v for use inside payload templates.
+func templateJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// Name implements Notifier.
+func (w *WebhookNotifier) Name() string {
+	if w.config.Name != "" {
+		return "webhook " + w.config.Name
+	}
+	return "webhook " + w.config.URL
+}
+
+// Accepts implements Notifier.
+func (w *WebhookNotifier) Accepts(eventType string) bool {
+	return acceptsEvent(w.config.Events, eventType)
+}
+
+// Render builds the request body for the event.
+func (w *WebhookNotifier) Render(event Event) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("rendering payload: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("rendered payload is not valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// Notify implements Notifier. Network errors, 429 and 5xx responses are retried with
+// exponential backoff; other 4xx responses fail immediately.
+func (w *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := w.Render(event)
+	if err != nil {
+		return err
+	}
+
+	retries := defaultWebhookRetries
+	if w.config.MaxRetries != nil {
+		retries = *w.config.MaxRetries
+	}
+	backoff := webhookInitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= retries {
+			return fmt.Errorf("after %d attempt(s): %w", attempt+1, err)
+		}
+		if err := w.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying.
+func (w *WebhookNotifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NetView")
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
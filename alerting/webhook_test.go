@@ -0,0 +1,184 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer records the requests it receives and answers with the given statuses in turn,
+// repeating the last one.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	t.Helper()
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.headers = append(s.headers, r.Header.Clone())
+		status := s.statuses[min(len(s.bodies), len(s.statuses))-1]
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+// newTestWebhook returns a notifier for config whose backoff waits are recorded instead of slept.
+func newTestWebhook(t *testing.T, config WebhookConfig) (*WebhookNotifier, *[]time.Duration) {
+	t.Helper()
+	n, err := NewWebhookNotifier(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var waits []time.Duration
+	n.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return n, &waits
+}
+
+func intPtr(n int) *int { return &n }
+
+var testEvent = Event{
+	Type:      EventHostDown,
+	Timestamp: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	IPAddress: "192.168.1.20",
+	Hostname:  "nas.local",
+	State:     "offline",
+	Message:   `nas.local (192.168.1.20) is "offline"`,
+}
+
+func TestWebhookPayload(t *testing.T) {
+	server := newWebhookServer(t, http.StatusOK)
+	n, _ := newTestWebhook(t, WebhookConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer abc"}})
+	if err := n.Notify(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Event
+	if err := json.Unmarshal([]byte(server.bodies[0]), &got); err != nil {
+		t.Fatalf("payload %s: %v", server.bodies[0], err)
+	}
+	if !reflect.DeepEqual(got, testEvent) {
+		t.Errorf("payload = %+v, want %+v", got, testEvent)
+	}
+	h := server.headers[0]
+	if h.Get("Content-Type") != "application/json" || h.Get("Authorization") != "Bearer abc" || h.Get("User-Agent") != "NetView" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestWebhookTemplates(t *testing.T) {
+	for _, c := range []struct {
+		format, template string
+		want             string
+	}{
+		{WebhookFormatSlack, "", `{"text": "nas.local (192.168.1.20) is \"offline\""}`},
+		{WebhookFormatDiscord, "", `{"content": "nas.local (192.168.1.20) is \"offline\""}`},
+		{WebhookFormatCustom, `{"host": {{json .HostLabel}}, "down": {{if eq .Type "host_down"}}true{{else}}false{{end}}}`, `{"host": "nas.local (192.168.1.20)", "down": true}`},
+	} {
+		n, _ := newTestWebhook(t, WebhookConfig{URL: "http://example.invalid", Format: c.format, Template: c.template})
+		body, err := n.Render(testEvent)
+		if err != nil || string(body) != c.want {
+			t.Errorf("%s payload = %s, %v; want %s", c.format, body, err, c.want)
+		}
+	}
+
+	teams, _ := newTestWebhook(t, WebhookConfig{URL: "http://example.invalid", Format: WebhookFormatTeams})
+	body, err := teams.Render(testEvent)
+	if err != nil || !strings.Contains(string(body), `"themeColor": "D13438"`) {
+		t.Errorf("teams payload = %s, %v", body, err)
+	}
+
+	// A custom template must produce JSON
+	broken, _ := newTestWebhook(t, WebhookConfig{URL: "http://example.invalid", Format: WebhookFormatCustom, Template: `host={{.IPAddress}}`})
+	if _, err := broken.Render(testEvent); err == nil {
+		t.Error("non-JSON payload accepted")
+	}
+}
+
+func TestValidateWebhookConfig(t *testing.T) {
+	for _, c := range []struct {
+		config WebhookConfig
+		valid  bool
+	}{
+		{WebhookConfig{URL: "https://hooks.example.com/x"}, true},
+		{WebhookConfig{URL: "https://hooks.example.com/x", MaxRetries: intPtr(0)}, true},
+		{WebhookConfig{URL: "ftp://hooks.example.com/x"}, false},
+		{WebhookConfig{URL: "https://hooks.example.com/x", Format: "irc"}, false},
+		{WebhookConfig{URL: "https://hooks.example.com/x", Format: WebhookFormatCustom}, false},
+		{WebhookConfig{URL: "https://hooks.example.com/x", Format: WebhookFormatCustom, Template: "{{.Nope"}, false},
+		{WebhookConfig{URL: "https://hooks.example.com/x", MaxRetries: intPtr(-1)}, false},
+	} {
+		if err := ValidateWebhookConfig(c.config); (err == nil) != c.valid {
+			t.Errorf("ValidateWebhookConfig(%+v) = %v, want valid %v", c.config, err, c.valid)
+		}
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		statuses   []int
+		maxRetries *int
+		wantErr    bool
+		requests   int
+		waits      []time.Duration
+	}{
+		{"server errors are retried with backoff", []int{500, 502, 200}, nil, false, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"rate limiting is retried", []int{429, 200}, nil, false, 2, []time.Duration{time.Second}},
+		{"retries run out", []int{503}, intPtr(2), true, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"default retries", []int{503}, nil, true, 1 + defaultWebhookRetries, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"zero disables retries", []int{503}, intPtr(0), true, 1, nil},
+		{"client errors are not retried", []int{400}, nil, true, 1, nil},
+		{"auth errors are not retried", []int{403}, intPtr(5), true, 1, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			server := newWebhookServer(t, c.statuses...)
+			n, waits := newTestWebhook(t, WebhookConfig{URL: server.URL, MaxRetries: c.maxRetries})
+			err := n.Notify(context.Background(), testEvent)
+			if (err != nil) != c.wantErr {
+				t.Errorf("Notify error = %v, want error %v", err, c.wantErr)
+			}
+			if got := server.requests(); got != c.requests {
+				t.Errorf("%d requests, want %d", got, c.requests)
+			}
+			if !slices.Equal(*waits, c.waits) {
+				t.Errorf("backoff waits = %v, want %v", *waits, c.waits)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoffIsCapped(t *testing.T) {
+	server := newWebhookServer(t, http.StatusServiceUnavailable)
+	n, waits := newTestWebhook(t, WebhookConfig{URL: server.URL, MaxRetries: intPtr(8)})
+	n.Notify(context.Background(), testEvent)
+	if last := (*waits)[len(*waits)-1]; last != webhookMaxBackoff {
+		t.Errorf("backoff waits = %v, want capped at %v", *waits, webhookMaxBackoff)
+	}
+}
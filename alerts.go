@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"netview/alerting"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// AlertSettings holds the alert destinations configured by the user.
+type AlertSettings struct {
+	Webhooks []alerting.WebhookConfig `json:"webhooks"`
+	Email    alerting.SMTPConfig      `json:"email"`
+	Desktop  alerting.DesktopConfig   `json:"desktop"`
+}
+
+const alertSettingsFilename = "alert_settings.json"
+const alertTestTimeout = 15 * time.Second // Upper bound for the "send test" bindings
+
+// alerter delivers alerts to the destinations configured by the user.
+type alerter struct {
+	ctx        context.Context      // Long-lived context for deliveries, so stopping monitoring doesn't abort them
+	dispatcher *alerting.Dispatcher // Delivers alerts to the configured destinations
+
+	mu       sync.Mutex // Protects settings
+	settings AlertSettings
+}
+
+// openAlerter loads the alert settings and configures the dispatcher. Called on app startup.
+func openAlerter(ctx context.Context) *alerter {
+	al := &alerter{ctx: ctx, settings: AlertSettings{Webhooks: []alerting.WebhookConfig{}}}
+	al.dispatcher = alerting.NewDispatcher(al.logf)
+
+	path, err := alertSettingsFilePath()
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Alert settings path unavailable: %v", err))
+		return al
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			runtime.LogError(ctx, fmt.Sprintf("Error reading alert settings file '%s': %v", path, err))
+		}
+		return al
+	}
+	if err := json.Unmarshal(data, &al.settings); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error unmarshalling alert settings from '%s': %v", path, err))
+		al.settings = AlertSettings{Webhooks: []alerting.WebhookConfig{}}
+		return al
+	}
+	notifiers, err := al.buildNotifiers(ctx, al.settings)
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Some alert destinations could not be configured: %v", err))
+	}
+	al.setNotifiers(ctx, notifiers)
+	return al
+}
+
+// logf forwards alerting diagnostics to the Wails log.
+func (al *alerter) logf(format string, args ...any) {
+	runtime.LogInfo(al.ctx, fmt.Sprintf(format, args...))
+}
+
+// alertSettingsFilePath returns the full path of the alert settings file.
+func alertSettingsFilePath() (string, error) {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDataDir, alertSettingsFilename), nil
+}
+
+// buildNotifiers creates the notifiers for settings without installing them.
+// Invalid destinations are skipped and reported.
+func (al *alerter) buildNotifiers(ctx context.Context, settings AlertSettings) ([]alerting.Notifier, error) {
+	var notifiers []alerting.Notifier
+	var firstErr error
+	for _, cfg := range settings.Webhooks {
+		if !cfg.Enabled {
+			continue
+		}
+		n, err := alerting.NewWebhookNotifier(cfg, nil)
+		if err != nil {
+			runtime.LogWarning(ctx, fmt.Sprintf("Skipping webhook: %v", err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		notifiers = append(notifiers, n)
+	}
+	if settings.Email.Enabled {
+		n, err := alerting.NewEmailNotifier(settings.Email, al.logf)
+		if err != nil {
+			runtime.LogWarning(ctx, fmt.Sprintf("Skipping email alerts: %v", err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			notifiers = append(notifiers, n)
+		}
+	}
+	if settings.Desktop.Enabled {
+		n, err := alerting.NewDesktopNotifier(settings.Desktop)
+		if err != nil {
+			runtime.LogWarning(ctx, fmt.Sprintf("Skipping desktop notifications: %v", err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			notifiers = append(notifiers, n)
+		}
+	}
+	return notifiers, firstErr
+}
+
+// setNotifiers replaces the dispatcher's notifiers.
+func (al *alerter) setNotifiers(ctx context.Context, notifiers []alerting.Notifier) {
+	al.dispatcher.SetNotifiers(notifiers)
+	runtime.LogDebug(ctx, fmt.Sprintf("Alerting configured with %d destination(s).", len(notifiers)))
+}
+
+// closeAlertNotifiers releases notifiers that were built but never installed.
+func closeAlertNotifiers(notifiers []alerting.Notifier) {
+	for _, n := range notifiers {
+		if c, ok := n.(interface{ Close() error }); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+// saveAlertSettingsFile writes settings to the alert settings file.
+func saveAlertSettingsFile(settings AlertSettings) error {
+	path, err := alertSettingsFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling alert settings: %w", err)
+	}
+	return writeFileAtomic(path, data, 0600) // 0600: webhook URLs, headers and the SMTP password are secrets
+}
+
+// raise hands an event to the alert dispatcher. Delivery happens in the background.
+func (al *alerter) raise(event alerting.Event) {
+	al.dispatcher.Dispatch(al.ctx, event)
+}
+
+// hostStatusAlert builds the alert for a monitored host changing status. previousChange is
+// when the host entered its previous state (zero if unknown).
+func hostStatusAlert(host Host, isOnline bool, previousChange, now time.Time) alerting.Event {
+	event := alerting.Event{
+		Timestamp:     now,
+		IPAddress:     host.IPAddress,
+		Hostname:      host.Hostname,
+		MACAddress:    host.MACAddress,
+		DeviceType:    host.DeviceType,
+		State:         "offline",
+		PreviousState: "online",
+		Type:          alerting.EventHostDown,
+	}
+	if isOnline {
+		event.Type = alerting.EventHostUp
+		event.State, event.PreviousState = "online", "offline"
+	}
+	if !previousChange.IsZero() {
+		event.DurationSeconds = now.Sub(previousChange).Seconds()
+	}
+	event.Message = event.Title()
+	if isOnline && event.DurationSeconds > 0 {
+		event.Message = fmt.Sprintf("%s after %s offline", event.Title(), time.Duration(event.DurationSeconds*float64(time.Second)).Round(time.Second))
+	}
+	return event
+}
+
+// GetAlertSettings returns the configured alert destinations.
+func (a *App) GetAlertSettings() AlertSettings {
+	a.alerts.mu.Lock()
+	defer a.alerts.mu.Unlock()
+
+	settings := a.alerts.settings
+	settings.Webhooks = append([]alerting.WebhookConfig{}, a.alerts.settings.Webhooks...)
+	return settings
+}
+
+// SaveAlertSettings validates, applies and persists the alert destinations.
+func (a *App) SaveAlertSettings(settings AlertSettings) error {
+	for _, cfg := range settings.Webhooks {
+		if err := alerting.ValidateWebhookConfig(cfg); err != nil {
+			return err
+		}
+	}
+	if settings.Email.Enabled {
+		if err := alerting.ValidateSMTPConfig(settings.Email); err != nil {
+			return err
+		}
+	}
+	if settings.Desktop.Enabled {
+		if err := alerting.ValidateDesktopConfig(settings.Desktop); err != nil {
+			return err
+		}
+	}
+	if settings.Webhooks == nil {
+		settings.Webhooks = []alerting.WebhookConfig{}
+	}
+
+	a.alerts.mu.Lock()
+	defer a.alerts.mu.Unlock()
+
+	// The new settings only go live once every destination is valid and they are on disk
+	notifiers, err := a.alerts.buildNotifiers(a.ctx, settings)
+	if err != nil {
+		closeAlertNotifiers(notifiers)
+		return err
+	}
+	if err := saveAlertSettingsFile(settings); err != nil {
+		closeAlertNotifiers(notifiers)
+		return err
+	}
+	a.alerts.settings = settings
+	a.alerts.setNotifiers(a.ctx, notifiers)
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Saved alert settings with %d webhook(s).", len(settings.Webhooks)))
+	return nil
+}
+
+// SendTestWebhook sends a test alert to a single webhook and reports the delivery result.
+func (a *App) SendTestWebhook(config alerting.WebhookConfig) error {
+	n, err := alerting.NewWebhookNotifier(config, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, alertTestTimeout)
+	defer cancel()
+
+	event := alerting.Event{Type: alerting.EventTest, Timestamp: time.Now()}
+	event.Message = "This is a test alert from NetView."
+	return n.Notify(ctx, event)
+}
+
+// SendTestEmail sends a test email with the given SMTP settings, bypassing digest batching,
+// and reports the delivery result.
+func (a *App) SendTestEmail(config alerting.SMTPConfig) error {
+	n, err := alerting.NewEmailNotifier(config, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, alertTestTimeout)
+	defer cancel()
+
+	event := alerting.Event{Type: alerting.EventTest, Timestamp: time.Now()}
+	event.Message = "This is a test email from NetView. If you can read this, email alerts are configured correctly."
+	return n.Notify(ctx, event)
+}
+
+// SendTestDesktopNotification raises a test OS notification, ignoring rate limits and
+// the do-not-disturb schedule.
+func (a *App) SendTestDesktopNotification() error {
+	n, err := alerting.NewDesktopNotifier(alerting.DesktopConfig{Enabled: true})
+	if err != nil {
+		return err
+	}
+	event := alerting.Event{Type: alerting.EventTest, Timestamp: time.Now()}
+	event.Message = "Desktop notifications from NetView are working."
+	return n.Notify(a.ctx, event)
+}
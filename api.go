@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// APISettings controls the embedded REST API server, which lets other tools start scans and
+// read results, history, the inventory and monitored hosts.
+type APISettings struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"` // Listen address, e.g. "127.0.0.1:7878"; loopback unless other machines need access
+	Token   string `json:"token"`   // Bearer token required on every request except for the OpenAPI spec and /metrics
+	// Read-only bearer token for /metrics, so a Prometheus scrape config cannot start scans or
+	// change monitoring
+	MetricsToken string `json:"metricsToken"`
+}
+
+const apiSettingsKey = "api" // Key of the API settings in the settings bucket
+const defaultAPIAddress = "127.0.0.1:7878"
+const apiShutdownTimeout = 2 * time.Second // Grace period for in-flight requests before connections are closed
+
+// apiServer is the embedded REST API server and its settings.
+type apiServer struct {
+	app *App
+
+	mu       sync.Mutex
+	settings APISettings
+	server   *http.Server // Nil while the API is disabled
+}
+
+// openAPIServer loads the API settings and starts the server if it is enabled. Called on app
+// startup, once the services it exposes are ready.
+func openAPIServer(ctx context.Context, app *App) *apiServer {
+	s := &apiServer{app: app, settings: APISettings{Address: defaultAPIAddress}}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app.store != nil {
+		if err := storage.GetJSON(app.store, settingsBucket, apiSettingsKey, &s.settings); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			runtime.LogError(ctx, fmt.Sprintf("Error loading API settings: %v", err))
+		}
+	}
+	if !s.settings.Enabled {
+		return s
+	}
+	if s.settings.MetricsToken == "" { // Settings saved before /metrics had its own token
+		if token, err := newAPIToken(); err == nil {
+			s.settings.MetricsToken = token
+			if err := s.saveLocked(); err != nil {
+				runtime.LogError(ctx, fmt.Sprintf("Error saving API settings: %v", err))
+			}
+		}
+	}
+	if err := s.startLocked(ctx); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Could not start the API server: %v", err))
+	}
+	return s
+}
+
+// startLocked listens on the configured address and serves the API in the background. The
+// caller must hold s.mu and must have stopped any previous server.
+func (s *apiServer) startLocked(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.settings.Address)
+	if err != nil {
+		return err
+	}
+	// Requests run under a context cancelled on shutdown, so streams end promptly
+	serverCtx, cancel := context.WithCancel(ctx)
+	server := &http.Server{
+		Handler:           newAPIHandler(s.app),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return serverCtx },
+	}
+	server.RegisterOnShutdown(cancel)
+	s.server = server
+	if host, _, _ := net.SplitHostPort(s.settings.Address); !isLoopbackHost(host) {
+		runtime.LogWarning(ctx, fmt.Sprintf("API server listening on %s is reachable from other machines.", s.settings.Address))
+	}
+	runtime.LogInfo(ctx, fmt.Sprintf("API server listening on %s.", listener.Addr()))
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			runtime.LogError(ctx, fmt.Sprintf("API server stopped: %v", err))
+		}
+	}()
+	return nil
+}
+
+// stopLocked shuts the server down, giving in-flight requests a moment to finish. Streaming
+// responses are cut off. The caller must hold s.mu.
+func (s *apiServer) stopLocked(ctx context.Context) {
+	if s.server == nil {
+		return
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		s.server.Close()
+	}
+	s.server = nil
+	runtime.LogInfo(ctx, "API server stopped.")
+}
+
+// stop stops the server on app shutdown.
+func (s *apiServer) stop(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopLocked(ctx)
+}
+
+// isLoopbackHost reports whether a listen host only accepts local connections.
+// An empty host listens on every interface.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newAPIToken returns a random bearer token.
+func newAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating API token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validAPIToken reports whether token matches the configured API token.
+func (s *apiServer) validAPIToken(token string) bool {
+	s.mu.Lock()
+	want := s.settings.Token
+	s.mu.Unlock()
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// validMetricsToken reports whether token matches the configured metrics token.
+func (s *apiServer) validMetricsToken(token string) bool {
+	s.mu.Lock()
+	want := s.settings.MetricsToken
+	s.mu.Unlock()
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// GetAPISettings returns the API server settings.
+func (a *App) GetAPISettings() APISettings {
+	a.api.mu.Lock()
+	defer a.api.mu.Unlock()
+	return a.api.settings
+}
+
+// SaveAPISettings validates and applies the API server settings, restarting the server as
+// needed. If the server cannot listen on the new address, the previous settings stay in
+// effect. Enabling the API without a token or metrics token generates them.
+func (a *App) SaveAPISettings(settings APISettings) error {
+	settings.Address = strings.TrimSpace(settings.Address)
+	if settings.Address == "" {
+		settings.Address = defaultAPIAddress
+	}
+	if _, port, err := net.SplitHostPort(settings.Address); err != nil || port == "" {
+		return fmt.Errorf("invalid API address %q: expected host:port", settings.Address)
+	}
+	settings.Token = strings.TrimSpace(settings.Token)
+	if settings.Enabled && settings.Token == "" {
+		token, err := newAPIToken()
+		if err != nil {
+			return err
+		}
+		settings.Token = token
+	}
+	settings.MetricsToken = strings.TrimSpace(settings.MetricsToken)
+	if settings.Enabled && settings.MetricsToken == "" {
+		token, err := newAPIToken()
+		if err != nil {
+			return err
+		}
+		settings.MetricsToken = token
+	}
+
+	a.api.mu.Lock()
+	defer a.api.mu.Unlock()
+
+	previous := a.api.settings
+	a.api.stopLocked(a.ctx)
+	a.api.settings = settings
+	if settings.Enabled {
+		if err := a.api.startLocked(a.ctx); err != nil {
+			a.api.settings = previous
+			if previous.Enabled {
+				if restartErr := a.api.startLocked(a.ctx); restartErr != nil {
+					runtime.LogError(a.ctx, fmt.Sprintf("Could not restart the API server on %s: %v", previous.Address, restartErr))
+				}
+			}
+			return fmt.Errorf("API server could not listen on %s: %w", settings.Address, err)
+		}
+	}
+	return a.api.saveLocked()
+}
+
+// RegenerateAPIToken replaces the API token, locking out clients using the old one, and
+// returns the new token.
+func (a *App) RegenerateAPIToken() (string, error) {
+	token, err := newAPIToken()
+	if err != nil {
+		return "", err
+	}
+	a.api.mu.Lock()
+	defer a.api.mu.Unlock()
+	a.api.settings.Token = token
+	if err := a.api.saveLocked(); err != nil {
+		return "", err
+	}
+	runtime.LogInfo(a.ctx, "API token regenerated.")
+	return token, nil
+}
+
+// RegenerateMetricsToken replaces the metrics token, locking out scrapers using the old one,
+// and returns the new token.
+func (a *App) RegenerateMetricsToken() (string, error) {
+	token, err := newAPIToken()
+	if err != nil {
+		return "", err
+	}
+	a.api.mu.Lock()
+	defer a.api.mu.Unlock()
+	a.api.settings.MetricsToken = token
+	if err := a.api.saveLocked(); err != nil {
+		return "", err
+	}
+	runtime.LogInfo(a.ctx, "Metrics token regenerated.")
+	return token, nil
+}
+
+// saveLocked persists the API settings. The caller must hold s.mu.
+func (s *apiServer) saveLocked() error {
+	if s.app.store == nil {
+		return fmt.Errorf("data store is not available")
+	}
+	return storage.PutJSON(s.app.store, settingsBucket, apiSettingsKey, s.settings)
+}
+
+// requireAPIToken rejects requests without the configured bearer token. With allowQueryToken
+// the token may instead be passed as the token query parameter, for clients that cannot set
+// headers such as browser EventSource clients of the event stream. Other routes never accept
+// it there, so it stays out of URLs and access logs.
+func (s *apiServer) requireAPIToken(next http.Handler, allowQueryToken bool) http.Handler {
+	return requireToken(next, s.validAPIToken, allowQueryToken)
+}
+
+// requireMetricsToken rejects requests without the metrics bearer token. The API token is not
+// accepted either, so scrape configs only ever hold the read-only one.
+func (s *apiServer) requireMetricsToken(next http.Handler) http.Handler {
+	return requireToken(next, s.validMetricsToken, false)
+}
+
+// requireToken rejects requests whose bearer token does not pass valid.
+func requireToken(next http.Handler, valid func(token string) bool, allowQueryToken bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && allowQueryToken {
+			token, ok = r.URL.Query().Get("token"), r.URL.Query().Has("token")
+		}
+		if !ok || !valid(strings.TrimSpace(token)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="netview"`)
+			writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"netview/events"
+)
+
+const eventStreamBuffer = 256                 // Events buffered per client before it starts losing them
+const eventStreamKeepAlive = 15 * time.Second // Comment lines keep idle connections open through proxies
+const eventStreamRetry = 3 * time.Second      // Reconnection delay suggested to clients
+
+// streamEvents serves the events the GUI receives as server-sent events, optionally filtered
+// with ?types=hostFound,scanComplete and ?scanId=. Each message carries the event name and a
+// JSON-encoded events.Event. A client that falls behind loses events and is sent a "dropped"
+// event with the number lost, so it can refetch state.
+func (h apiHandler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEventFilter(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	sub := h.app.events.stream.Subscribe(filter, eventStreamBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry.Milliseconds())
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	var dropped uint64
+	for {
+		var err error
+		select {
+		case e, open := <-sub.C:
+			if !open {
+				return
+			}
+			if lost := sub.Dropped(); lost > dropped {
+				_, err = fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", lost-dropped)
+				dropped = lost
+			}
+			if err == nil {
+				err = writeServerSentEvent(w, e)
+			}
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err != nil {
+			return // Client went away
+		}
+		flusher.Flush()
+	}
+}
+
+// writeServerSentEvent writes e as one server-sent event message.
+func writeServerSentEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		data, _ = json.Marshal(events.Event{Seq: e.Seq, Name: e.Name, ScanID: e.ScanID, Time: e.Time})
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Name, data)
+	return err
+}
+
+// parseEventFilter reads the event stream filter from the query: types (comma-separated or
+// repeated) and scanId.
+func parseEventFilter(query url.Values) (events.Filter, error) {
+	filter := events.Filter{ScanID: strings.TrimSpace(query.Get("scanId"))}
+	for _, param := range query["types"] {
+		for _, name := range strings.Split(param, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !slices.Contains(events.Names, name) {
+				return filter, fmt.Errorf("unknown event type %q", name)
+			}
+			filter.Names = append(filter.Names, name)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"netview/events"
+)
+
+// sseMessage is one server-sent event message: its fields by name.
+type sseMessage map[string]string
+
+// readSSE returns the next message of an event stream.
+func readSSE(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	msg := sseMessage{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(msg) == 0 {
+				continue
+			}
+			return msg
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		msg[field] += value
+	}
+}
+
+// openEventStream connects to the event stream of a test app with the query and reads the
+// opening retry message. Events emitted on the returned hub reach the stream.
+func openEventStream(t *testing.T, query string) (*bufio.Reader, *events.Hub) {
+	t.Helper()
+	server, app := newTestAPI(t)
+	resp := apiRequest(t, server, "GET", "/api/v1/events"+query, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("event stream = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	stream := bufio.NewReaderSize(resp.Body, 1<<20)
+	if msg := readSSE(t, stream); msg["retry"] != "3000" {
+		t.Fatalf("first message = %v, want the retry delay", msg)
+	}
+	return stream, app.events.stream
+}
+
+func TestAPIEventStream(t *testing.T) {
+	stream, hub := openEventStream(t, "?types=hostFound&types=scanComplete,&scanId=s1")
+
+	hub.EmitScan("s1", events.HostFound, Host{IPAddress: "10.0.0.5"})
+	hub.EmitScan("s2", events.HostFound, Host{IPAddress: "10.0.0.6"}) // Another scan
+	hub.EmitScan("s1", events.ScanSaved, ScanResultInfo{ID: "s1"})    // Not a selected type
+	hub.EmitScan("s1", events.ScanComplete, true)
+
+	found := readSSE(t, stream)
+	var e struct {
+		events.Event
+		Data Host `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(found["data"]), &e); err != nil {
+		t.Fatalf("data %q: %v", found["data"], err)
+	}
+	if found["event"] != events.HostFound || found["id"] != strconv.FormatUint(e.Seq, 10) ||
+		e.Name != events.HostFound || e.ScanID != "s1" || e.Time.IsZero() || e.Data.IPAddress != "10.0.0.5" {
+		t.Errorf("first event = %v", found)
+	}
+	complete := readSSE(t, stream)
+	if complete["event"] != events.ScanComplete || complete["id"] != strconv.FormatUint(e.Seq+3, 10) ||
+		!strings.Contains(complete["data"], `"data":true`) {
+		t.Errorf("second event = %v, want scanComplete with id %d", complete, e.Seq+3)
+	}
+}
+
+func TestAPIEventStreamRejectsUnknownTypes(t *testing.T) {
+	server, _ := newTestAPI(t)
+	resp := apiRequest(t, server, "GET", "/api/v1/events?types=hostFound,hostLost", "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), `unknown event type \"hostLost\"`) {
+		t.Errorf("unknown type = %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAPIEventStreamReportsDroppedEvents(t *testing.T) {
+	stream, hub := openEventStream(t, "")
+
+	// The client reads nothing until the socket buffers and the subscription's buffer are full
+	payload := strings.Repeat("x", 64<<10)
+	const sent = 2000
+	for range sent {
+		hub.Emit(events.ScanError, payload)
+	}
+
+	// Every event is either delivered or counted in a dropped notice
+	var received, dropped uint64
+	for received+dropped < sent {
+		msg := readSSE(t, stream)
+		if msg["event"] != "dropped" {
+			if msg["event"] != events.ScanError {
+				t.Fatalf("unexpected message %v", msg["event"])
+			}
+			received++
+			continue
+		}
+		var lost struct{ Dropped uint64 }
+		if err := json.Unmarshal([]byte(msg["data"]), &lost); err != nil || lost.Dropped == 0 {
+			t.Fatalf("dropped event data %q: %v", msg["data"], err)
+		}
+		dropped += lost.Dropped
+	}
+	if dropped == 0 || received+dropped != sent {
+		t.Errorf("%d received and %d dropped of %d", received, dropped, sent)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+//go:embed api/openapi.yaml
+var apiSpec []byte
+
+const maxAPIRequestBody = 1 << 20
+
+// apiHandler serves the REST API on top of the same services as the Wails bindings.
+type apiHandler struct {
+	app *App
+}
+
+// newAPIHandler returns the API routes and the Prometheus /metrics endpoint. Everything except
+// the OpenAPI spec requires the API token, apart from /metrics, which requires the metrics
+// token; only the event stream accepts a token in the query string.
+func newAPIHandler(app *App) http.Handler {
+	h := apiHandler{app: app}
+
+	api := http.NewServeMux()
+	api.HandleFunc("POST /api/v1/scans", h.startScan)
+	api.HandleFunc("GET /api/v1/scans", h.listScans)
+	api.HandleFunc("GET /api/v1/scans/{id}", h.getScan)
+	api.HandleFunc("POST /api/v1/scans/{id}/cancel", h.cancelScan)
+	api.HandleFunc("GET /api/v1/scans/{id}/hosts", h.streamScanHosts)
+	api.HandleFunc("GET /api/v1/history", h.listHistory)
+	api.HandleFunc("GET /api/v1/results", h.listResults)
+	api.HandleFunc("GET /api/v1/results/{id}", h.getResult)
+	api.HandleFunc("GET /api/v1/inventory", h.listInventory)
+	api.HandleFunc("GET /api/v1/monitor/hosts", h.listMonitoredHosts)
+	api.HandleFunc("POST /api/v1/monitor/hosts", h.addMonitoredHost)
+	api.HandleFunc("DELETE /api/v1/monitor/hosts/{ip}", h.removeMonitoredHost)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(apiSpec)
+	})
+	mux.Handle("/api/v1/", app.api.requireAPIToken(api, false))
+	mux.Handle("GET /api/v1/events", app.api.requireAPIToken(http.HandlerFunc(h.streamEvents), true))
+	mux.Handle("GET /metrics", app.api.requireMetricsToken(http.HandlerFunc(h.serveMetrics)))
+	return mux
+}
+
+// writeAPIJSON writes v as the JSON response body.
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes an error response: {"error": "..."}.
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// readAPIJSON decodes the JSON request body into v.
+func readAPIJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBody)).Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// startScan starts a scan of the posted ScanRange, like the GUI's scan button.
+func (h apiHandler) startScan(w http.ResponseWriter, r *http.Request) {
+	var params ScanRange
+	if err := readAPIJSON(w, r, &params); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	job, err := h.app.performScan(params)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	info := job.snapshot()
+	runtime.LogInfo(h.app.ctx, fmt.Sprintf("API started scan %s of %s - %s.", info.ID, params.StartIP, params.EndIP))
+	w.Header().Set("Location", "/api/v1/scans/"+info.ID)
+	writeAPIJSON(w, http.StatusAccepted, info)
+}
+
+func (h apiHandler) listScans(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, h.app.scanJobs.list())
+}
+
+func (h apiHandler) getScan(w http.ResponseWriter, r *http.Request) {
+	job := h.app.scanJobs.find(r.PathValue("id"))
+	if job == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", r.PathValue("id")))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, job.snapshot())
+}
+
+func (h apiHandler) cancelScan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job := h.app.scanJobs.find(id)
+	if job == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", id))
+		return
+	}
+	if err := h.app.scanJobs.cancel(id); err != nil {
+		writeAPIError(w, http.StatusConflict, err)
+		return
+	}
+	runtime.LogInfo(h.app.ctx, fmt.Sprintf("API cancelled scan %s.", id))
+	writeAPIJSON(w, http.StatusAccepted, job.snapshot())
+}
+
+// streamScanHosts writes the hosts found by a scan as newline-delimited JSON: those found so
+// far, then each new one as it is found, ending when the scan ends.
+func (h apiHandler) streamScanHosts(w http.ResponseWriter, r *http.Request) {
+	job := h.app.scanJobs.find(r.PathValue("id"))
+	if job == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", r.PathValue("id")))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	sent := 0
+	for {
+		hosts, done, changed := job.hostsFrom(sent)
+		for _, host := range hosts {
+			if err := encoder.Encode(host); err != nil {
+				return // Client went away
+			}
+		}
+		sent += len(hosts)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (h apiHandler) listHistory(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, h.app.GetScanHistory())
+}
+
+func (h apiHandler) listResults(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, h.app.ListScanResults())
+}
+
+func (h apiHandler) getResult(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	stored := false
+	for _, info := range h.app.ListScanResults() {
+		if info.ID == id {
+			stored = true
+			break
+		}
+	}
+	if !stored {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan result %s not found", id))
+		return
+	}
+	result, err := h.app.GetScanResult(id)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+// listInventory returns the known devices, optionally only those with ?status=pending,
+// approved or ignored.
+func (h apiHandler) listInventory(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", deviceStatusPending, deviceStatusApproved, deviceStatusIgnored:
+	default:
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown device status %q", status))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, h.app.inventory.snapshot(status))
+}
+
+func (h apiHandler) listMonitoredHosts(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, h.app.ListMonitoredHosts())
+}
+
+// addMonitoredHost adds the posted Host to monitoring, starting a session if none is active.
+func (h apiHandler) addMonitoredHost(w http.ResponseWriter, r *http.Request) {
+	var host Host
+	if err := readAPIJSON(w, r, &host); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if monitoredHostState(h.app, host.IPAddress) != nil {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("host %s is already monitored", host.IPAddress))
+		return
+	}
+	if err := h.app.AddMonitoredHost(host); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, monitoredHostState(h.app, host.IPAddress))
+}
+
+func (h apiHandler) removeMonitoredHost(w http.ResponseWriter, r *http.Request) {
+	if err := h.app.RemoveMonitoredHost(r.PathValue("ip")); err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// monitoredHostState returns the state of a monitored host, or nil if it is not monitored.
+func monitoredHostState(app *App, ip string) *MonitoredHostState {
+	for _, state := range app.ListMonitoredHosts() {
+		if state.Host.IPAddress == ip {
+			return &state
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"netview/events"
+	"netview/monitor"
+)
+
+const (
+	testAPIToken     = "0123456789abcdef"
+	testMetricsToken = "fedcba9876543210"
+)
+
+// newTestAPI serves the API of an app with testAPIToken and testMetricsToken configured, an
+// empty monitor and inventory, no stored results and no scan jobs.
+func newTestAPI(t *testing.T) (*httptest.Server, *App) {
+	t.Helper()
+	app := &App{ctx: context.Background()}
+	app.events = wailsEvents{ctx: app.ctx, stream: events.NewHub()}
+	app.monitor = monitor.New(app.ctx, monitor.Options{Events: events.Discard, Log: events.Discard})
+	app.metrics = newAppMetrics(app)
+	app.scanResults = &scanResults{index: []ScanResultInfo{}}
+	app.scanJobs = &scanJobs{}
+	app.inventory = newInventory(nil, app.events, nil)
+	app.api = &apiServer{app: app, settings: APISettings{Token: testAPIToken, MetricsToken: testMetricsToken}}
+	server := httptest.NewServer(newAPIHandler(app))
+	t.Cleanup(server.Close)
+	return server, app
+}
+
+// apiRequest sends a request with the test token, unless auth is given ("" for none).
+func apiRequest(t *testing.T, server *httptest.Server, method, path, body string, auth ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "Bearer " + testAPIToken
+	if len(auth) > 0 {
+		header = auth[0]
+	}
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// startTestScanJob registers a running job, as performScan does, without scanning anything.
+func startTestScanJob(app *App) *scanJob {
+	job, _ := newScanJob(context.Background(), ScanRange{StartIP: "10.0.0.1", EndIP: "10.0.0.9"})
+	app.scanJobs.register(job)
+	return job
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	server, app := newTestAPI(t)
+	for _, c := range []struct {
+		name, method, path, auth string
+		want                     int
+	}{
+		{"valid token", "GET", "/api/v1/scans", "Bearer " + testAPIToken, http.StatusOK},
+		{"no token", "GET", "/api/v1/scans", "", http.StatusUnauthorized},
+		{"wrong token", "GET", "/api/v1/scans", "Bearer " + strings.Repeat("0", len(testAPIToken)), http.StatusUnauthorized},
+		{"token prefix", "GET", "/api/v1/scans", "Bearer " + testAPIToken[:8], http.StatusUnauthorized},
+		{"basic auth", "GET", "/api/v1/scans", "Basic " + testAPIToken, http.StatusUnauthorized},
+		{"before routing", "GET", "/api/v1/nothing-here", "", http.StatusUnauthorized},
+		{"writes", "POST", "/api/v1/scans", "", http.StatusUnauthorized},
+		{"deletes", "DELETE", "/api/v1/monitor/hosts/10.0.0.1", "", http.StatusUnauthorized},
+		{"metrics", "GET", "/metrics", "", http.StatusUnauthorized},
+		{"metrics with the metrics token", "GET", "/metrics", "Bearer " + testMetricsToken, http.StatusOK},
+		{"metrics with the API token", "GET", "/metrics", "Bearer " + testAPIToken, http.StatusUnauthorized},
+		{"API with the metrics token", "GET", "/api/v1/scans", "Bearer " + testMetricsToken, http.StatusUnauthorized},
+		{"event stream with the metrics token", "GET", "/api/v1/events", "Bearer " + testMetricsToken, http.StatusUnauthorized},
+		{"OpenAPI spec is public", "GET", "/api/v1/openapi.yaml", "", http.StatusOK},
+		{"event stream", "GET", "/api/v1/events", "Bearer " + testAPIToken, http.StatusOK},
+		{"query token for the event stream", "GET", "/api/v1/events?token=" + testAPIToken, "", http.StatusOK},
+		{"wrong query token", "GET", "/api/v1/events?token=" + testAPIToken[:8], "", http.StatusUnauthorized},
+		{"query token elsewhere", "GET", "/api/v1/scans?token=" + testAPIToken, "", http.StatusUnauthorized},
+		{"query token for writes", "POST", "/api/v1/scans?token=" + testAPIToken, "", http.StatusUnauthorized},
+		{"query token for metrics", "GET", "/metrics?token=" + testMetricsToken, "", http.StatusUnauthorized},
+	} {
+		resp := apiRequest(t, server, c.method, c.path, "", c.auth)
+		if resp.StatusCode != c.want {
+			t.Errorf("%s: %s %s = %d, want %d", c.name, c.method, c.path, resp.StatusCode, c.want)
+		}
+		if c.want == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != `Bearer realm="netview"` {
+			t.Errorf("%s: WWW-Authenticate = %q", c.name, resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+
+	// Without a configured token nothing gets in
+	app.api.mu.Lock()
+	app.api.settings.Token, app.api.settings.MetricsToken = "", ""
+	app.api.mu.Unlock()
+	if resp := apiRequest(t, server, "GET", "/api/v1/scans", "", "Bearer "); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("empty token accepted: %d", resp.StatusCode)
+	}
+	if resp := apiRequest(t, server, "GET", "/metrics", "", "Bearer "); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("empty metrics token accepted: %d", resp.StatusCode)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	server, app := newTestAPI(t)
+	finished := startTestScanJob(app)
+	finished.finish(scanStateCompleted, true)
+
+	for _, c := range []struct {
+		method, path, body string
+		want               int
+		wantErr            string
+	}{
+		{"GET", "/api/v1/scans/missing", "", http.StatusNotFound, "scan missing not found"},
+		{"POST", "/api/v1/scans/missing/cancel", "", http.StatusNotFound, "scan missing not found"},
+		{"GET", "/api/v1/scans/missing/hosts", "", http.StatusNotFound, "scan missing not found"},
+		{"POST", "/api/v1/scans/" + finished.info.ID + "/cancel", "", http.StatusConflict, "is not running"},
+		{"GET", "/api/v1/results/missing", "", http.StatusNotFound, "scan result missing not found"},
+		{"DELETE", "/api/v1/monitor/hosts/10.0.0.1", "", http.StatusNotFound, "host 10.0.0.1 is not monitored"},
+		{"GET", "/api/v1/inventory?status=stolen", "", http.StatusBadRequest, `unknown device status \"stolen\"`},
+		{"POST", "/api/v1/scans", "{", http.StatusBadRequest, "invalid request body"},
+		{"POST", "/api/v1/scans", `{"startIp": 1}`, http.StatusBadRequest, "invalid request body"},
+		{"POST", "/api/v1/monitor/hosts", "[]", http.StatusBadRequest, "invalid request body"},
+		{"POST", "/api/v1/scans", `{"startIp":"10.0.0.1","endIp":"10.0.0.9","ports":[` + strings.Repeat("1,", maxAPIRequestBody/2) + `1]}`,
+			http.StatusBadRequest, "request body too large"},
+	} {
+		resp := apiRequest(t, server, c.method, c.path, c.body)
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != c.want || resp.Header.Get("Content-Type") != "application/json" || !strings.Contains(string(body), c.wantErr) {
+			t.Errorf("%s %s = %d %q, want %d mentioning %q", c.method, c.path, resp.StatusCode, body, c.want, c.wantErr)
+		}
+	}
+
+	// Unknown routes and methods, behind the token check
+	if resp := apiRequest(t, server, "GET", "/api/v1/nothing-here", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown route = %d", resp.StatusCode)
+	}
+	if resp := apiRequest(t, server, "PUT", "/api/v1/scans", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("unknown method = %d", resp.StatusCode)
+	}
+}
+
+func TestAPIListScans(t *testing.T) {
+	server, app := newTestAPI(t)
+	first := startTestScanJob(app)
+	first.finish(scanStateCancelled, false)
+	second := startTestScanJob(app)
+
+	var jobs []ScanJob
+	if err := json.NewDecoder(apiRequest(t, server, "GET", "/api/v1/scans", "").Body).Decode(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != second.info.ID || jobs[0].State != scanStateRunning || jobs[1].State != scanStateCancelled {
+		t.Errorf("jobs = %+v, want the running one first", jobs)
+	}
+	var job ScanJob
+	if err := json.NewDecoder(apiRequest(t, server, "GET", "/api/v1/scans/"+first.info.ID, "").Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != first.info.ID || job.Parameters.EndIP != "10.0.0.9" || job.FinishedAt.IsZero() {
+		t.Errorf("job = %+v", job)
+	}
+}
+
+func TestAPIStreamScanHosts(t *testing.T) {
+	server, app := newTestAPI(t)
+	job := startTestScanJob(app)
+	job.addHost(Host{IPAddress: "10.0.0.1"}) // Found before the client connects
+
+	resp := apiRequest(t, server, "GET", "/api/v1/scans/"+job.info.ID+"/hosts", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("stream = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	lines := bufio.NewScanner(resp.Body)
+	next := func() Host {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("stream ended early: %v", lines.Err())
+		}
+		var h Host
+		if err := json.Unmarshal(lines.Bytes(), &h); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		return h
+	}
+
+	if h := next(); h.IPAddress != "10.0.0.1" {
+		t.Errorf("first host = %+v", h)
+	}
+	// Each host is sent as it is found
+	job.addHost(Host{IPAddress: "10.0.0.5", OpenPorts: []int{22}})
+	if h := next(); h.IPAddress != "10.0.0.5" || len(h.OpenPorts) != 1 {
+		t.Errorf("second host = %+v", h)
+	}
+	job.addHost(Host{IPAddress: "10.0.0.7"})
+	job.finish(scanStateCompleted, true)
+	if h := next(); h.IPAddress != "10.0.0.7" {
+		t.Errorf("third host = %+v", h)
+	}
+	if lines.Scan() {
+		t.Errorf("stream continued after the scan ended: %q", lines.Text())
+	}
+
+	// A finished scan streams all its hosts at once
+	resp = apiRequest(t, server, "GET", "/api/v1/scans/"+job.info.ID+"/hosts", "")
+	body, _ := io.ReadAll(resp.Body)
+	if n := strings.Count(string(body), "\n"); n != 3 {
+		t.Errorf("finished scan streamed %d lines: %q", n, body)
+	}
+}
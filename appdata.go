@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const appDataDirName = "NetView" // App-specific folder inside the user config dir
+
+// resolveAppDataDir returns the NetView config directory, creating it if needed.
+func resolveAppDataDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting user config dir: %v\n", err)
+		configDir = "." // Fallback to current directory (not ideal)
+	}
+	appDataDir := filepath.Join(configDir, appDataDirName)
+	if err := os.MkdirAll(appDataDir, 0750); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("creating app data dir '%s': %w", appDataDir, err)
+	}
+	return appDataDir, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path,
+// so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tempFilePath := path + ".tmp"
+	if err := os.WriteFile(tempFilePath, data, perm); err != nil {
+		return fmt.Errorf("writing temporary file '%s': %w", tempFilePath, err)
+	}
+	if err := os.Rename(tempFilePath, path); err != nil {
+		_ = os.Remove(tempFilePath) // Attempt to clean up temp file
+		return fmt.Errorf("renaming temporary file to '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	runtime_go "runtime" // To pick the routing table source for the OS
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/alerting"
+	"netview/events"
+	"netview/scanner"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ARPBinding is an IP -> MAC pair observed in the system's ARP table.
+type ARPBinding struct {
+	IPAddress  string    `json:"ipAddress"`
+	MACAddress string    `json:"macAddress"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+// Kinds of ARP anomaly.
+const (
+	arpAnomalyMACChanged   = "mac_changed"      // An IP is now answered by a different MAC
+	arpAnomalyDuplicateIP  = "duplicate_ip"     // Two MACs answer for the same IP (IP conflict)
+	arpAnomalyGatewaySpoof = "gateway_spoof"    // The gateway's IP is claimed by a MAC that also owns other IPs
+	arpAnomalyManyIPs      = "mac_multiple_ips" // One MAC claims more IPs than expected
+)
+
+// ARPAnomaly is emitted as an arpAnomaly event when an IP/MAC binding looks like ARP poisoning
+// or an address conflict.
+type ARPAnomaly struct {
+	Type               string    `json:"type"`
+	IPAddress          string    `json:"ipAddress"`
+	MACAddress         string    `json:"macAddress"`
+	PreviousMACAddress string    `json:"previousMacAddress,omitempty"` // mac_changed: the MAC the IP used to have
+	OtherMACAddresses  []string  `json:"otherMacAddresses,omitempty"`  // duplicate_ip: the other MACs answering
+	OtherIPAddresses   []string  `json:"otherIpAddresses,omitempty"`   // gateway_spoof, mac_multiple_ips: the other IPs claimed
+	Timestamp          time.Time `json:"timestamp"`
+	Message            string    `json:"message"`
+}
+
+// ARPWatchSettings controls the ARP anomaly detector.
+type ARPWatchSettings struct {
+	Enabled      bool     `json:"enabled"`
+	MaxIPsPerMAC int      `json:"maxIpsPerMac"` // More IPs than this on one MAC is reported
+	TrustedMACs  []string `json:"trustedMacs"`  // MACs allowed to claim many IPs (e.g. a router doing proxy ARP)
+}
+
+// arpWatchFile is the on-disk form of the ARP watch state.
+type arpWatchFile struct {
+	Settings ARPWatchSettings `json:"settings"`
+	Bindings []ARPBinding     `json:"bindings"`
+}
+
+const arpWatchFilename = "arp_bindings.json"
+const defaultMaxIPsPerMAC = 4
+const arpBindingExpiry = 24 * time.Hour     // Bindings not seen for this long no longer count as current
+const arpDuplicateWindow = time.Hour        // An IP alternating between MACs within this window is a conflict
+const arpAnomalyCooldown = 30 * time.Minute // The same anomaly is reported at most this often
+const maxARPBindings = 4096                 // Oldest bindings are dropped beyond this
+const gatewayLookupTimeout = 2 * time.Second
+
+// arpWatch detects ARP poisoning and IP conflicts from the IP/MAC bindings the scanner sees.
+type arpWatch struct {
+	ctx    context.Context // Context for events raised from the scanner's ARP lookups, which have none of their own
+	events wailsEvents
+	alerts *alerter
+
+	mu        sync.Mutex
+	bindings  map[string]*ARPBinding // "ip|mac" -> binding
+	current   map[string]*ARPBinding // IP -> its most recently seen binding in bindings
+	settings  ARPWatchSettings
+	gatewayIP string               // Default gateway, refreshed after each scan
+	reported  map[string]time.Time // Anomaly key -> last report, for the cooldown
+	dirty     bool
+}
+
+// newARPWatch returns an ARP watch without bindings.
+func newARPWatch(settings ARPWatchSettings, gatewayIP string) *arpWatch {
+	return &arpWatch{
+		bindings:  make(map[string]*ARPBinding),
+		current:   make(map[string]*ARPBinding),
+		reported:  make(map[string]time.Time),
+		settings:  settings,
+		gatewayIP: gatewayIP,
+	}
+}
+
+// openARPWatch loads the known IP/MAC bindings. Called on app startup.
+func openARPWatch(ctx context.Context, ev wailsEvents, alerts *alerter) *arpWatch {
+	w := newARPWatch(ARPWatchSettings{Enabled: true, MaxIPsPerMAC: defaultMaxIPsPerMAC, TrustedMACs: []string{}}, defaultGatewayIP())
+	w.ctx, w.events, w.alerts = ctx, ev, alerts
+
+	path, err := arpWatchFilePath()
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("ARP watch path unavailable: %v", err))
+		return w
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			runtime.LogError(ctx, fmt.Sprintf("Error reading ARP bindings '%s': %v", path, err))
+		}
+		return w
+	}
+	var file arpWatchFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error unmarshalling ARP bindings from '%s': %v. Starting fresh.", path, err))
+		_ = os.Rename(path, path+".bak")
+		return w
+	}
+	w.settings = file.Settings
+	for i := range file.Bindings {
+		b := file.Bindings[i]
+		w.bindings[arpBindingKey(b.IPAddress, b.MACAddress)] = &b
+		w.indexBindingLocked(&b)
+	}
+	runtime.LogDebug(ctx, fmt.Sprintf("Loaded %d ARP bindings; default gateway %q.", len(w.bindings), w.gatewayIP))
+	return w
+}
+
+// arpWatchFilePath returns the full path of the ARP bindings file.
+func arpWatchFilePath() (string, error) {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDataDir, arpWatchFilename), nil
+}
+
+func arpBindingKey(ip, mac string) string {
+	return ip + "|" + mac
+}
+
+// indexBindingLocked makes b the current binding of its IP if it was seen more recently than
+// the current one. The caller must hold w.mu.
+func (w *arpWatch) indexBindingLocked(b *ARPBinding) {
+	if current, ok := w.current[b.IPAddress]; !ok || b.LastSeen.After(current.LastSeen) {
+		w.current[b.IPAddress] = b
+	}
+}
+
+// saveLocked persists the settings and bindings. The caller must hold w.mu.
+func (w *arpWatch) saveLocked(ctx context.Context) {
+	path, err := arpWatchFilePath()
+	if err != nil {
+		runtime.LogWarning(ctx, fmt.Sprintf("ARP watch path unavailable, skipping save: %v", err))
+		return
+	}
+	data, err := json.MarshalIndent(arpWatchFile{Settings: w.settings, Bindings: w.snapshotLocked()}, "", "  ")
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error marshalling ARP bindings: %v", err))
+		return
+	}
+	if err := writeFileAtomic(path, data, 0640); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error saving ARP bindings: %v", err))
+		return
+	}
+	w.dirty = false
+}
+
+// snapshotLocked returns the bindings sorted by IP, most recently seen first per IP. The caller
+// must hold w.mu.
+func (w *arpWatch) snapshotLocked() []ARPBinding {
+	bindings := make([]ARPBinding, 0, len(w.bindings))
+	for _, b := range w.bindings {
+		bindings = append(bindings, *b)
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].IPAddress != bindings[j].IPAddress {
+			a, errA := scanner.IPToUint32(bindings[i].IPAddress)
+			b, errB := scanner.IPToUint32(bindings[j].IPAddress)
+			if errA != nil || errB != nil {
+				return bindings[i].IPAddress < bindings[j].IPAddress
+			}
+			return a < b
+		}
+		return bindings[i].LastSeen.After(bindings[j].LastSeen)
+	})
+	return bindings
+}
+
+// flush saves new bindings and refreshes the default gateway. Called when a scan completes.
+func (w *arpWatch) flush(ctx context.Context) {
+	gateway := defaultGatewayIP()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if gateway != "" {
+		w.gatewayIP = gateway
+	}
+	if len(w.bindings) > maxARPBindings {
+		all := w.snapshotLocked()
+		sort.Slice(all, func(i, j int) bool { return all[i].LastSeen.After(all[j].LastSeen) })
+		for _, b := range all[maxARPBindings:] {
+			delete(w.bindings, arpBindingKey(b.IPAddress, b.MACAddress))
+		}
+		w.current = make(map[string]*ARPBinding, len(w.bindings))
+		for _, b := range w.bindings {
+			w.indexBindingLocked(b)
+		}
+		w.dirty = true
+	}
+	if w.dirty {
+		w.saveLocked(ctx)
+	}
+}
+
+// record records the MACs found for ip in the ARP table and reports anomalies in the new
+// state. It is the scanner's OnARPLookup hook, so every scan feeds the detector.
+func (w *arpWatch) record(ip string, macs []string) {
+	if len(macs) == 0 {
+		return
+	}
+	w.mu.Lock()
+	if !w.settings.Enabled {
+		w.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	var due []ARPAnomaly
+	for _, a := range w.observeLocked(ip, macs, now) {
+		if w.shouldReportLocked(a, now) {
+			a.Timestamp = now
+			due = append(due, a)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, a := range due {
+		w.report(a)
+	}
+}
+
+// observeLocked records the MACs found for ip and returns the anomalies the new state shows.
+// The caller must hold w.mu.
+func (w *arpWatch) observeLocked(ip string, macs []string, now time.Time) []ARPAnomaly {
+	var anomalies []ARPAnomaly
+
+	normalized := make([]string, 0, len(macs))
+	for _, m := range macs {
+		if mac := normalizeMAC(m); isUnicastMAC(mac) {
+			normalized = append(normalized, mac)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	if len(normalized) > 1 {
+		// More than one entry for the same IP in a single lookup
+		anomalies = append(anomalies, ARPAnomaly{Type: arpAnomalyDuplicateIP, IPAddress: ip, MACAddress: normalized[0], OtherMACAddresses: normalized[1:]})
+	} else if current := w.currentBindingLocked(ip, now); current != nil && current.MACAddress != normalized[0] {
+		mac := normalized[0]
+		if earlier, ok := w.bindings[arpBindingKey(ip, mac)]; ok && now.Sub(earlier.LastSeen) < arpDuplicateWindow {
+			// The IP went A -> B -> A within the window: both devices are answering
+			anomalies = append(anomalies, ARPAnomaly{Type: arpAnomalyDuplicateIP, IPAddress: ip, MACAddress: mac, OtherMACAddresses: []string{current.MACAddress}})
+		} else {
+			anomalies = append(anomalies, ARPAnomaly{Type: arpAnomalyMACChanged, IPAddress: ip, MACAddress: mac, PreviousMACAddress: current.MACAddress})
+		}
+	}
+
+	// The first MAC is touched last, so it becomes the IP's current binding
+	for i := len(normalized) - 1; i >= 0; i-- {
+		mac := normalized[i]
+		key := arpBindingKey(ip, mac)
+		b, ok := w.bindings[key]
+		if ok {
+			b.LastSeen = now
+		} else {
+			b = &ARPBinding{IPAddress: ip, MACAddress: mac, FirstSeen: now, LastSeen: now}
+			w.bindings[key] = b
+		}
+		w.current[ip] = b
+	}
+	w.dirty = true
+
+	if a := w.checkMACClaimsLocked(normalized[0], now); a != nil {
+		anomalies = append(anomalies, *a)
+	}
+	return anomalies
+}
+
+// currentBindingLocked returns the most recently seen, unexpired binding for ip.
+// The caller must hold w.mu.
+func (w *arpWatch) currentBindingLocked(ip string, now time.Time) *ARPBinding {
+	current := w.current[ip]
+	if current == nil || now.Sub(current.LastSeen) > arpBindingExpiry {
+		return nil
+	}
+	return current
+}
+
+// checkMACClaimsLocked reports a MAC that is currently bound to the gateway and other IPs, or
+// to more IPs than allowed. The caller must hold w.mu.
+func (w *arpWatch) checkMACClaimsLocked(mac string, now time.Time) *ARPAnomaly {
+	for _, trusted := range w.settings.TrustedMACs {
+		if normalizeMAC(trusted) == mac {
+			return nil
+		}
+	}
+	var ips []string
+	seen := make(map[string]bool)
+	for ip, current := range w.current {
+		if current.MACAddress == mac && now.Sub(current.LastSeen) <= arpBindingExpiry {
+			ips = append(ips, ip)
+			seen[ip] = true
+		}
+	}
+	if len(ips) < 2 {
+		return nil
+	}
+	sort.Strings(ips)
+
+	if w.gatewayIP != "" && seen[w.gatewayIP] {
+		var others []string
+		for _, ip := range ips {
+			if ip != w.gatewayIP {
+				others = append(others, ip)
+			}
+		}
+		return &ARPAnomaly{Type: arpAnomalyGatewaySpoof, IPAddress: w.gatewayIP, MACAddress: mac, OtherIPAddresses: others}
+	}
+	limit := w.settings.MaxIPsPerMAC
+	if limit <= 0 {
+		limit = defaultMaxIPsPerMAC
+	}
+	if len(ips) > limit {
+		return &ARPAnomaly{Type: arpAnomalyManyIPs, IPAddress: ips[0], MACAddress: mac, OtherIPAddresses: ips[1:]}
+	}
+	return nil
+}
+
+// shouldReportLocked reports whether an anomaly is due for reporting, i.e. the same anomaly was
+// not reported within the cooldown, and if so records it as reported now. The caller must hold
+// w.mu.
+func (w *arpWatch) shouldReportLocked(a ARPAnomaly, now time.Time) bool {
+	key := a.Type + "|" + a.IPAddress + "|" + a.MACAddress
+	if last, ok := w.reported[key]; ok && now.Sub(last) < arpAnomalyCooldown {
+		return false
+	}
+	w.reported[key] = now
+	return true
+}
+
+// report emits an anomaly and raises an alert.
+func (w *arpWatch) report(a ARPAnomaly) {
+	switch a.Type {
+	case arpAnomalyMACChanged:
+		a.Message = fmt.Sprintf("%s changed MAC from %s to %s", a.IPAddress, a.PreviousMACAddress, a.MACAddress)
+	case arpAnomalyDuplicateIP:
+		a.Message = fmt.Sprintf("IP conflict: %s is answered by %s and %s", a.IPAddress, a.MACAddress, strings.Join(a.OtherMACAddresses, ", "))
+	case arpAnomalyGatewaySpoof:
+		a.Message = fmt.Sprintf("Possible ARP spoofing: gateway %s is claimed by %s, which also answers for %s", a.IPAddress, a.MACAddress, strings.Join(a.OtherIPAddresses, ", "))
+	case arpAnomalyManyIPs:
+		a.Message = fmt.Sprintf("Possible ARP spoofing: %s answers for %d IPs (%s)", a.MACAddress, len(a.OtherIPAddresses)+1, strings.Join(append([]string{a.IPAddress}, a.OtherIPAddresses...), ", "))
+	}
+	runtime.LogWarning(w.ctx, a.Message)
+	w.events.Emit(events.ARPAnomaly, a)
+
+	event := alerting.Event{
+		Type:               alerting.EventARPAnomaly,
+		Timestamp:          a.Timestamp,
+		IPAddress:          a.IPAddress,
+		MACAddress:         a.MACAddress,
+		Anomaly:            a.Type,
+		PreviousMACAddress: a.PreviousMACAddress,
+	}
+	event.Message = fmt.Sprintf("%s: %s", event.Title(), a.Message)
+	w.alerts.raise(event)
+}
+
+// isUnicastMAC rejects broadcast, multicast and all-zero (incomplete) ARP entries.
+func isUnicastMAC(mac string) bool {
+	if len(mac) != 17 || mac == "00:00:00:00:00:00" {
+		return false
+	}
+	firstOctet, err := strconv.ParseUint(mac[:2], 16, 8)
+	if err != nil {
+		return false
+	}
+	return firstOctet&0x01 == 0 // Multicast bit, also set for ff:ff:ff:ff:ff:ff
+}
+
+// defaultGatewayIP returns the IPv4 default gateway, or "" if it cannot be determined.
+func defaultGatewayIP() string {
+	if runtime_go.GOOS == "linux" {
+		data, err := os.ReadFile("/proc/net/route")
+		if err != nil {
+			return ""
+		}
+		return parseProcNetRoute(string(data))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayLookupTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch runtime_go.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "route", "-n", "get", "default")
+	case "windows":
+		cmd = exec.CommandContext(ctx, "route", "print", "-4", "0.0.0.0")
+	default:
+		return ""
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	lines := bufio.NewScanner(strings.NewReader(string(output)))
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		switch {
+		case len(fields) == 2 && fields[0] == "gateway:": // darwin: "gateway: 192.168.1.1"
+			return fields[1]
+		case len(fields) >= 3 && fields[0] == "0.0.0.0" && fields[1] == "0.0.0.0": // windows route table row
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// parseProcNetRoute extracts the default gateway from /proc/net/route, where addresses are
+// little-endian hex.
+func parseProcNetRoute(table string) string {
+	lines := bufio.NewScanner(strings.NewReader(table))
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		gw, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil || gw == 0 {
+			continue
+		}
+		return fmt.Sprintf("%d.%d.%d.%d", byte(gw), byte(gw>>8), byte(gw>>16), byte(gw>>24))
+	}
+	return ""
+}
+
+// GetARPBindings returns the IP/MAC bindings observed so far.
+func (a *App) GetARPBindings() []ARPBinding {
+	a.arpWatch.mu.Lock()
+	defer a.arpWatch.mu.Unlock()
+	return a.arpWatch.snapshotLocked()
+}
+
+// ClearARPBindings forgets all observed bindings, accepting the next scan as the new normal.
+func (a *App) ClearARPBindings() error {
+	a.arpWatch.mu.Lock()
+	defer a.arpWatch.mu.Unlock()
+	a.arpWatch.bindings = make(map[string]*ARPBinding)
+	a.arpWatch.current = make(map[string]*ARPBinding)
+	a.arpWatch.reported = make(map[string]time.Time)
+	a.arpWatch.saveLocked(a.ctx)
+	runtime.LogInfo(a.ctx, "ARP bindings cleared.")
+	return nil
+}
+
+// GetARPWatchSettings returns the ARP anomaly detector settings.
+func (a *App) GetARPWatchSettings() ARPWatchSettings {
+	a.arpWatch.mu.Lock()
+	defer a.arpWatch.mu.Unlock()
+	return a.arpWatch.settings
+}
+
+// SaveARPWatchSettings updates the ARP anomaly detector settings.
+func (a *App) SaveARPWatchSettings(settings ARPWatchSettings) error {
+	if settings.MaxIPsPerMAC < 0 {
+		return fmt.Errorf("maximum IPs per MAC cannot be negative")
+	}
+	for _, m := range settings.TrustedMACs {
+		if len(normalizeMAC(m)) != 17 {
+			return fmt.Errorf("invalid MAC address %q", m)
+		}
+	}
+	if settings.TrustedMACs == nil {
+		settings.TrustedMACs = []string{}
+	}
+
+	a.arpWatch.mu.Lock()
+	defer a.arpWatch.mu.Unlock()
+	a.arpWatch.settings = settings
+	a.arpWatch.saveLocked(a.ctx)
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// observeARP feeds one ARP lookup to the detector and returns the anomaly types found.
+func observeARP(w *arpWatch, at time.Time, ip string, macs ...string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var types []string
+	for _, a := range w.observeLocked(ip, macs, at) {
+		types = append(types, a.Type)
+	}
+	return types
+}
+
+func TestARPAnomalyRules(t *testing.T) {
+	const (
+		macA = "aa:bb:cc:00:00:0a"
+		macB = "AA-BB-CC-00-00-0B"
+		macC = "aa:bb:cc:00:00:0c"
+	)
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	type lookup struct {
+		after time.Duration // Since t0
+		ip    string
+		macs  []string
+		want  []string // Anomaly types
+	}
+	for _, c := range []struct {
+		name     string
+		settings ARPWatchSettings
+		lookups  []lookup
+	}{
+		{"stable binding", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{time.Minute, "192.168.1.20", []string{macA}, nil},
+		}},
+		{"ignored entries", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{"00:00:00:00:00:00", "ff:ff:ff:ff:ff:ff", "01:00:5e:00:00:fb"}, nil},
+			{time.Minute, "192.168.1.20", []string{macA}, nil},
+		}},
+		{"two MACs in one lookup", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{macA, macB}, []string{arpAnomalyDuplicateIP}},
+		}},
+		{"MAC changed", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{time.Minute, "192.168.1.20", []string{macB}, []string{arpAnomalyMACChanged}},
+			{2 * time.Minute, "192.168.1.20", []string{macB}, nil},
+		}},
+		{"IP alternating between MACs", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{time.Minute, "192.168.1.20", []string{macB}, []string{arpAnomalyMACChanged}},
+			{2 * time.Minute, "192.168.1.20", []string{macA}, []string{arpAnomalyDuplicateIP}},
+		}},
+		{"device returning after the duplicate window", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{time.Minute, "192.168.1.20", []string{macB}, []string{arpAnomalyMACChanged}},
+			{3 * time.Hour, "192.168.1.20", []string{macA}, []string{arpAnomalyMACChanged}},
+		}},
+		{"expired binding", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{arpBindingExpiry + time.Minute, "192.168.1.20", []string{macB}, nil},
+		}},
+		{"gateway claimed by another host's MAC", ARPWatchSettings{Enabled: true}, []lookup{
+			{0, "192.168.1.1", []string{macC}, nil},
+			{0, "192.168.1.20", []string{macA}, nil},
+			{time.Minute, "192.168.1.1", []string{macA}, []string{arpAnomalyMACChanged, arpAnomalyGatewaySpoof}},
+		}},
+		{"too many IPs on one MAC", ARPWatchSettings{Enabled: true, MaxIPsPerMAC: 2}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{0, "192.168.1.21", []string{macA}, nil},
+			{0, "192.168.1.22", []string{macA}, []string{arpAnomalyManyIPs}},
+		}},
+		{"IPs that moved away no longer count", ARPWatchSettings{Enabled: true, MaxIPsPerMAC: 2}, []lookup{
+			{0, "192.168.1.20", []string{macA}, nil},
+			{0, "192.168.1.21", []string{macA}, nil},
+			{time.Minute, "192.168.1.21", []string{macB}, []string{arpAnomalyMACChanged}},
+			{time.Minute, "192.168.1.22", []string{macA}, nil},
+		}},
+		{"trusted MAC", ARPWatchSettings{Enabled: true, MaxIPsPerMAC: 1, TrustedMACs: []string{"AA-BB-CC-00-00-0A"}}, []lookup{
+			{0, "192.168.1.1", []string{macA}, nil},
+			{0, "192.168.1.20", []string{macA}, nil},
+			{0, "192.168.1.21", []string{macA}, nil},
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := newARPWatch(c.settings, "192.168.1.1")
+			for i, l := range c.lookups {
+				if got := observeARP(w, t0.Add(l.after), l.ip, l.macs...); !slices.Equal(got, l.want) {
+					t.Errorf("lookup %d (%s -> %v): anomalies %v, want %v", i, l.ip, l.macs, got, l.want)
+				}
+			}
+		})
+	}
+}
+
+func TestARPCurrentBinding(t *testing.T) {
+	w := newARPWatch(ARPWatchSettings{Enabled: true}, "")
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	observeARP(w, t0, "192.168.1.20", "aa:bb:cc:00:00:0a")
+	observeARP(w, t0.Add(time.Minute), "192.168.1.20", "aa:bb:cc:00:00:0b")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if b := w.currentBindingLocked("192.168.1.20", t0.Add(2*time.Minute)); b == nil || b.MACAddress != "AA:BB:CC:00:00:0B" {
+		t.Errorf("current binding = %+v, want the most recent MAC", b)
+	}
+	if b := w.currentBindingLocked("192.168.1.20", t0.Add(arpBindingExpiry+2*time.Minute)); b != nil {
+		t.Errorf("expired binding still current: %+v", b)
+	}
+	if n := len(w.bindings); n != 2 {
+		t.Errorf("%d bindings, want 2", n)
+	}
+}
+
+func TestARPAnomalyCooldown(t *testing.T) {
+	w := newARPWatch(ARPWatchSettings{Enabled: true}, "")
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := ARPAnomaly{Type: arpAnomalyMACChanged, IPAddress: "192.168.1.20", MACAddress: "AA:BB:CC:00:00:0B"}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, c := range []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{time.Minute, false},
+		{arpAnomalyCooldown + time.Second, true},
+	} {
+		if got := w.shouldReportLocked(a, t0.Add(c.after)); got != c.want {
+			t.Errorf("after %v: report %v, want %v", c.after, got, c.want)
+		}
+	}
+}
+
+func TestParseProcNetRoute(t *testing.T) {
+	const header = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n"
+	for _, c := range []struct {
+		name, table, want string
+	}{
+		{"default route", header +
+			"eth0\t0000A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+			"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n", "192.168.1.1"},
+		{"first default route wins", header +
+			"wlan0\t00000000\tFE01000A\t0003\t0\t0\t600\t00000000\t0\t0\t0\n" +
+			"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n", "10.0.1.254"},
+		{"default route without a gateway", header +
+			"tun0\t00000000\t00000000\t0001\t0\t0\t0\t00000000\t0\t0\t0\n" +
+			"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n", "192.168.1.1"},
+		{"no default route", header + "eth0\t0000A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n", ""},
+		{"malformed gateway", header + "eth0\t00000000\tXYZ\t0003\t0\t0\t100\t00000000\t0\t0\t0\n", ""},
+		{"empty", "", ""},
+	} {
+		if got := parseProcNetRoute(c.table); got != c.want {
+			t.Errorf("%s: gateway %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestIsUnicastMAC(t *testing.T) {
+	for mac, want := range map[string]bool{
+		"AA:BB:CC:00:00:0A": true,
+		"00:1A:2B:3C:4D:5E": true,
+		"00:00:00:00:00:00": false,
+		"FF:FF:FF:FF:FF:FF": false,
+		"01:00:5E:00:00:FB": false,
+		"33:33:00:00:00:01": false,
+		"AA:BB:CC":          false,
+	} {
+		if got := isUnicastMAC(mac); got != want {
+			t.Errorf("isUnicastMAC(%q) = %v, want %v", mac, got, want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"netview/events"
+	"netview/scanner"
+)
+
+// Exit codes of the command line.
+const (
+	exitOK          = 0
+	exitError       = 1   // The scan could not run
+	exitUsage       = 2   // Invalid arguments or targets
+	exitInterrupted = 130 // Stopped by Ctrl+C / SIGTERM
+)
+
+const maxCLIAddresses = 1 << 16 // A /16; larger targets are almost always a typo
+
+// scanTimingProfiles are the -T presets of `netview scan`.
+var scanTimingProfiles = map[string]scanner.Timing{
+	"polite":     {Concurrency: 16, PingTimeout: 2 * time.Second, TCPPingTimeout: 500 * time.Millisecond, PortTimeout: time.Second},
+	"normal":     scanner.DefaultTiming,
+	"aggressive": {Concurrency: 256, PingTimeout: 500 * time.Millisecond, TCPPingTimeout: 100 * time.Millisecond, PortTimeout: 250 * time.Millisecond},
+}
+
+// cliCommands lists the subcommands that run headless instead of opening the window.
+var cliCommands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"scan": runScanCommand,
+}
+
+// runScanCommand implements `netview scan [flags] TARGET...`. Hosts are written to stdout as
+// they are found; diagnostics and the final summary go to stderr.
+func runScanCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	portSpec := fs.String("p", "", "service ports to scan, e.g. 22,80,8000-8100 (default "+joinPortNumbers(scanner.DefaultPorts)+")")
+	timingName := fs.String("T", "normal", "timing profile: polite, normal or aggressive")
+	format := fs.String("o", "table", "output format: table, jsonl or csv")
+	hiddenSpec := fs.String("hidden", "", "also treat hosts as up if one of these ports answers, e.g. 22,3389")
+	verbose := fs.Bool("v", false, "print probe diagnostics to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: netview scan [flags] TARGET...\n\n")
+		fmt.Fprintf(stderr, "Targets are IPv4 addresses, CIDR blocks (192.168.1.0/24), ranges\n")
+		fmt.Fprintf(stderr, "(10.0.0.1-10.0.0.50 or 10.0.0.1-50) or host names.\n\nFlags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExit status: 0 on success, 1 if the scan failed, 2 for invalid arguments, 130 if interrupted.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	usageError := func(format string, args ...any) int {
+		fmt.Fprintf(stderr, "netview scan: "+format+"\n", args...)
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	targets, err := parseTargets(fs.Args())
+	if err != nil {
+		return usageError("%v", err)
+	}
+	addresses := 0
+	for _, t := range targets {
+		addresses += t.Size()
+	}
+	if addresses > maxCLIAddresses {
+		return usageError("%d addresses requested; at most %d can be scanned at once", addresses, maxCLIAddresses)
+	}
+	servicePorts := scanner.DefaultPorts
+	if *portSpec != "" {
+		if servicePorts, err = parsePortSpec(*portSpec); err != nil {
+			return usageError("-p: %v", err)
+		}
+	}
+	params := ScanRange{StartIP: scanner.Uint32ToIP(targets[0].Start), EndIP: scanner.Uint32ToIP(targets[len(targets)-1].End), Ports: servicePorts}
+	if *hiddenSpec != "" {
+		if params.HiddenHostsPorts, err = parsePortSpec(*hiddenSpec); err != nil {
+			return usageError("-hidden: %v", err)
+		}
+		params.SearchHiddenHosts = true
+	}
+	timing, ok := scanTimingProfiles[*timingName]
+	if !ok {
+		return usageError("unknown timing profile %q", *timingName)
+	}
+	out, err := newCLIScanOutput(*format, stdout)
+	if err != nil {
+		return usageError("%v", err)
+	}
+
+	// Keep stdout for results: probe diagnostics go to stderr, or nowhere
+	s := scanner.New(events.Discard, cliLog{w: stderr, verbose: *verbose})
+	s.Timing = timing
+	if db, err := loadOuiDatabase(); err == nil {
+		s.Vendors = db
+	} else {
+		fmt.Fprintf(stderr, "netview scan: vendor lookup unavailable: %v\n", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	startedAt := time.Now()
+	if err := out.begin(); err != nil {
+		fmt.Fprintf(stderr, "netview scan: %v\n", err)
+		return exitError
+	}
+	hosts, completed := s.Run(ctx, targets, params, out)
+	if err := out.end(); err != nil {
+		fmt.Fprintf(stderr, "netview scan: %v\n", err)
+		return exitError
+	}
+	if out.err != nil {
+		fmt.Fprintf(stderr, "netview scan: writing results: %v\n", out.err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "%d of %d addresses up, scanned in %.1fs\n", len(hosts), addresses, time.Since(startedAt).Seconds())
+	if !completed {
+		fmt.Fprintln(stderr, "netview scan: interrupted")
+		return exitInterrupted
+	}
+	return exitOK
+}
+
+// parseTargets turns target specs into address ranges, in the order given.
+func parseTargets(specs []string) ([]scanner.Range, error) {
+	var targets []scanner.Range
+	for _, spec := range specs {
+		r, err := parseTargetSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, r)
+	}
+	return targets, nil
+}
+
+// parseTargetSpec parses one target: an address, a CIDR block, a range ("a.b.c.d-e.f.g.h" or
+// "a.b.c.d-h") or a host name, which is resolved to its first IPv4 address.
+func parseTargetSpec(spec string) (scanner.Range, error) {
+	spec = strings.TrimSpace(spec)
+	if _, network, err := net.ParseCIDR(spec); err == nil {
+		ip4 := network.IP.To4()
+		if ip4 == nil {
+			return scanner.Range{}, fmt.Errorf("%s: only IPv4 is supported", spec)
+		}
+		ones, _ := network.Mask.Size()
+		start, _ := scanner.IPToUint32(ip4.String())
+		return scanner.Range{Start: start, End: start | (1<<(32-ones) - 1)}, nil
+	}
+	// A dash only makes a range after an address; host names such as "web-01" may contain one
+	if from, to, ok := strings.Cut(spec, "-"); ok && net.ParseIP(from).To4() != nil {
+		start, err := scanner.IPToUint32(from)
+		if err != nil {
+			return scanner.Range{}, fmt.Errorf("%s: invalid start address", spec)
+		}
+		if !strings.Contains(to, ".") {
+			// Short form: only the last octet of the end address
+			octet, err := strconv.Atoi(to)
+			if err != nil || octet < 0 || octet > 255 {
+				return scanner.Range{}, fmt.Errorf("%s: invalid end of range", spec)
+			}
+			to = from[:strings.LastIndex(from, ".")+1] + to
+		}
+		end, err := scanner.IPToUint32(to)
+		if err != nil {
+			return scanner.Range{}, fmt.Errorf("%s: invalid end address", spec)
+		}
+		if start > end {
+			return scanner.Range{}, fmt.Errorf("%s: start address is after end address", spec)
+		}
+		return scanner.Range{Start: start, End: end}, nil
+	}
+	if n, err := scanner.IPToUint32(spec); err == nil {
+		return scanner.Range{Start: n, End: n}, nil
+	}
+	addrs, err := net.LookupIP(spec)
+	if err != nil {
+		return scanner.Range{}, fmt.Errorf("%s: %v", spec, err)
+	}
+	for _, a := range addrs {
+		if ip4 := a.To4(); ip4 != nil {
+			n, _ := scanner.IPToUint32(ip4.String())
+			return scanner.Range{Start: n, End: n}, nil
+		}
+	}
+	return scanner.Range{}, fmt.Errorf("%s: no IPv4 address", spec)
+}
+
+// parsePortSpec parses a port list such as "22,80,8000-8100".
+func parsePortSpec(spec string) ([]int, error) {
+	seen := map[int]bool{}
+	var ports []int
+	add := func(p int) {
+		if !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(item, "-")
+		low, err := strconv.Atoi(from)
+		if err != nil || low < 1 || low > 65535 {
+			return nil, fmt.Errorf("invalid port %q", item)
+		}
+		high := low
+		if isRange {
+			if high, err = strconv.Atoi(to); err != nil || high < low || high > 65535 {
+				return nil, fmt.Errorf("invalid port range %q", item)
+			}
+		}
+		for p := low; p <= high; p++ {
+			add(p)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// formatPortSpec formats ports as a list parsePortSpec reads back, in ascending order with
+// consecutive ports as ranges: "22,80,8000-8100".
+func formatPortSpec(ports []int) string {
+	ports = sortedInts(intSet(ports))
+	var parts []string
+	for i := 0; i < len(ports); {
+		j := i
+		for j+1 < len(ports) && ports[j+1] == ports[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", ports[i], ports[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(ports[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// cliScanOutput streams hosts to stdout in the chosen format. It is the scanner.Observer of a
+// command-line scan.
+type cliScanOutput struct {
+	mu     sync.Mutex
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	err    error // First write error
+}
+
+// cliTableFormat lays out the table columns.
+const cliTableFormat = "%-15s  %-28s  %-17s  %-24s  %-14s  %s\n"
+
+// cliCSVFields are the host fields written by -o csv.
+var cliCSVFields = []string{"ipAddress", "hostname", "macAddress", "vendor", "deviceType", "openPorts", "services"}
+
+func newCLIScanOutput(format string, w io.Writer) (*cliScanOutput, error) {
+	switch format {
+	case "table", "jsonl":
+		return &cliScanOutput{format: format, w: w}, nil
+	case "csv":
+		return &cliScanOutput{format: format, w: w, csv: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// begin writes the header, if the format has one.
+func (o *cliScanOutput) begin() error {
+	switch o.format {
+	case "table":
+		_, err := fmt.Fprintf(o.w, cliTableFormat, "IP ADDRESS", "HOSTNAME", "MAC ADDRESS", "VENDOR", "TYPE", "OPEN PORTS")
+		return err
+	case "csv":
+		o.csv.Write(fieldTitles(cliCSVFields))
+		o.csv.Flush()
+		return o.csv.Error()
+	}
+	return nil
+}
+
+// end flushes the output.
+func (o *cliScanOutput) end() error {
+	if o.csv != nil {
+		o.csv.Flush()
+		return o.csv.Error()
+	}
+	return nil
+}
+
+// HostFound writes one host, flushing immediately so results can be piped as they arrive.
+func (o *cliScanOutput) HostFound(h Host) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return
+	}
+	switch o.format {
+	case "table":
+		_, o.err = fmt.Fprintf(o.w, cliTableFormat, h.IPAddress, truncate(h.Hostname, 28), h.MACAddress, truncate(hostVendor(h), 24), h.DeviceType, joinPortNumbers(h.OpenPorts))
+	case "jsonl":
+		var line []byte
+		if line, o.err = json.Marshal(h); o.err == nil {
+			_, o.err = o.w.Write(append(line, '\n'))
+		}
+	case "csv":
+		row := make([]string, len(cliCSVFields))
+		for i, f := range cliCSVFields {
+			row[i] = hostFieldText(h, f)
+		}
+		o.csv.Write(csvSafeRow(row))
+		o.csv.Flush()
+		o.err = o.csv.Error()
+	}
+}
+
+// cliLog writes scanner diagnostics to stderr. Debug output is only shown with -v.
+type cliLog struct {
+	w       io.Writer
+	verbose bool
+}
+
+func (l cliLog) Debug(message string) {
+	if l.verbose {
+		fmt.Fprintln(l.w, message)
+	}
+}
+func (l cliLog) Info(message string)    { l.Debug(message) }
+func (l cliLog) Warning(message string) { fmt.Fprintln(l.w, "netview scan: "+message) }
+func (l cliLog) Error(message string)   { fmt.Fprintln(l.w, "netview scan: "+message) }
+
+// truncate shortens s to at most n characters for a table column.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"netview/scanner"
+)
+
+func TestParseTargetSpec(t *testing.T) {
+	ip := func(s string) uint32 {
+		n, err := scanner.IPToUint32(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	for _, c := range []struct {
+		spec       string
+		start, end string
+		wantErr    string // Substring of the error; empty if the spec is valid
+	}{
+		{"192.168.1.10", "192.168.1.10", "192.168.1.10", ""},
+		{" 192.168.1.10 ", "192.168.1.10", "192.168.1.10", ""},
+		{"192.168.1.0/24", "192.168.1.0", "192.168.1.255", ""},
+		{"192.168.1.77/24", "192.168.1.0", "192.168.1.255", ""}, // Host bits are ignored
+		{"10.0.0.0/15", "10.0.0.0", "10.1.255.255", ""},
+		{"10.0.0.5/32", "10.0.0.5", "10.0.0.5", ""},
+		{"10.0.0.1-10.0.0.50", "10.0.0.1", "10.0.0.50", ""},
+		{"10.0.0.250-10.0.1.4", "10.0.0.250", "10.0.1.4", ""},
+		{"10.0.0.1-50", "10.0.0.1", "10.0.0.50", ""},
+		{"10.0.0.7-7", "10.0.0.7", "10.0.0.7", ""},
+		{"10.0.0.1-255", "10.0.0.1", "10.0.0.255", ""},
+		{"fd00::/64", "", "", "only IPv4"},
+		{"10.0.0.50-10", "", "", "start address is after end address"},
+		{"10.0.1.1-10.0.0.1", "", "", "start address is after end address"},
+		{"10.0.0.1-256", "", "", "invalid end of range"},
+		{"10.0.0.1-x", "", "", "invalid end of range"},
+		{"10.0.0.1-", "", "", "invalid end of range"},
+		{"10.0.0-10.0.0.9", "", "", "lookup 10.0.0-10.0.0.9"}, // Not an address, so a host name
+		{"web-01.invalid", "", "", "lookup web-01.invalid"},   // Resolved, not split at the dash
+		{"10.0.0.1-10.0.0", "", "", "invalid end address"},
+		{"10.0.0.1-10.0.0.999", "", "", "invalid end address"},
+	} {
+		got, err := parseTargetSpec(c.spec)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("parseTargetSpec(%q) = %v", c.spec, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("parseTargetSpec(%q) error = %v, want one mentioning %q", c.spec, err, c.wantErr)
+		case c.wantErr == "" && got != (scanner.Range{Start: ip(c.start), End: ip(c.end)}):
+			t.Errorf("parseTargetSpec(%q) = %s - %s, want %s - %s", c.spec, scanner.Uint32ToIP(got.Start), scanner.Uint32ToIP(got.End), c.start, c.end)
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := parseTargets([]string{"10.0.0.9", "192.168.1.0/30"})
+	if err != nil || len(targets) != 2 || targets[0].Size() != 1 || targets[1].Size() != 4 {
+		t.Errorf("parseTargets = %+v, %v", targets, err)
+	}
+	if _, err := parseTargets([]string{"10.0.0.1", "10.0.0.9-1"}); err == nil || !strings.HasPrefix(err.Error(), "10.0.0.9-1: ") {
+		t.Errorf("error %v does not name the invalid target", err)
+	}
+}
+
+func TestParsePortSpec(t *testing.T) {
+	for _, c := range []struct {
+		spec    string
+		want    []int
+		wantErr string
+	}{
+		{"22", []int{22}, ""},
+		{"443,22,80", []int{22, 80, 443}, ""},
+		{" 22 , 80 ,", []int{22, 80}, ""},
+		{"8000-8003", []int{8000, 8001, 8002, 8003}, ""},
+		{"8080,8079-8081,22,22", []int{22, 8079, 8080, 8081}, ""},
+		{"1,65535", []int{1, 65535}, ""},
+		{"", nil, "no ports given"},
+		{" , ", nil, "no ports given"},
+		{"0", nil, `invalid port "0"`},
+		{"65536", nil, `invalid port "65536"`},
+		{"ssh", nil, `invalid port "ssh"`},
+		{"-80", nil, `invalid port "-80"`},
+		{"80-22", nil, `invalid port range "80-22"`},
+		{"80-", nil, `invalid port range "80-"`},
+		{"65000-70000", nil, `invalid port range "65000-70000"`},
+	} {
+		got, err := parsePortSpec(c.spec)
+		switch {
+		case c.wantErr == "" && (err != nil || !reflect.DeepEqual(got, c.want)):
+			t.Errorf("parsePortSpec(%q) = %v, %v; want %v", c.spec, got, err, c.want)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("parsePortSpec(%q) error = %v, want one mentioning %q", c.spec, err, c.wantErr)
+		}
+	}
+}
+
+func TestFormatPortSpec(t *testing.T) {
+	for _, c := range []struct {
+		ports []int
+		want  string
+	}{
+		{nil, ""},
+		{[]int{22}, "22"},
+		{[]int{443, 22, 80}, "22,80,443"},
+		{[]int{22, 80, 8000, 8001, 8002, 8003, 8004, 8005}, "22,80,8000-8005"},
+		{[]int{1, 2, 4, 5, 5, 7}, "1-2,4-5,7"},
+	} {
+		got := formatPortSpec(c.ports)
+		if got != c.want {
+			t.Errorf("formatPortSpec(%v) = %q, want %q", c.ports, got, c.want)
+		}
+		if back, err := parsePortSpec(got); len(c.ports) > 0 && (err != nil || !reflect.DeepEqual(back, sortedInts(intSet(c.ports)))) {
+			t.Errorf("%q reads back as %v, %v", got, back, err)
+		}
+	}
+}
+
+func TestRunScanCommandUsage(t *testing.T) {
+	for _, c := range []struct {
+		args    []string
+		want    int
+		wantErr string // Substring of stderr
+	}{
+		{[]string{"-h"}, exitOK, "Usage: netview scan"},
+		{[]string{}, exitUsage, "Usage: netview scan"},
+		{[]string{"-x", "10.0.0.1"}, exitUsage, "flag provided but not defined: -x"},
+		{[]string{"10.0.0.9-1"}, exitUsage, "netview scan: 10.0.0.9-1: start address is after end address"},
+		{[]string{"10.0.0.0/8"}, exitUsage, "16777216 addresses requested; at most 65536"},
+		{[]string{"10.0.0.0/16", "10.1.0.1"}, exitUsage, "65537 addresses requested"},
+		{[]string{"-p", "22,http", "10.0.0.1"}, exitUsage, `-p: invalid port "http"`},
+		{[]string{"-hidden", "0", "10.0.0.1"}, exitUsage, `-hidden: invalid port "0"`},
+		{[]string{"-T", "insane", "10.0.0.1"}, exitUsage, `unknown timing profile "insane"`},
+		{[]string{"-o", "xml", "10.0.0.1"}, exitUsage, `unknown output format "xml"`},
+	} {
+		var stdout, stderr bytes.Buffer
+		if got := runScanCommand(c.args, &stdout, &stderr); got != c.want || !strings.Contains(stderr.String(), c.wantErr) {
+			t.Errorf("scan %q: exit %d, stderr %q; want exit %d mentioning %q", c.args, got, stderr.String(), c.want, c.wantErr)
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("scan %q wrote to stdout: %q", c.args, stdout.String())
+		}
+	}
+}
+
+func TestCLIScanOutput(t *testing.T) {
+	hosts := []Host{
+		{IPAddress: "192.168.1.10", Hostname: "a-very-long-hostname.example.internal", MACAddress: "AA:BB:CC:00:00:10", Vendor: "Acme",
+			DeviceType: "NAS", OpenPorts: []int{22, 80}, Services: []ServiceInfo{{Port: 22, Name: "ssh", Version: "OpenSSH_9.6p1"}}},
+		{IPAddress: "192.168.1.11", Hostname: "comma, \"quote\""},
+	}
+	for _, c := range []struct {
+		format string
+		want   string
+	}{
+		{"table", "IP ADDRESS       HOSTNAME                      MAC ADDRESS        VENDOR                    TYPE            OPEN PORTS\n" +
+			"192.168.1.10     a-very-long-hostname.exampl…  AA:BB:CC:00:00:10  Acme                      NAS             22, 80\n" +
+			"192.168.1.11     comma, \"quote\"" + strings.Repeat(" ", 77) + "\n"}, // Empty columns keep their width
+		{"jsonl", `{"ipAddress":"192.168.1.10","hostname":"a-very-long-hostname.example.internal","macAddress":"AA:BB:CC:00:00:10",` +
+			`"vendor":"Acme","openPorts":[22,80],"services":[{"port":22,"name":"ssh","version":"OpenSSH_9.6p1"}],"deviceType":"NAS"}` + "\n" +
+			`{"ipAddress":"192.168.1.11","hostname":"comma, \"quote\""}` + "\n"},
+		{"csv", "IP Address,Hostname,MAC Address,Vendor,Device Type,Open Ports,Services\n" +
+			"192.168.1.10,a-very-long-hostname.example.internal,AA:BB:CC:00:00:10,Acme,NAS,\"22, 80\",22/ssh OpenSSH_9.6p1\n" +
+			"192.168.1.11,\"comma, \"\"quote\"\"\",,,,,\n"},
+	} {
+		var buf bytes.Buffer
+		out, err := newCLIScanOutput(c.format, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := out.begin(); err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hosts {
+			out.HostFound(h)
+		}
+		if err := out.end(); err != nil || out.err != nil {
+			t.Fatal(err, out.err)
+		}
+		if buf.String() != c.want {
+			t.Errorf("%s output:\n%s\nwant\n%s", c.format, buf.String(), c.want)
+		}
+	}
+}
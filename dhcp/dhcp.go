@@ -0,0 +1,222 @@
+// Package dhcp discovers the DHCP servers answering on a network segment. It broadcasts a
+// DHCPDISCOVER and collects the DHCPOFFERs without ever requesting a lease, so probing has no
+// effect on the network beyond the offers the servers send back.
+package dhcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Offer is a DHCPOFFER received in response to a discover.
+type Offer struct {
+	ServerID      string   `json:"serverId"`      // Server identifier (option 54); the address allowlists match on
+	ServerAddress string   `json:"serverAddress"` // Source address of the offer (the server, or a relay agent)
+	OfferedIP     string   `json:"offeredIp"`
+	SubnetMask    string   `json:"subnetMask,omitempty"`
+	Gateways      []string `json:"gateways"`
+	DNSServers    []string `json:"dnsServers"`
+	LeaseSeconds  uint32   `json:"leaseSeconds"`
+}
+
+// DHCP message types (option 53).
+const (
+	messageDiscover = 1
+	messageOffer    = 2
+)
+
+// DHCP options used by the probe.
+const (
+	optSubnetMask     = 1
+	optRouter         = 3
+	optDNS            = 6
+	optLeaseTime      = 51
+	optMessageType    = 53
+	optServerID       = 54
+	optParameterList  = 55
+	optClientID       = 61
+	optPad            = 0
+	optEnd            = 255
+	headerLength      = 236
+	minimumPacketSize = 300 // Some servers ignore BOOTP-sized packets smaller than this
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+const (
+	serverPort = 67
+	clientPort = 68
+)
+
+// ErrNoHardwareAddress is returned for interfaces without a MAC address (e.g. loopback).
+var ErrNoHardwareAddress = errors.New("interface has no hardware address")
+
+// Discover broadcasts a DHCPDISCOVER on iface and returns every offer received before the
+// timeout or ctx's deadline. Cancelling ctx stops listening at once and returns the offers
+// received so far along with context.Canceled. Listening on the DHCP client port usually
+// requires administrator privileges.
+func Discover(ctx context.Context, iface *net.Interface, timeout time.Duration) ([]Offer, error) {
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("%s: %w", iface.Name, ErrNoHardwareAddress)
+	}
+
+	lc := net.ListenConfig{Control: socketControl(iface.Name)}
+	conn, err := lc.ListenPacket(ctx, "udp4", fmt.Sprintf("0.0.0.0:%d", clientPort))
+	if err != nil {
+		return nil, fmt.Errorf("listening on DHCP client port %d (administrator privileges are usually required): %w", clientPort, err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() }) // Unblocks ReadFrom on cancellation
+	defer stop()
+
+	xid := make([]byte, 4)
+	if _, err := rand.Read(xid); err != nil {
+		return nil, err
+	}
+	packet := BuildDiscover(binary.BigEndian.Uint32(xid), iface.HardwareAddr)
+	broadcast := &net.UDPAddr{IP: net.IPv4bcast, Port: serverPort}
+	if _, err := conn.WriteTo(packet, broadcast); err != nil {
+		return nil, fmt.Errorf("sending DHCPDISCOVER on %s: %w", iface.Name, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	offers := []Offer{}
+	seen := make(map[string]bool)
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if err := ctx.Err(); errors.Is(err, context.Canceled) {
+				return offers, err
+			} else if err != nil {
+				return offers, nil // ctx's deadline ended the collection window
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return offers, nil // Collection window over
+			}
+			return offers, err
+		}
+		offer, ok := ParseOffer(buf[:n], binary.BigEndian.Uint32(xid), iface.HardwareAddr)
+		if !ok {
+			continue // Another client's traffic, or not an offer
+		}
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			offer.ServerAddress = udpAddr.IP.String()
+		}
+		if offer.ServerID == "" {
+			offer.ServerID = offer.ServerAddress
+		}
+		key := offer.ServerID + "|" + offer.OfferedIP
+		if seen[key] {
+			continue // Servers may repeat an offer
+		}
+		seen[key] = true
+		offers = append(offers, offer)
+	}
+}
+
+// BuildDiscover returns a DHCPDISCOVER packet with the broadcast flag set, so servers answer
+// to the broadcast address rather than an IP the client does not have.
+func BuildDiscover(xid uint32, mac net.HardwareAddr) []byte {
+	p := make([]byte, headerLength, minimumPacketSize)
+	p[0] = 1 // op: BOOTREQUEST
+	p[1] = 1 // htype: Ethernet
+	p[2] = 6 // hlen
+	binary.BigEndian.PutUint32(p[4:8], xid)
+	binary.BigEndian.PutUint16(p[10:12], 0x8000) // flags: broadcast
+	copy(p[28:34], mac)                          // chaddr
+
+	p = append(p, magicCookie...)
+	p = append(p, optMessageType, 1, messageDiscover)
+	p = append(p, optClientID, 7, 1) // Hardware type 1 followed by the MAC
+	p = append(p, mac...)
+	p = append(p, optParameterList, 4, optSubnetMask, optRouter, optDNS, optLeaseTime)
+	p = append(p, optEnd)
+	for len(p) < minimumPacketSize {
+		p = append(p, optPad)
+	}
+	return p
+}
+
+// ParseOffer decodes a DHCPOFFER for the given transaction and client MAC. It reports false
+// for anything else.
+func ParseOffer(p []byte, xid uint32, mac net.HardwareAddr) (Offer, bool) {
+	if len(p) < headerLength+len(magicCookie) || p[0] != 2 {
+		return Offer{}, false // Too short, or not a BOOTREPLY
+	}
+	if binary.BigEndian.Uint32(p[4:8]) != xid || !bytes.Equal(p[28:34], mac) {
+		return Offer{}, false
+	}
+	if !bytes.Equal(p[headerLength:headerLength+4], magicCookie) {
+		return Offer{}, false
+	}
+
+	offer := Offer{OfferedIP: net.IP(p[16:20]).String(), Gateways: []string{}, DNSServers: []string{}}
+	messageType := byte(0)
+	options := p[headerLength+4:]
+	for i := 0; i < len(options); {
+		code := options[i]
+		if code == optPad {
+			i++
+			continue
+		}
+		if code == optEnd || i+1 >= len(options) {
+			break
+		}
+		length := int(options[i+1])
+		if i+2+length > len(options) {
+			break // Truncated option
+		}
+		value := options[i+2 : i+2+length]
+		switch code {
+		case optMessageType:
+			if length == 1 {
+				messageType = value[0]
+			}
+		case optServerID:
+			if length == 4 {
+				offer.ServerID = net.IP(value).String()
+			}
+		case optSubnetMask:
+			if length == 4 {
+				offer.SubnetMask = net.IP(value).String()
+			}
+		case optRouter:
+			offer.Gateways = append(offer.Gateways, ipList(value)...)
+		case optDNS:
+			offer.DNSServers = append(offer.DNSServers, ipList(value)...)
+		case optLeaseTime:
+			if length == 4 {
+				offer.LeaseSeconds = binary.BigEndian.Uint32(value)
+			}
+		}
+		i += 2 + length
+	}
+	if messageType != messageOffer {
+		return Offer{}, false
+	}
+	return offer, true
+}
+
+// ipList decodes a list of IPv4 addresses from an option value.
+func ipList(value []byte) []string {
+	var ips []string
+	for i := 0; i+4 <= len(value); i += 4 {
+		ips = append(ips, net.IP(value[i:i+4]).String())
+	}
+	return ips
+}
@@ -0,0 +1,147 @@
+package dhcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var testMAC = net.HardwareAddr{0x02, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+const testXID = 0x1234abcd
+
+// buildOffer answers a discover the way a DHCP server would: a BOOTREPLY for the same
+// transaction and client, offering 192.168.1.50 with the given options.
+func buildOffer(discover []byte, options ...[]byte) []byte {
+	p := make([]byte, headerLength)
+	copy(p, discover[:headerLength])
+	p[0] = 2                                        // op: BOOTREPLY
+	copy(p[16:20], net.IPv4(192, 168, 1, 50).To4()) // yiaddr
+	p = append(p, magicCookie...)
+	for _, o := range options {
+		p = append(p, o...)
+	}
+	return append(p, optEnd)
+}
+
+func option(code byte, value ...byte) []byte {
+	return append([]byte{code, byte(len(value))}, value...)
+}
+
+func TestBuildDiscover(t *testing.T) {
+	p := BuildDiscover(testXID, testMAC)
+	if len(p) < minimumPacketSize {
+		t.Errorf("discover is %d bytes, want at least %d", len(p), minimumPacketSize)
+	}
+	if p[0] != 1 || p[1] != 1 || p[2] != 6 {
+		t.Errorf("op/htype/hlen = %d/%d/%d", p[0], p[1], p[2])
+	}
+	if xid := binary.BigEndian.Uint32(p[4:8]); xid != testXID {
+		t.Errorf("xid = %#x", xid)
+	}
+	if flags := binary.BigEndian.Uint16(p[10:12]); flags != 0x8000 {
+		t.Errorf("flags = %#x, want the broadcast flag", flags)
+	}
+	if !bytes.Equal(p[28:34], testMAC) {
+		t.Errorf("chaddr = % x", p[28:34])
+	}
+	if !bytes.Equal(p[headerLength:headerLength+4], magicCookie) {
+		t.Errorf("magic cookie = % x", p[headerLength:headerLength+4])
+	}
+	wantOptions := []byte{
+		optMessageType, 1, messageDiscover,
+		optClientID, 7, 1, 0x02, 0x11, 0x22, 0x33, 0x44, 0x55,
+		optParameterList, 4, optSubnetMask, optRouter, optDNS, optLeaseTime,
+		optEnd,
+	}
+	if got := p[headerLength+4 : headerLength+4+len(wantOptions)]; !bytes.Equal(got, wantOptions) {
+		t.Errorf("options = % x, want % x", got, wantOptions)
+	}
+	for i, b := range p[headerLength+4+len(wantOptions):] {
+		if b != optPad {
+			t.Fatalf("padding byte %d = %#x", i, b)
+		}
+	}
+}
+
+func TestParseOfferRoundTrip(t *testing.T) {
+	offer := buildOffer(BuildDiscover(testXID, testMAC),
+		option(optMessageType, messageOffer),
+		[]byte{optPad, optPad},
+		option(optServerID, 192, 168, 1, 1),
+		option(optSubnetMask, 255, 255, 255, 0),
+		option(optRouter, 192, 168, 1, 1, 192, 168, 1, 2),
+		option(optDNS, 1, 1, 1, 1),
+		option(optLeaseTime, 0, 1, 0x51, 0x80),
+		option(12, 'r', 'o', 'u', 't', 'e', 'r'), // Host name: ignored
+	)
+	got, ok := ParseOffer(offer, testXID, testMAC)
+	if !ok {
+		t.Fatal("offer rejected")
+	}
+	want := Offer{
+		ServerID:     "192.168.1.1",
+		OfferedIP:    "192.168.1.50",
+		SubnetMask:   "255.255.255.0",
+		Gateways:     []string{"192.168.1.1", "192.168.1.2"},
+		DNSServers:   []string{"1.1.1.1"},
+		LeaseSeconds: 86400,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("offer = %+v, want %+v", got, want)
+	}
+
+	// Only the message type is required
+	got, ok = ParseOffer(buildOffer(BuildDiscover(testXID, testMAC), option(optMessageType, messageOffer)), testXID, testMAC)
+	if !ok || got.ServerID != "" || got.OfferedIP != "192.168.1.50" || got.Gateways == nil || got.DNSServers == nil {
+		t.Errorf("minimal offer = %+v, %v", got, ok)
+	}
+}
+
+func TestParseOfferRejects(t *testing.T) {
+	discover := BuildDiscover(testXID, testMAC)
+	valid := buildOffer(discover, option(optMessageType, messageOffer), option(optServerID, 192, 168, 1, 1))
+	modified := func(change func(p []byte) []byte) []byte {
+		return change(append([]byte(nil), valid...))
+	}
+
+	for name, p := range map[string][]byte{
+		"empty":                   nil,
+		"header only":             valid[:headerLength],
+		"own discover":            discover,
+		"other xid":               modified(func(p []byte) []byte { p[7]++; return p }),
+		"other client":            modified(func(p []byte) []byte { p[33]++; return p }),
+		"bad cookie":              modified(func(p []byte) []byte { p[headerLength] = 0; return p }),
+		"no message type":         buildOffer(discover, option(optServerID, 192, 168, 1, 1)),
+		"ack":                     buildOffer(discover, option(optMessageType, 5)),
+		"bad message type length": buildOffer(discover, option(optMessageType, messageOffer, 0)),
+		"truncated before message type": append(buildOffer(discover, option(optServerID, 192, 168, 1, 1))[:headerLength+4],
+			optServerID, 4, 192, 168, optMessageType, 1, messageOffer),
+		"message type after end": append(buildOffer(discover), option(optMessageType, messageOffer)...),
+	} {
+		if offer, ok := ParseOffer(p, testXID, testMAC); ok {
+			t.Errorf("%s: accepted as %+v", name, offer)
+		}
+	}
+
+	// Malformed optional fields are skipped rather than misread
+	p := buildOffer(discover,
+		option(optMessageType, messageOffer),
+		option(optServerID, 192, 168, 1),
+		option(optSubnetMask, 255, 255),
+		option(optRouter, 10, 0, 0, 1, 10, 0),
+		option(optLeaseTime, 1),
+	)
+	offer, ok := ParseOffer(p, testXID, testMAC)
+	if !ok || offer.ServerID != "" || offer.SubnetMask != "" || !reflect.DeepEqual(offer.Gateways, []string{"10.0.0.1"}) || offer.LeaseSeconds != 0 {
+		t.Errorf("offer with malformed options = %+v, %v", offer, ok)
+	}
+
+	// An option running past the end of the packet ends parsing
+	truncated := append(buildOffer(discover, option(optMessageType, messageOffer))[:headerLength+4+3], optDNS, 8, 1, 1, 1, 1)
+	if offer, ok := ParseOffer(truncated, testXID, testMAC); !ok || len(offer.DNSServers) != 0 {
+		t.Errorf("offer with a truncated option = %+v, %v", offer, ok)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package dhcp
+
+import "syscall"
+
+// socketControl enables address reuse (a DHCP client may already own port 68) and broadcast,
+// and binds the socket to the interface so the discover leaves, and offers arrive, only there.
+func socketControl(ifaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); sockErr != nil {
+				return
+			}
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); sockErr != nil {
+				return
+			}
+			sockErr = syscall.BindToDevice(int(fd), ifaceName)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,24 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || ios || netbsd || openbsd || solaris
+
+package dhcp
+
+import "syscall"
+
+// socketControl enables address reuse (a DHCP client may already own port 68) and broadcast.
+// Binding to a device is Linux-only; elsewhere the discover leaves through the interface the
+// OS picks for broadcasts.
+func socketControl(ifaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); sockErr != nil {
+				return
+			}
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,88 @@
+// Package events defines how NetView's core packages (scanner, monitor, history) report what
+// they do without depending on the Wails runtime: events go to a Sink and diagnostics to a
+// Logger. The desktop app adapts both to the Wails runtime; the command line and tests supply
+// their own.
+package events
+
+import "context"
+
+// Event names, as received by the frontend.
+const (
+	HostFound             = "hostFound"             // Host: a live host found by a scan
+	ScanError             = "scanError"             // string: a scan could not start
+	ScanComplete          = "scanComplete"          // bool: a scan finished, or failed to start (false)
+	ScanSaved             = "scanSaved"             // ScanResultInfo: a scan result was stored
+	HostStatusUpdate      = "hostStatusUpdate"      // monitor.HostStatusUpdate: a monitored host changed status
+	MonitoredHostsChanged = "monitoredHostsChanged" // []monitor.HostState: the monitored-host set changed
+	MonitoringResumed     = "monitoringResumed"     // []monitor.HostState: a saved session was resumed on startup
+	NewDeviceDetected     = "newDeviceDetected"     // KnownDevice: a device not in the inventory
+	PortsChanged          = "portsChanged"          // PortChange: a host's open ports changed
+	ARPAnomaly            = "arpAnomaly"            // ARPAnomaly: possible ARP spoofing or IP conflict
+	RogueDHCPDetected     = "rogueDhcpDetected"     // DHCPCheckResult: a DHCP server not on the allowlist answered
+)
+
+// Names lists every event name.
+var Names = []string{
+	HostFound, ScanError, ScanComplete, ScanSaved, HostStatusUpdate, MonitoredHostsChanged,
+	MonitoringResumed, NewDeviceDetected, PortsChanged, ARPAnomaly, RogueDHCPDetected,
+}
+
+// Sink receives events. Emit may be called from several goroutines at once and must not block.
+type Sink interface {
+	Emit(name string, data any)
+}
+
+// ScanSink is implemented by Sinks that can attribute events to the scan that caused them.
+type ScanSink interface {
+	Sink
+	EmitScan(scanID, name string, data any)
+}
+
+// EmitScan emits an event caused by the scan with the given ID, through sink.EmitScan if the
+// sink supports it and sink.Emit otherwise. An empty scanID is a plain Emit.
+func EmitScan(sink Sink, scanID, name string, data any) {
+	if s, ok := sink.(ScanSink); ok && scanID != "" {
+		s.EmitScan(scanID, name, data)
+		return
+	}
+	sink.Emit(name, data)
+}
+
+type scanIDKey struct{}
+
+// WithScanID returns a context that attributes the events of work done under it, such as a
+// scan started with it, to scanID.
+func WithScanID(ctx context.Context, scanID string) context.Context {
+	return context.WithValue(ctx, scanIDKey{}, scanID)
+}
+
+// ScanID returns the scan ID attached to ctx by WithScanID, or "".
+func ScanID(ctx context.Context) string {
+	id, _ := ctx.Value(scanIDKey{}).(string)
+	return id
+}
+
+// Logger receives diagnostics. Its methods match the Wails logger.
+type Logger interface {
+	Debug(message string)
+	Info(message string)
+	Warning(message string)
+	Error(message string)
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(name string, data any)
+
+// Emit calls f(name, data).
+func (f SinkFunc) Emit(name string, data any) { f(name, data) }
+
+// Discard is a Sink and Logger that drops everything.
+var Discard discard
+
+type discard struct{}
+
+func (discard) Emit(string, any) {}
+func (discard) Debug(string)     {}
+func (discard) Info(string)      {}
+func (discard) Warning(string)   {}
+func (discard) Error(string)     {}
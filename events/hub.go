@@ -0,0 +1,110 @@
+package events
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event is an event as delivered to Hub subscribers.
+type Event struct {
+	Seq    uint64    `json:"seq"`              // Increases by one with every event the Hub receives
+	Name   string    `json:"event"`            // One of Names
+	ScanID string    `json:"scanId,omitempty"` // Scan that caused the event, if any
+	Time   time.Time `json:"time"`
+	Data   any       `json:"data"`
+}
+
+// Filter selects the events a subscriber receives. Zero fields match everything.
+type Filter struct {
+	Names  []string // Only events with one of these names
+	ScanID string   // Only events caused by this scan
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Event) bool {
+	if len(f.Names) > 0 && !slices.Contains(f.Names, e.Name) {
+		return false
+	}
+	return f.ScanID == "" || f.ScanID == e.ScanID
+}
+
+// Hub is a ScanSink that fans events out to subscribers, such as clients of a live event
+// stream. Emit never blocks: a subscriber that falls behind loses events instead.
+type Hub struct {
+	mu   sync.Mutex
+	seq  uint64
+	subs map[*Subscription]struct{}
+}
+
+// NewHub returns a Hub without subscribers.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events that match its filter until it is closed.
+type Subscription struct {
+	C <-chan Event // Closed by Close
+
+	c       chan Event
+	filter  Filter
+	hub     *Hub
+	dropped atomic.Uint64
+}
+
+// Emit implements Sink.
+func (h *Hub) Emit(name string, data any) {
+	h.EmitScan("", name, data)
+}
+
+// EmitScan implements ScanSink.
+func (h *Hub) EmitScan(scanID, name string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	e := Event{Seq: h.seq, Name: name, ScanID: scanID, Time: time.Now(), Data: data}
+	for s := range h.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events matching filter, buffering up to buffer
+// events for a slow reader.
+func (h *Hub) Subscribe(filter Filter, buffer int) *Subscription {
+	c := make(chan Event, max(buffer, 1))
+	s := &Subscription{C: c, c: c, filter: filter, hub: h}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[s] = struct{}{}
+	return s
+}
+
+// Subscribers returns the number of open subscriptions.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close ends the subscription and closes C. It may be called more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subs[s]; ok {
+		delete(s.hub.subs, s)
+		close(s.c)
+	}
+}
+
+// Dropped returns how many matching events were lost because the subscriber fell behind.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
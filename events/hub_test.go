@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+// drain returns the events buffered for s.
+func drain(s *Subscription) []Event {
+	var got []Event
+	for {
+		select {
+		case e := <-s.C:
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestHubFilters(t *testing.T) {
+	hub := NewHub()
+	all := hub.Subscribe(Filter{}, 10)
+	status := hub.Subscribe(Filter{Names: []string{HostStatusUpdate}}, 10)
+	scan := hub.Subscribe(Filter{ScanID: "a"}, 10)
+	scanHosts := hub.Subscribe(Filter{Names: []string{HostFound}, ScanID: "a"}, 10)
+
+	hub.EmitScan("a", HostFound, "10.0.0.1")
+	hub.EmitScan("b", HostFound, "10.0.0.2")
+	hub.Emit(HostStatusUpdate, "10.0.0.3")
+	hub.EmitScan("a", ScanComplete, true)
+
+	for _, tc := range []struct {
+		name string
+		sub  *Subscription
+		want []uint64
+	}{
+		{"all", all, []uint64{1, 2, 3, 4}},
+		{"by name", status, []uint64{3}},
+		{"by scan", scan, []uint64{1, 4}},
+		{"by name and scan", scanHosts, []uint64{1}},
+	} {
+		var seqs []uint64
+		for _, e := range drain(tc.sub) {
+			seqs = append(seqs, e.Seq)
+		}
+		if len(seqs) != len(tc.want) {
+			t.Errorf("%s: got events %v, want %v", tc.name, seqs, tc.want)
+			continue
+		}
+		for i := range seqs {
+			if seqs[i] != tc.want[i] {
+				t.Errorf("%s: got events %v, want %v", tc.name, seqs, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHubDropsForSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	s := hub.Subscribe(Filter{}, 2)
+	for i := 0; i < 5; i++ {
+		hub.Emit(HostFound, i) // Must not block
+	}
+	if got := len(drain(s)); got != 2 {
+		t.Errorf("received %d events, want the 2 buffered", got)
+	}
+	if s.Dropped() != 3 {
+		t.Errorf("dropped %d events, want 3", s.Dropped())
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	hub := NewHub()
+	s := hub.Subscribe(Filter{}, 1)
+	s.Close()
+	s.Close()
+	if _, open := <-s.C; open {
+		t.Error("channel still open after Close")
+	}
+	if hub.Subscribers() != 0 {
+		t.Errorf("%d subscribers after Close", hub.Subscribers())
+	}
+	hub.Emit(HostFound, nil) // No send on the closed channel
+}
+
+func TestEmitScan(t *testing.T) {
+	hub := NewHub()
+	s := hub.Subscribe(Filter{}, 2)
+	ctx := WithScanID(context.Background(), "scan-1")
+	EmitScan(hub, ScanID(ctx), HostFound, nil)
+
+	var plain []string
+	EmitScan(SinkFunc(func(name string, _ any) { plain = append(plain, name) }), "scan-1", HostFound, nil)
+
+	if got := drain(s); len(got) != 1 || got[0].ScanID != "scan-1" {
+		t.Errorf("hub received %+v, want one event of scan-1", got)
+	}
+	if len(plain) != 1 {
+		t.Errorf("plain sink received %v, want the event without its scan", plain)
+	}
+	if id := ScanID(context.Background()); id != "" {
+		t.Errorf("ScanID of a plain context = %q", id)
+	}
+}
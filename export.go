@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"netview/scanner"
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Export formats accepted by ExportScan.
+const (
+	exportFormatCSV      = "csv"
+	exportFormatJSON     = "json"
+	exportFormatMarkdown = "markdown"
+	exportFormatNmap     = "nmap" // nmap-compatible XML; the field selection does not apply
+)
+
+// CSV layouts: one row per host, or one row per open port of each host.
+const (
+	csvLayoutHost = "host"
+	csvLayoutPort = "port"
+)
+
+// exportSchemaVersion is bumped whenever the JSON export document changes incompatibly.
+const exportSchemaVersion = 1
+
+// exportFields are the host fields that can be exported, in column order.
+var exportFields = []string{"ipAddress", "hostname", "macAddress", "vendor", "os", "deviceType", "openPorts", "services"}
+
+// exportFieldTitles are the column headings for CSV and Markdown.
+var exportFieldTitles = map[string]string{
+	"ipAddress":  "IP Address",
+	"hostname":   "Hostname",
+	"macAddress": "MAC Address",
+	"vendor":     "Vendor",
+	"os":         "OS",
+	"deviceType": "Device Type",
+	"openPorts":  "Open Ports",
+	"services":   "Services",
+}
+
+// ExportSettings selects what ExportScan writes.
+type ExportSettings struct {
+	Fields    []string `json:"fields"`    // Host fields to include, from exportFields
+	CSVLayout string   `json:"csvLayout"` // "host" (one row per host) or "port" (one row per open port)
+}
+
+// ExportDocument is the JSON export format. Its shape only changes with SchemaVersion; every
+// selected field is present on every host, even when empty.
+type ExportDocument struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Generator     string           `json:"generator"`
+	ExportedAt    time.Time        `json:"exportedAt"`
+	Scan          ScanResultInfo   `json:"scan"`
+	Fields        []string         `json:"fields"`
+	Hosts         []map[string]any `json:"hosts"`
+}
+
+const exportSettingsKey = "export"
+
+// defaultExportSettings exports every field, one CSV row per host. The OS is only known for
+// hosts imported from nmap and is empty otherwise.
+func defaultExportSettings() ExportSettings {
+	return ExportSettings{
+		Fields:    append([]string{}, exportFields...),
+		CSVLayout: csvLayoutHost,
+	}
+}
+
+// loadExportSettings returns the saved export settings, or the defaults.
+func (a *App) loadExportSettings() ExportSettings {
+	settings := defaultExportSettings()
+	if a.store == nil {
+		return settings
+	}
+	var saved ExportSettings
+	if err := storage.GetJSON(a.store, settingsBucket, exportSettingsKey, &saved); err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			runtime.LogError(a.ctx, fmt.Sprintf("Error loading export settings, using defaults: %v", err))
+		}
+		return settings
+	}
+	if validateExportSettings(saved) != nil {
+		return settings
+	}
+	return saved
+}
+
+// validateExportSettings checks the field names and layout.
+func validateExportSettings(settings ExportSettings) error {
+	if len(settings.Fields) == 0 {
+		return fmt.Errorf("at least one field must be selected")
+	}
+	for _, f := range settings.Fields {
+		if _, ok := exportFieldTitles[f]; !ok {
+			return fmt.Errorf("unknown export field %q", f)
+		}
+	}
+	if settings.CSVLayout != csvLayoutHost && settings.CSVLayout != csvLayoutPort {
+		return fmt.Errorf("unknown CSV layout %q", settings.CSVLayout)
+	}
+	return nil
+}
+
+// GetExportFields lists the host fields that can be selected for export, in column order.
+func (a *App) GetExportFields() []string {
+	return append([]string{}, exportFields...)
+}
+
+// GetExportSettings returns the export field selection and CSV layout.
+func (a *App) GetExportSettings() ExportSettings {
+	return a.loadExportSettings()
+}
+
+// SaveExportSettings validates and stores the export field selection and CSV layout.
+func (a *App) SaveExportSettings(settings ExportSettings) error {
+	if err := validateExportSettings(settings); err != nil {
+		return err
+	}
+	if a.store == nil {
+		return fmt.Errorf("data store is not available")
+	}
+	return storage.PutJSON(a.store, settingsBucket, exportSettingsKey, settings)
+}
+
+// ExportScan writes a stored scan to path as "csv", "json", "markdown" or "nmap" (XML), using
+// the saved export settings.
+func (a *App) ExportScan(id string, format string, path string) error {
+	if path == "" {
+		return fmt.Errorf("no export path given")
+	}
+	result, err := a.scanResults.load(id)
+	if err != nil {
+		return err
+	}
+	data, err := renderScanExport(result, format, a.loadExportSettings(), time.Now())
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Exported scan %s as %s to %s (%d hosts).", id, format, path, len(result.Hosts)))
+	return nil
+}
+
+// ExportScanWithDialog asks where to save the export, then writes it. It returns the chosen
+// path, or "" if the dialog was cancelled.
+func (a *App) ExportScanWithDialog(id string, format string) (string, error) {
+	ext, filter := exportFileType(format)
+	if ext == "" {
+		return "", fmt.Errorf("unknown export format %q", format)
+	}
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Scan",
+		DefaultFilename: fmt.Sprintf("netview-scan-%s%s", time.Now().Format("2006-01-02-1504"), ext),
+		Filters:         []runtime.FileFilter{filter},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ext) {
+		path += ext
+	}
+	return path, a.ExportScan(id, format, path)
+}
+
+// exportFileType returns the file extension and dialog filter for a format.
+func exportFileType(format string) (string, runtime.FileFilter) {
+	switch format {
+	case exportFormatCSV:
+		return ".csv", runtime.FileFilter{DisplayName: "CSV files (*.csv)", Pattern: "*.csv"}
+	case exportFormatJSON:
+		return ".json", runtime.FileFilter{DisplayName: "JSON files (*.json)", Pattern: "*.json"}
+	case exportFormatMarkdown:
+		return ".md", runtime.FileFilter{DisplayName: "Markdown files (*.md)", Pattern: "*.md"}
+	case exportFormatNmap:
+		return ".xml", runtime.FileFilter{DisplayName: "nmap XML (*.xml)", Pattern: "*.xml"}
+	}
+	return "", runtime.FileFilter{}
+}
+
+// renderScanExport serialises a scan in the given format.
+func renderScanExport(result ScanResult, format string, settings ExportSettings, now time.Time) ([]byte, error) {
+	if err := validateExportSettings(settings); err != nil {
+		return nil, err
+	}
+	switch format {
+	case exportFormatCSV:
+		return renderCSV(result, settings)
+	case exportFormatJSON:
+		return renderJSON(result, settings, now)
+	case exportFormatMarkdown:
+		return renderMarkdown(result, settings), nil
+	case exportFormatNmap:
+		return renderNmapXML(result)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// csvSafeRow prefixes cells that a spreadsheet would read as a formula with an apostrophe.
+// Hostnames and banners come from the scanned devices, so "=HYPERLINK(...)" in a banner must
+// stay text when the export is opened.
+func csvSafeRow(row []string) []string {
+	for i, cell := range row {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			row[i] = "'" + cell
+		}
+	}
+	return row
+}
+
+// renderCSV writes one row per host, or one row per open port with the port's service details.
+// Hosts without open ports still get a row in the per-port layout.
+func renderCSV(result ScanResult, settings ExportSettings) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if settings.CSVLayout == csvLayoutHost {
+		_ = w.Write(fieldTitles(settings.Fields))
+		for _, h := range result.Hosts {
+			row := make([]string, len(settings.Fields))
+			for i, f := range settings.Fields {
+				row[i] = hostFieldText(h, f)
+			}
+			_ = w.Write(csvSafeRow(row))
+		}
+	} else {
+		// Per-port rows carry the port details in their own columns instead of the list fields
+		var fields []string
+		for _, f := range settings.Fields {
+			if f != "openPorts" && f != "services" {
+				fields = append(fields, f)
+			}
+		}
+		_ = w.Write(append(fieldTitles(fields), "Port", "Service", "Version", "Banner"))
+		for _, h := range result.Hosts {
+			base := make([]string, len(fields))
+			for i, f := range fields {
+				base[i] = hostFieldText(h, f)
+			}
+			if len(h.OpenPorts) == 0 {
+				_ = w.Write(csvSafeRow(append(base, "", "", "", "")))
+				continue
+			}
+			services := servicesByPort(h)
+			for _, p := range h.OpenPorts {
+				s := services[p]
+				if s.Name == "" {
+					s.Name = scanner.WellKnownPortNames[p]
+				}
+				_ = w.Write(csvSafeRow(append(append([]string{}, base...), strconv.Itoa(p), s.Name, s.Version, s.Banner)))
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// renderJSON writes an ExportDocument, indented.
+func renderJSON(result ScanResult, settings ExportSettings, now time.Time) ([]byte, error) {
+	doc := ExportDocument{
+		SchemaVersion: exportSchemaVersion,
+		Generator:     "NetView",
+		ExportedAt:    now.UTC(),
+		Scan:          result.ScanResultInfo,
+		Fields:        settings.Fields,
+		Hosts:         make([]map[string]any, 0, len(result.Hosts)),
+	}
+	for _, h := range result.Hosts {
+		host := make(map[string]any, len(settings.Fields))
+		for _, f := range settings.Fields {
+			host[f] = hostFieldValue(h, f)
+		}
+		doc.Hosts = append(doc.Hosts, host)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// renderMarkdown writes a heading with the scan parameters and a table of the hosts.
+func renderMarkdown(result ScanResult, settings ExportSettings) []byte {
+	var b strings.Builder
+	info := result.ScanResultInfo
+	fmt.Fprintf(&b, "## NetView scan %s - %s\n\n", info.Parameters.StartIP, info.Parameters.EndIP)
+	fmt.Fprintf(&b, "Scanned %s (%.1fs): %d hosts found in %d addresses, %d open ports.\n\n",
+		info.StartedAt.Format("2006-01-02 15:04:05"), float64(info.DurationMs)/1000,
+		info.Summary.HostsFound, info.Summary.AddressesScanned, info.Summary.OpenPorts)
+
+	titles := fieldTitles(settings.Fields)
+	b.WriteString("| " + strings.Join(titles, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(titles)) + "\n")
+	for _, h := range result.Hosts {
+		cells := make([]string, len(settings.Fields))
+		for i, f := range settings.Fields {
+			cells[i] = markdownCell(hostFieldText(h, f))
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return []byte(b.String())
+}
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ") // Cells cannot span lines
+}
+
+func fieldTitles(fields []string) []string {
+	titles := make([]string, len(fields))
+	for i, f := range fields {
+		titles[i] = exportFieldTitles[f]
+	}
+	return titles
+}
+
+// hostFieldValue returns a field for JSON export. Lists are never null.
+func hostFieldValue(h Host, field string) any {
+	switch field {
+	case "openPorts":
+		if h.OpenPorts == nil {
+			return []int{}
+		}
+		return h.OpenPorts
+	case "services":
+		if h.Services == nil {
+			return []ServiceInfo{}
+		}
+		return h.Services
+	case "vendor":
+		return hostVendor(h)
+	}
+	return hostFieldText(h, field)
+}
+
+// hostFieldText returns a field as text for CSV and Markdown.
+func hostFieldText(h Host, field string) string {
+	switch field {
+	case "ipAddress":
+		return h.IPAddress
+	case "hostname":
+		return h.Hostname
+	case "macAddress":
+		return h.MACAddress
+	case "vendor":
+		return hostVendor(h)
+	case "os":
+		return h.OS
+	case "deviceType":
+		return h.DeviceType
+	case "openPorts":
+		ports := make([]string, len(h.OpenPorts))
+		for i, p := range h.OpenPorts {
+			ports[i] = strconv.Itoa(p)
+		}
+		return strings.Join(ports, ", ")
+	case "services":
+		// "22/ssh OpenSSH_9.6p1, 80/http nginx/1.24.0"
+		parts := []string{}
+		for _, s := range h.Services {
+			if s.Name == "" && s.Version == "" {
+				continue
+			}
+			part := strconv.Itoa(s.Port) + "/" + s.Name
+			if s.Version != "" {
+				part += " " + s.Version
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+// servicesByPort indexes a host's services by port.
+func servicesByPort(h Host) map[int]ServiceInfo {
+	services := make(map[int]ServiceInfo, len(h.Services))
+	for _, s := range h.Services {
+		services[s.Port] = s
+	}
+	return services
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportTestScan has a host with services, one with awkward text and one with nothing open.
+func exportTestScan() ScanResult {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return ScanResult{
+		ScanResultInfo: ScanResultInfo{
+			ID:         "a1",
+			Parameters: ScanRange{StartIP: "192.168.1.1", EndIP: "192.168.1.254", Ports: []int{22, 80, 3389}},
+			StartedAt:  t0, CompletedAt: t0.Add(2500 * time.Millisecond), DurationMs: 2500,
+			Summary: ScanSummary{AddressesScanned: 254, HostsFound: 3, OpenPorts: 3},
+		},
+		Hosts: []Host{
+			{IPAddress: "192.168.1.10", Hostname: "nas", MACAddress: "AA:BB:CC:00:00:10", Vendor: "Acme", DeviceType: "NAS",
+				OpenPorts: []int{22, 80},
+				Services:  []ServiceInfo{{Port: 22, Name: "ssh", Version: "OpenSSH_9.6p1", Banner: "SSH-2.0-OpenSSH_9.6p1"}, {Port: 80}}},
+			{IPAddress: "192.168.1.11", Hostname: "pipe|host\nname, \"quoted\"", OpenPorts: []int{3389}},
+			{IPAddress: "192.168.1.12"},
+		},
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		settings ExportSettings
+		want     string
+	}{
+		{"host rows", ExportSettings{Fields: []string{"ipAddress", "hostname", "openPorts", "services"}, CSVLayout: csvLayoutHost},
+			"IP Address,Hostname,Open Ports,Services\n" +
+				"192.168.1.10,nas,\"22, 80\",22/ssh OpenSSH_9.6p1\n" +
+				"192.168.1.11,\"pipe|host\nname, \"\"quoted\"\"\",3389,\n" +
+				"192.168.1.12,,,\n"},
+		{"field order follows the selection", ExportSettings{Fields: []string{"vendor", "ipAddress"}, CSVLayout: csvLayoutHost},
+			"Vendor,IP Address\nAcme,192.168.1.10\n,192.168.1.11\n,192.168.1.12\n"},
+		{"port rows", ExportSettings{Fields: []string{"ipAddress", "openPorts", "deviceType", "services"}, CSVLayout: csvLayoutPort},
+			"IP Address,Device Type,Port,Service,Version,Banner\n" +
+				"192.168.1.10,NAS,22,ssh,OpenSSH_9.6p1,SSH-2.0-OpenSSH_9.6p1\n" +
+				"192.168.1.10,NAS,80,http,,\n" + // Named from the well-known ports
+				"192.168.1.11,,3389,rdp,,\n" +
+				"192.168.1.12,,,,,\n"},
+	} {
+		got, err := renderScanExport(exportTestScan(), exportFormatCSV, c.settings, time.Time{})
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		} else if string(got) != c.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRenderCSVNeutralisesFormulas(t *testing.T) {
+	scan := newScanResult("f1", ScanRange{StartIP: "10.0.0.1", EndIP: "10.0.0.9"}, time.Time{}, 9, []Host{
+		{IPAddress: "10.0.0.5", Hostname: "=HYPERLINK(\"http://evil\",\"x\")", Vendor: "+cmd", DeviceType: "@SUM(A1)", OpenPorts: []int{23},
+			Services: []ServiceInfo{{Port: 23, Name: "-telnet", Banner: "\tlogin:"}}},
+		{IPAddress: "10.0.0.6", Hostname: "\r=1+1", Vendor: "a=b"},
+	})
+	for _, c := range []struct {
+		layout string
+		want   string
+	}{
+		{csvLayoutHost, "IP Address,Hostname,Vendor,Device Type,Services\n" +
+			"10.0.0.5,\"'=HYPERLINK(\"\"http://evil\"\",\"\"x\"\")\",'+cmd,'@SUM(A1),23/-telnet\n" +
+			"10.0.0.6,\"'\r=1+1\",a=b,,\n"},
+		{csvLayoutPort, "IP Address,Hostname,Vendor,Device Type,Port,Service,Version,Banner\n" +
+			"10.0.0.5,\"'=HYPERLINK(\"\"http://evil\"\",\"\"x\"\")\",'+cmd,'@SUM(A1),23,'-telnet,,'\tlogin:\n" +
+			"10.0.0.6,\"'\r=1+1\",a=b,,,,,\n"},
+	} {
+		settings := ExportSettings{Fields: []string{"ipAddress", "hostname", "vendor", "deviceType", "services"}, CSVLayout: c.layout}
+		got, err := renderScanExport(scan, exportFormatCSV, settings, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.want {
+			t.Errorf("%s layout: got\n%q\nwant\n%q", c.layout, got, c.want)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	settings := ExportSettings{Fields: []string{"ipAddress", "hostname", "openPorts"}, CSVLayout: csvLayoutHost}
+	got, err := renderScanExport(exportTestScan(), exportFormatMarkdown, settings, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## NetView scan 192.168.1.1 - 192.168.1.254\n\n" +
+		"Scanned 2026-03-01 12:00:00 (2.5s): 3 hosts found in 254 addresses, 3 open ports.\n\n" +
+		"| IP Address | Hostname | Open Ports |\n" +
+		"| --- | --- | --- |\n" +
+		"| 192.168.1.10 | nas | 22, 80 |\n" +
+		`| 192.168.1.11 | pipe\|host name, "quoted" | 3389 |` + "\n" +
+		"| 192.168.1.12 |  |  |\n"
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.FixedZone("CET", 3600))
+	settings := ExportSettings{Fields: []string{"ipAddress", "openPorts", "services", "vendor"}, CSVLayout: csvLayoutHost}
+	data, err := renderScanExport(exportTestScan(), exportFormatJSON, settings, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		SchemaVersion int                          `json:"schemaVersion"`
+		ExportedAt    string                       `json:"exportedAt"`
+		Scan          ScanResultInfo               `json:"scan"`
+		Fields        []string                     `json:"fields"`
+		Hosts         []map[string]json.RawMessage `json:"hosts"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != exportSchemaVersion || doc.ExportedAt != "2026-03-02T07:00:00Z" || doc.Scan.ID != "a1" ||
+		!reflect.DeepEqual(doc.Fields, settings.Fields) || len(doc.Hosts) != 3 {
+		t.Errorf("document = %+v", doc)
+	}
+	// Every selected field, and only those, on every host; lists are never null
+	empty := doc.Hosts[2]
+	if len(empty) != 4 || string(empty["openPorts"]) != "[]" || string(empty["services"]) != "[]" || string(empty["vendor"]) != `""` {
+		t.Errorf("host without data = %s", data)
+	}
+	if string(doc.Hosts[0]["openPorts"]) != "[\n        22,\n        80\n      ]" {
+		t.Errorf("open ports = %s", doc.Hosts[0]["openPorts"])
+	}
+}
+
+func TestRenderScanExportRejects(t *testing.T) {
+	for _, c := range []struct {
+		format   string
+		settings ExportSettings
+		wantErr  string
+	}{
+		{"xlsx", defaultExportSettings(), "unknown export format"},
+		{exportFormatCSV, ExportSettings{CSVLayout: csvLayoutHost}, "at least one field"},
+		{exportFormatCSV, ExportSettings{Fields: []string{"ipAddress", "uptime"}, CSVLayout: csvLayoutHost}, `unknown export field "uptime"`},
+		{exportFormatCSV, ExportSettings{Fields: []string{"ipAddress"}, CSVLayout: "column"}, "unknown CSV layout"},
+	} {
+		if _, err := renderScanExport(exportTestScan(), c.format, c.settings, time.Time{}); err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s %+v: error %v, want one mentioning %q", c.format, c.settings, err, c.wantErr)
+		}
+	}
+}
@@ -1,9 +1,70 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
-import {main} from '../models';
+import {main, alerting, history, monitor, mqtt, scanner, storage} from '../models';
 
-export function ScanNetwork(arg1:main.ScanRange):Promise<void>;
-export function GetScanHistory():Promise<main.ScanHistoryItem[]>;
-export function StartMonitoring(hostsToMonitor: main.Host[], searchHidden: boolean, hiddenPortsList: number[]):Promise<void>;
+export function ScanNetwork(arg1:scanner.ScanRange):Promise<void>;
+export function GetScanHistory():Promise<history.Item[]>;
+export function StartMonitoring(hostsToMonitor: scanner.Host[], searchHidden: boolean, hiddenPortsList: number[]):Promise<void>;
 export function StopMonitoring():Promise<void>;
 export function IsMonitoringActive():Promise<boolean>;
+export function SetStartMinimised(enabled: boolean):Promise<void>;
+export function GetStartMinimised():Promise<boolean>;
+export function AddMonitoredHost(host: scanner.Host):Promise<void>;
+export function RemoveMonitoredHost(ipAddress: string):Promise<void>;
+export function UpdateMonitoredHost(host: scanner.Host):Promise<void>;
+export function ListMonitoredHosts():Promise<monitor.HostState[]>;
+export function GetAlertSettings():Promise<main.AlertSettings>;
+export function SaveAlertSettings(settings: main.AlertSettings):Promise<void>;
+export function SendTestWebhook(config: alerting.WebhookConfig):Promise<void>;
+export function SendTestEmail(config: alerting.SMTPConfig):Promise<void>;
+export function SendTestDesktopNotification():Promise<void>;
+export function SetMonitoredHostParent(ipAddress: string, parentIP: string):Promise<void>;
+export function InferMonitorParents():Promise<Record<string, string>>;
+export function ListMaintenanceWindows():Promise<monitor.MaintenanceWindowStatus[]>;
+export function SaveMaintenanceWindow(window: monitor.MaintenanceWindow):Promise<monitor.MaintenanceWindow>;
+export function DeleteMaintenanceWindow(id: string):Promise<void>;
+export function SetMonitoredHostGroups(ipAddress: string, groups: string[]):Promise<void>;
+export function GetInventory():Promise<main.KnownDevice[]>;
+export function GetPendingDevices():Promise<main.KnownDevice[]>;
+export function ApproveDevice(key: string, name: string):Promise<void>;
+export function IgnoreDevice(key: string):Promise<void>;
+export function RemoveInventoryDevice(key: string):Promise<void>;
+export function GetInventorySettings():Promise<main.InventorySettings>;
+export function SaveInventorySettings(settings: main.InventorySettings):Promise<void>;
+export function SetMonitorPortRecheck(settings: monitor.PortRecheckSettings):Promise<void>;
+export function GetMonitorPortRecheck():Promise<monitor.PortRecheckSettings>;
+export function GetARPBindings():Promise<main.ARPBinding[]>;
+export function ClearARPBindings():Promise<void>;
+export function GetARPWatchSettings():Promise<main.ARPWatchSettings>;
+export function SaveARPWatchSettings(settings: main.ARPWatchSettings):Promise<void>;
+export function ListNetworkInterfaces():Promise<main.NetworkInterfaceInfo[]>;
+export function RunDHCPCheck(interfaceName: string):Promise<main.DHCPCheckResult>;
+export function GetDHCPCheckSettings():Promise<main.DHCPCheckSettings>;
+export function SaveDHCPCheckSettings(settings: main.DHCPCheckSettings):Promise<void>;
+export function GetScanResult(id: string):Promise<main.ScanResult>;
+export function ListScanResults():Promise<main.ScanResultInfo[]>;
+export function DiffScans(idA: string, idB: string):Promise<main.ScanDiff>;
+export function GetStorageStats():Promise<storage.Stats>;
+export function CompactStorage():Promise<storage.Stats>;
+export function DeleteHistoryItem(id: string):Promise<void>;
+export function ClearHistory(keepPinned: boolean):Promise<void>;
+export function PinHistoryItem(id: string, pinned: boolean):Promise<void>;
+export function RenameHistoryItem(id: string, name: string):Promise<void>;
+export function GetHistorySettings():Promise<history.Settings>;
+export function SaveHistorySettings(settings: history.Settings):Promise<void>;
+export function GetExportFields():Promise<string[]>;
+export function GetExportSettings():Promise<main.ExportSettings>;
+export function SaveExportSettings(settings: main.ExportSettings):Promise<void>;
+export function ExportScan(id: string, format: string, path: string):Promise<void>;
+export function ExportScanWithDialog(id: string, format: string):Promise<string>;
+export function ImportNmapXML(path: string):Promise<main.ScanResultInfo>;
+export function ImportNmapXMLWithDialog():Promise<main.ScanResultInfo>;
+export function GenerateReport(id: string, baselineID: string, path: string):Promise<void>;
+export function GenerateReportWithDialog(id: string, baselineID: string):Promise<string>;
+export function GetAPISettings():Promise<main.APISettings>;
+export function SaveAPISettings(settings: main.APISettings):Promise<void>;
+export function RegenerateAPIToken():Promise<string>;
+export function RegenerateMetricsToken():Promise<string>;
+export function GetMQTTSettings():Promise<mqtt.Config>;
+export function SaveMQTTSettings(config: mqtt.Config):Promise<void>;
+export function TestMQTTConnection(config: mqtt.Config):Promise<void>;
@@ -21,3 +21,247 @@ export function StopMonitoring() {
 export function IsMonitoringActive() {
   return window['go']['main']['App']['IsMonitoringActive']();
 }
+
+export function SetStartMinimised(enabled) {
+  return window['go']['main']['App']['SetStartMinimised'](enabled);
+}
+
+export function GetStartMinimised() {
+  return window['go']['main']['App']['GetStartMinimised']();
+}
+
+export function AddMonitoredHost(host) {
+  return window['go']['main']['App']['AddMonitoredHost'](host);
+}
+
+export function RemoveMonitoredHost(ipAddress) {
+  return window['go']['main']['App']['RemoveMonitoredHost'](ipAddress);
+}
+
+export function UpdateMonitoredHost(host) {
+  return window['go']['main']['App']['UpdateMonitoredHost'](host);
+}
+
+export function ListMonitoredHosts() {
+  return window['go']['main']['App']['ListMonitoredHosts']();
+}
+
+export function GetAlertSettings() {
+  return window['go']['main']['App']['GetAlertSettings']();
+}
+
+export function SaveAlertSettings(settings) {
+  return window['go']['main']['App']['SaveAlertSettings'](settings);
+}
+
+export function SendTestWebhook(config) {
+  return window['go']['main']['App']['SendTestWebhook'](config);
+}
+
+export function SendTestEmail(config) {
+  return window['go']['main']['App']['SendTestEmail'](config);
+}
+
+export function SendTestDesktopNotification() {
+  return window['go']['main']['App']['SendTestDesktopNotification']();
+}
+
+export function SetMonitoredHostParent(ipAddress, parentIP) {
+  return window['go']['main']['App']['SetMonitoredHostParent'](ipAddress, parentIP);
+}
+
+export function InferMonitorParents() {
+  return window['go']['main']['App']['InferMonitorParents']();
+}
+
+export function ListMaintenanceWindows() {
+  return window['go']['main']['App']['ListMaintenanceWindows']();
+}
+
+export function SaveMaintenanceWindow(window) {
+  return window['go']['main']['App']['SaveMaintenanceWindow'](window);
+}
+
+export function DeleteMaintenanceWindow(id) {
+  return window['go']['main']['App']['DeleteMaintenanceWindow'](id);
+}
+
+export function SetMonitoredHostGroups(ipAddress, groups) {
+  return window['go']['main']['App']['SetMonitoredHostGroups'](ipAddress, groups);
+}
+
+export function GetInventory() {
+  return window['go']['main']['App']['GetInventory']();
+}
+
+export function GetPendingDevices() {
+  return window['go']['main']['App']['GetPendingDevices']();
+}
+
+export function ApproveDevice(key, name) {
+  return window['go']['main']['App']['ApproveDevice'](key, name);
+}
+
+export function IgnoreDevice(key) {
+  return window['go']['main']['App']['IgnoreDevice'](key);
+}
+
+export function RemoveInventoryDevice(key) {
+  return window['go']['main']['App']['RemoveInventoryDevice'](key);
+}
+
+export function GetInventorySettings() {
+  return window['go']['main']['App']['GetInventorySettings']();
+}
+
+export function SaveInventorySettings(settings) {
+  return window['go']['main']['App']['SaveInventorySettings'](settings);
+}
+
+export function SetMonitorPortRecheck(settings) {
+  return window['go']['main']['App']['SetMonitorPortRecheck'](settings);
+}
+
+export function GetMonitorPortRecheck() {
+  return window['go']['main']['App']['GetMonitorPortRecheck']();
+}
+
+export function GetARPBindings() {
+  return window['go']['main']['App']['GetARPBindings']();
+}
+
+export function ClearARPBindings() {
+  return window['go']['main']['App']['ClearARPBindings']();
+}
+
+export function GetARPWatchSettings() {
+  return window['go']['main']['App']['GetARPWatchSettings']();
+}
+
+export function SaveARPWatchSettings(settings) {
+  return window['go']['main']['App']['SaveARPWatchSettings'](settings);
+}
+
+export function ListNetworkInterfaces() {
+  return window['go']['main']['App']['ListNetworkInterfaces']();
+}
+
+export function RunDHCPCheck(interfaceName) {
+  return window['go']['main']['App']['RunDHCPCheck'](interfaceName);
+}
+
+export function GetDHCPCheckSettings() {
+  return window['go']['main']['App']['GetDHCPCheckSettings']();
+}
+
+export function SaveDHCPCheckSettings(settings) {
+  return window['go']['main']['App']['SaveDHCPCheckSettings'](settings);
+}
+
+export function GetScanResult(id) {
+  return window['go']['main']['App']['GetScanResult'](id);
+}
+
+export function ListScanResults() {
+  return window['go']['main']['App']['ListScanResults']();
+}
+
+export function DiffScans(idA, idB) {
+  return window['go']['main']['App']['DiffScans'](idA, idB);
+}
+
+export function GetStorageStats() {
+  return window['go']['main']['App']['GetStorageStats']();
+}
+
+export function CompactStorage() {
+  return window['go']['main']['App']['CompactStorage']();
+}
+
+export function DeleteHistoryItem(id) {
+  return window['go']['main']['App']['DeleteHistoryItem'](id);
+}
+
+export function ClearHistory(keepPinned) {
+  return window['go']['main']['App']['ClearHistory'](keepPinned);
+}
+
+export function PinHistoryItem(id, pinned) {
+  return window['go']['main']['App']['PinHistoryItem'](id, pinned);
+}
+
+export function RenameHistoryItem(id, name) {
+  return window['go']['main']['App']['RenameHistoryItem'](id, name);
+}
+
+export function GetHistorySettings() {
+  return window['go']['main']['App']['GetHistorySettings']();
+}
+
+export function SaveHistorySettings(settings) {
+  return window['go']['main']['App']['SaveHistorySettings'](settings);
+}
+
+export function GetExportFields() {
+  return window['go']['main']['App']['GetExportFields']();
+}
+
+export function GetExportSettings() {
+  return window['go']['main']['App']['GetExportSettings']();
+}
+
+export function SaveExportSettings(settings) {
+  return window['go']['main']['App']['SaveExportSettings'](settings);
+}
+
+export function ExportScan(id, format, path) {
+  return window['go']['main']['App']['ExportScan'](id, format, path);
+}
+
+export function ExportScanWithDialog(id, format) {
+  return window['go']['main']['App']['ExportScanWithDialog'](id, format);
+}
+
+export function ImportNmapXML(path) {
+  return window['go']['main']['App']['ImportNmapXML'](path);
+}
+
+export function ImportNmapXMLWithDialog() {
+  return window['go']['main']['App']['ImportNmapXMLWithDialog']();
+}
+
+export function GenerateReport(id, baselineID, path) {
+  return window['go']['main']['App']['GenerateReport'](id, baselineID, path);
+}
+
+export function GenerateReportWithDialog(id, baselineID) {
+  return window['go']['main']['App']['GenerateReportWithDialog'](id, baselineID);
+}
+
+export function GetAPISettings() {
+  return window['go']['main']['App']['GetAPISettings']();
+}
+
+export function SaveAPISettings(settings) {
+  return window['go']['main']['App']['SaveAPISettings'](settings);
+}
+
+export function RegenerateAPIToken() {
+  return window['go']['main']['App']['RegenerateAPIToken']();
+}
+
+export function RegenerateMetricsToken() {
+  return window['go']['main']['App']['RegenerateMetricsToken']();
+}
+
+export function GetMQTTSettings() {
+  return window['go']['main']['App']['GetMQTTSettings']();
+}
+
+export function SaveMQTTSettings(config) {
+  return window['go']['main']['App']['SaveMQTTSettings'](config);
+}
+
+export function TestMQTTConnection(config) {
+  return window['go']['main']['App']['TestMQTTConnection'](config);
+}
@@ -1,40 +1,760 @@
 export namespace main {
 	
-	export class ScanRange {
-	    startIp: string;
-	    endIp: string;
-	    ports: number[];
-	    searchHiddenHosts: boolean;
-	    hiddenHostsPorts: number[];
+	export class AlertSettings {
+	    webhooks: alerting.WebhookConfig[];
+	    email: alerting.SMTPConfig;
+	    desktop: alerting.DesktopConfig;
+
+	    static createFrom(source: any = {}) {
+	        return new AlertSettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.webhooks = source["webhooks"];
+	        this.email = source["email"];
+	        this.desktop = source["desktop"];
+	    }
+	}
+
+	export class KnownDevice {
+	    key: string;
+	    macAddress?: string;
+	    ipAddress: string;
+	    hostname?: string;
+	    vendor?: string;
+	    deviceType?: string;
+	    name?: string;
+	    status: string;
+	    firstSeen: string;
+	    lastSeen: string;
+
+	    static createFrom(source: any = {}) {
+	        return new KnownDevice(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.macAddress = source["macAddress"];
+	        this.ipAddress = source["ipAddress"];
+	        this.hostname = source["hostname"];
+	        this.vendor = source["vendor"];
+	        this.deviceType = source["deviceType"];
+	        this.name = source["name"];
+	        this.status = source["status"];
+	        this.firstSeen = source["firstSeen"];
+	        this.lastSeen = source["lastSeen"];
+	    }
+	}
+
+	export class InventorySettings {
+	    alertOnNewDevice: boolean;
+	    sweepEnabled: boolean;
+	    sweepStartIp: string;
+	    sweepEndIp: string;
+	    sweepIntervalMinutes: number;
+
+	    static createFrom(source: any = {}) {
+	        return new InventorySettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.alertOnNewDevice = source["alertOnNewDevice"];
+	        this.sweepEnabled = source["sweepEnabled"];
+	        this.sweepStartIp = source["sweepStartIp"];
+	        this.sweepEndIp = source["sweepEndIp"];
+	        this.sweepIntervalMinutes = source["sweepIntervalMinutes"];
+	    }
+	}
+
+	export class PortChange {
+	    ipAddress: string;
+	    hostname?: string;
+	    opened: number[];
+	    closed: number[];
+	    source: string;
+	    timestamp: string;
+
+	    static createFrom(source: any = {}) {
+	        return new PortChange(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ipAddress = source["ipAddress"];
+	        this.hostname = source["hostname"];
+	        this.opened = source["opened"];
+	        this.closed = source["closed"];
+	        this.source = source["source"];
+	        this.timestamp = source["timestamp"];
+	    }
+	}
+
+	export class ARPBinding {
+	    ipAddress: string;
+	    macAddress: string;
+	    firstSeen: string;
+	    lastSeen: string;
+
+	    static createFrom(source: any = {}) {
+	        return new ARPBinding(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ipAddress = source["ipAddress"];
+	        this.macAddress = source["macAddress"];
+	        this.firstSeen = source["firstSeen"];
+	        this.lastSeen = source["lastSeen"];
+	    }
+	}
+
+	export class ARPAnomaly {
+	    type: string;
+	    ipAddress: string;
+	    macAddress: string;
+	    previousMacAddress?: string;
+	    otherMacAddresses?: string[];
+	    otherIpAddresses?: string[];
+	    timestamp: string;
+	    message: string;
+
+	    static createFrom(source: any = {}) {
+	        return new ARPAnomaly(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.ipAddress = source["ipAddress"];
+	        this.macAddress = source["macAddress"];
+	        this.previousMacAddress = source["previousMacAddress"];
+	        this.otherMacAddresses = source["otherMacAddresses"];
+	        this.otherIpAddresses = source["otherIpAddresses"];
+	        this.timestamp = source["timestamp"];
+	        this.message = source["message"];
+	    }
+	}
+
+	export class ARPWatchSettings {
+	    enabled: boolean;
+	    maxIpsPerMac: number;
+	    trustedMacs: string[];
+
+	    static createFrom(source: any = {}) {
+	        return new ARPWatchSettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.maxIpsPerMac = source["maxIpsPerMac"];
+	        this.trustedMacs = source["trustedMacs"];
+	    }
+	}
+
+	export class DHCPServerOffer {
+	    serverId: string;
+	    serverAddress: string;
+	    offeredIp: string;
+	    subnetMask?: string;
+	    gateways: string[];
+	    dnsServers: string[];
+	    leaseSeconds: number;
+	    allowed: boolean;
+
+	    static createFrom(source: any = {}) {
+	        return new DHCPServerOffer(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.serverId = source["serverId"];
+	        this.serverAddress = source["serverAddress"];
+	        this.offeredIp = source["offeredIp"];
+	        this.subnetMask = source["subnetMask"];
+	        this.gateways = source["gateways"];
+	        this.dnsServers = source["dnsServers"];
+	        this.leaseSeconds = source["leaseSeconds"];
+	        this.allowed = source["allowed"];
+	    }
+	}
+
+	export class DHCPCheckResult {
+	    interface: string;
+	    timestamp: string;
+	    offers: DHCPServerOffer[];
+	    rogueServers: string[];
+
+	    static createFrom(source: any = {}) {
+	        return new DHCPCheckResult(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.timestamp = source["timestamp"];
+	        this.offers = source["offers"];
+	        this.rogueServers = source["rogueServers"];
+	    }
+	}
+
+	export class DHCPCheckSettings {
+	    interface: string;
+	    allowedServers: string[];
+	    intervalMinutes: number;
+	    timeoutSeconds: number;
+
+	    static createFrom(source: any = {}) {
+	        return new DHCPCheckSettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.allowedServers = source["allowedServers"];
+	        this.intervalMinutes = source["intervalMinutes"];
+	        this.timeoutSeconds = source["timeoutSeconds"];
+	    }
+	}
+
+	export class NetworkInterfaceInfo {
+	    name: string;
+	    macAddress: string;
+	    addresses: string[];
+
+	    static createFrom(source: any = {}) {
+	        return new NetworkInterfaceInfo(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.macAddress = source["macAddress"];
+	        this.addresses = source["addresses"];
+	    }
+	}
+
+	export class ScanSummary {
+	    addressesScanned: number;
+	    hostsFound: number;
+	    openPorts: number;
+	    deviceTypes: Record<string, number>;
+	    portCounts: Record<number, number>;
+
+	    static createFrom(source: any = {}) {
+	        return new ScanSummary(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.addressesScanned = source["addressesScanned"];
+	        this.hostsFound = source["hostsFound"];
+	        this.openPorts = source["openPorts"];
+	        this.deviceTypes = source["deviceTypes"];
+	        this.portCounts = source["portCounts"];
+	    }
+	}
+
+	export class ScanResultInfo {
+	    id: string;
+	    parameters: scanner.ScanRange;
+	    startedAt: string;
+	    completedAt: string;
+	    durationMs: number;
+	    summary: ScanSummary;
+    checkedPorts?: string;
+    portsUnknown?: boolean;
+
+	    static createFrom(source: any = {}) {
+	        return new ScanResultInfo(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.parameters = source["parameters"];
+	        this.startedAt = source["startedAt"];
+	        this.completedAt = source["completedAt"];
+	        this.durationMs = source["durationMs"];
+	        this.summary = source["summary"];
+        this.checkedPorts = source["checkedPorts"];
+        this.portsUnknown = source["portsUnknown"];
+	    }
+	}
+
+	export class ScanResult {
+	    id: string;
+	    parameters: scanner.ScanRange;
+	    startedAt: string;
+	    completedAt: string;
+	    durationMs: number;
+	    summary: ScanSummary;
+    checkedPorts?: string;
+    portsUnknown?: boolean;
+	    hosts: scanner.Host[];
+
+	    static createFrom(source: any = {}) {
+	        return new ScanResult(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.parameters = source["parameters"];
+	        this.startedAt = source["startedAt"];
+	        this.completedAt = source["completedAt"];
+	        this.durationMs = source["durationMs"];
+	        this.summary = source["summary"];
+        this.checkedPorts = source["checkedPorts"];
+        this.portsUnknown = source["portsUnknown"];
+	        this.hosts = source["hosts"];
+	    }
+	}
+
+	export class FieldChange {
+	    field: string;
+	    before: string;
+	    after: string;
+
+	    static createFrom(source: any = {}) {
+	        return new FieldChange(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.field = source["field"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	    }
+	}
+
+	export class ServiceChange {
+	    port: number;
+	    name?: string;
+	    before: string;
+	    after: string;
+
+	    static createFrom(source: any = {}) {
+	        return new ServiceChange(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.port = source["port"];
+	        this.name = source["name"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	    }
+	}
+
+	export class HostDiff {
+	    ipAddress: string;
+	    hostname?: string;
+	    changes: FieldChange[];
+	    openedPorts: number[];
+	    closedPorts: number[];
+	    serviceChanges: ServiceChange[];
+
+	    static createFrom(source: any = {}) {
+	        return new HostDiff(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ipAddress = source["ipAddress"];
+	        this.hostname = source["hostname"];
+	        this.changes = source["changes"];
+	        this.openedPorts = source["openedPorts"];
+	        this.closedPorts = source["closedPorts"];
+	        this.serviceChanges = source["serviceChanges"];
+	    }
+	}
+
+	export class ScanDiff {
+	    scanA: ScanResultInfo;
+	    scanB: ScanResultInfo;
+	    appeared: scanner.Host[];
+	    disappeared: scanner.Host[];
+	    changed: HostDiff[];
+	    unchanged: number;
+
+	    static createFrom(source: any = {}) {
+	        return new ScanDiff(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.scanA = source["scanA"];
+	        this.scanB = source["scanB"];
+	        this.appeared = source["appeared"];
+	        this.disappeared = source["disappeared"];
+	        this.changed = source["changed"];
+	        this.unchanged = source["unchanged"];
+	    }
+	}
+
+	export class ExportSettings {
+	    fields: string[];
+	    csvLayout: string;
+
+	    static createFrom(source: any = {}) {
+	        return new ExportSettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fields = source["fields"];
+	        this.csvLayout = source["csvLayout"];
+	    }
+	}
+
+	export class APISettings {
+	    enabled: boolean;
+	    address: string;
+	    token: string;
+	    metricsToken: string;
+
+	    static createFrom(source: any = {}) {
+	        return new APISettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.address = source["address"];
+	        this.token = source["token"];
+	        this.metricsToken = source["metricsToken"];
+	    }
+	}
+}
+
+export namespace alerting {
 	
+	export class WebhookConfig {
+	    name: string;
+	    enabled: boolean;
+	    url: string;
+	    format: string;
+	    template?: string;
+	    headers?: Record<string, string>;
+	    events?: string[];
+	    maxRetries?: number;
+
 	    static createFrom(source: any = {}) {
-	        return new ScanRange(source);
+	        return new WebhookConfig(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.enabled = source["enabled"];
+	        this.url = source["url"];
+	        this.format = source["format"];
+	        this.template = source["template"];
+	        this.headers = source["headers"];
+	        this.events = source["events"];
+	        this.maxRetries = source["maxRetries"];
+	    }
+	}
+
+	export class SMTPConfig {
+	    enabled: boolean;
+	    host: string;
+	    port: number;
+	    security: string;
+	    username?: string;
+	    password?: string;
+	    from: string;
+	    to: string[];
+	    events?: string[];
+	    digestSeconds: number;
+	    insecureSkipVerify: boolean;
+	    subjectPrefix?: string;
+
+	    static createFrom(source: any = {}) {
+	        return new SMTPConfig(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.security = source["security"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.events = source["events"];
+	        this.digestSeconds = source["digestSeconds"];
+	        this.insecureSkipVerify = source["insecureSkipVerify"];
+	        this.subjectPrefix = source["subjectPrefix"];
+	    }
+	}
+
+	export class DNDSchedule {
+	    enabled: boolean;
+	    start: string;
+	    end: string;
+	    days?: number[];
+
+	    static createFrom(source: any = {}) {
+	        return new DNDSchedule(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.days = source["days"];
+	    }
+	}
+
+	export class DesktopConfig {
+	    enabled: boolean;
+	    events?: string[];
+	    hostIntervalSeconds: number;
+	    maxPerMinute: number;
+	    doNotDisturb: DNDSchedule;
+
+	    static createFrom(source: any = {}) {
+	        return new DesktopConfig(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.events = source["events"];
+	        this.hostIntervalSeconds = source["hostIntervalSeconds"];
+	        this.maxPerMinute = source["maxPerMinute"];
+	        this.doNotDisturb = source["doNotDisturb"];
 	    }
+	}
+}
+
+export namespace storage {
 	
+	export class Stats {
+	    segments: number;
+	    totalBytes: number;
+	    liveBytes: number;
+	    buckets: Record<string, number>;
+	    skippedBytes: number;
+
+	    static createFrom(source: any = {}) {
+	        return new Stats(source);
+	    }
+
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.startIp = source["startIp"];
-	        this.endIp = source["endIp"];
-	        this.ports = source["ports"];
-	        this.searchHiddenHosts = source["searchHiddenHosts"];
-	        this.hiddenHostsPorts = source["hiddenHostsPorts"];
+	        this.segments = source["segments"];
+	        this.totalBytes = source["totalBytes"];
+	        this.liveBytes = source["liveBytes"];
+	        this.buckets = source["buckets"];
+	        this.skippedBytes = source["skippedBytes"];
 	    }
 	}
+}
 
-	export class ScanHistoryItem {
+export namespace history {
+	
+	export class Item {
+	    id: string;
 	    startIp: string;
 	    endIp: string;
 	    timestamp: string; // Go time.Time is marshalled to ISO string
+	    lastScanId?: string;
+	    name?: string;
+	    pinned: boolean;
 
 	    static createFrom(source: any = {}) {
-	        return new ScanHistoryItem(source);
+	        return new Item(source);
 	    }
 
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
 	        this.startIp = source["startIp"];
 	        this.endIp = source["endIp"];
 	        this.timestamp = source["timestamp"];
+	        this.lastScanId = source["lastScanId"];
+	        this.name = source["name"];
+	        this.pinned = source["pinned"];
+	    }
+	}
+
+	export class Settings {
+	    maxItems: number;
+
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxItems = source["maxItems"];
+	    }
+	}
+
+}
+
+export namespace monitor {
+	
+	export class StatusChange {
+	    timestamp: string;
+	    isOnline: boolean;
+	    status: string;
+	    maintenanceWindow?: string;
+
+	    static createFrom(source: any = {}) {
+	        return new StatusChange(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = source["timestamp"];
+	        this.isOnline = source["isOnline"];
+	        this.status = source["status"];
+	        this.maintenanceWindow = source["maintenanceWindow"];
+	    }
+	}
+
+	export class HostState {
+	    host: scanner.Host;
+	    isOnline: boolean;
+	    status: string;
+	    parentIp: string;
+	    groups: string[];
+	    flapCount: number;
+	    maintenanceWindow: string;
+	    lastChecked: string;
+	    lastChange: string;
+	    lastPortScan: string;
+	    history: StatusChange[];
+
+	    static createFrom(source: any = {}) {
+	        return new HostState(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.isOnline = source["isOnline"];
+	        this.status = source["status"];
+	        this.parentIp = source["parentIp"];
+	        this.groups = source["groups"];
+	        this.flapCount = source["flapCount"];
+	        this.maintenanceWindow = source["maintenanceWindow"];
+	        this.lastChecked = source["lastChecked"];
+	        this.lastChange = source["lastChange"];
+	        this.lastPortScan = source["lastPortScan"];
+	        this.history = source["history"];
+	    }
+	}
+
+	export class MaintenanceWindow {
+	    id: string;
+	    name: string;
+	    enabled: boolean;
+	    hosts?: string[];
+	    groups?: string[];
+	    start?: string;
+	    end?: string;
+	    cron?: string;
+	    durationMinutes?: number;
+
+	    static createFrom(source: any = {}) {
+	        return new MaintenanceWindow(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.enabled = source["enabled"];
+	        this.hosts = source["hosts"];
+	        this.groups = source["groups"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.cron = source["cron"];
+	        this.durationMinutes = source["durationMinutes"];
+	    }
+	}
+
+	export class MaintenanceWindowStatus {
+	    id: string;
+	    name: string;
+	    enabled: boolean;
+	    hosts?: string[];
+	    groups?: string[];
+	    start?: string;
+	    end?: string;
+	    cron?: string;
+	    durationMinutes?: number;
+	    active: boolean;
+	    nextStart?: string;
+
+	    static createFrom(source: any = {}) {
+	        return new MaintenanceWindowStatus(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.enabled = source["enabled"];
+	        this.hosts = source["hosts"];
+	        this.groups = source["groups"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.cron = source["cron"];
+	        this.durationMinutes = source["durationMinutes"];
+	        this.active = source["active"];
+	        this.nextStart = source["nextStart"];
+	    }
+	}
+
+	export class PortRecheckSettings {
+	    intervalMinutes: number;
+	    ports: number[];
+
+	    static createFrom(source: any = {}) {
+	        return new PortRecheckSettings(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.intervalMinutes = source["intervalMinutes"];
+	        this.ports = source["ports"];
+	    }
+	}
+
+}
+
+export namespace scanner {
+	
+	export class ScanRange {
+	    startIp: string;
+	    endIp: string;
+	    ports: number[];
+	    searchHiddenHosts: boolean;
+	    hiddenHostsPorts: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.startIp = source["startIp"];
+	        this.endIp = source["endIp"];
+	        this.ports = source["ports"];
+	        this.searchHiddenHosts = source["searchHiddenHosts"];
+	        this.hiddenHostsPorts = source["hiddenHostsPorts"];
 	    }
 	}
 
@@ -43,8 +763,10 @@ export namespace main {
 	    ipAddress: string;
 	    hostname?: string;
 	    macAddress?: string;
+	    vendor?: string;
 	    os?: string;
 	    openPorts?: number[];
+	    services?: ServiceInfo[];
 	    deviceType?: string;
 
 	    static createFrom(source: any = {}) {
@@ -56,9 +778,96 @@ export namespace main {
 	        this.ipAddress = source["ipAddress"];
 	        this.hostname = source["hostname"];
 	        this.macAddress = source["macAddress"];
+	        this.vendor = source["vendor"];
 	        this.os = source["os"];
 	        this.openPorts = source["openPorts"];
+	        this.services = source["services"];
 	        this.deviceType = source["deviceType"];
 	    }
 	}
+
+	export class ServiceInfo {
+	    port: number;
+	    name?: string;
+	    version?: string;
+	    banner?: string;
+	    tls?: TLSInfo;
+
+	    static createFrom(source: any = {}) {
+	        return new ServiceInfo(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.port = source["port"];
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.banner = source["banner"];
+	        this.tls = source["tls"];
+	    }
+	}
+
+	export class TLSInfo {
+	    version: string;
+	    subject: string;
+	    issuer: string;
+	    dnsNames?: string[];
+	    notBefore: string;
+	    notAfter: string;
+	    selfSigned: boolean;
+	    keyType: string;
+	    keyBits: number;
+	    signatureAlgorithm: string;
+
+	    static createFrom(source: any = {}) {
+	        return new TLSInfo(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.subject = source["subject"];
+	        this.issuer = source["issuer"];
+	        this.dnsNames = source["dnsNames"];
+	        this.notBefore = source["notBefore"];
+	        this.notAfter = source["notAfter"];
+	        this.selfSigned = source["selfSigned"];
+	        this.keyType = source["keyType"];
+	        this.keyBits = source["keyBits"];
+	        this.signatureAlgorithm = source["signatureAlgorithm"];
+	    }
+	}
+
+}
+
+export namespace mqtt {
+	
+	export class Config {
+	    enabled: boolean;
+	    broker: string;
+	    tls: boolean;
+	    username?: string;
+	    password?: string;
+	    clientId?: string;
+	    topicPrefix?: string;
+	    discovery: boolean;
+	    discoveryPrefix?: string;
+
+	    static createFrom(source: any = {}) {
+	        return new Config(source);
+	    }
+
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.broker = source["broker"];
+	        this.tls = source["tls"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.clientId = source["clientId"];
+	        this.topicPrefix = source["topicPrefix"];
+	        this.discovery = source["discovery"];
+	        this.discoveryPrefix = source["discoveryPrefix"];
+	    }
+	}
 }
@@ -0,0 +1,279 @@
+// Package history keeps the list of recently scanned IP ranges, persisted in the application
+// store. Entries can be named and pinned; the oldest unpinned ones are evicted beyond a
+// configurable size.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/events"
+	"netview/storage"
+)
+
+// Item represents a single entry in the scan history.
+// Ensure JSON tags match frontend expectations (camelCase).
+type Item struct {
+	ID         string    `json:"id"`
+	StartIP    string    `json:"startIp"`
+	EndIP      string    `json:"endIp"`
+	Timestamp  time.Time `json:"timestamp"`
+	LastScanID string    `json:"lastScanId,omitempty"` // Stored result of the latest completed scan of this range
+	Name       string    `json:"name,omitempty"`       // Friendly name, e.g. "Office VLAN 20"
+	Pinned     bool      `json:"pinned"`               // Pinned items are never evicted
+}
+
+// Settings controls how much scan history is kept.
+type Settings struct {
+	MaxItems int `json:"maxItems"` // Unpinned items kept; the oldest are evicted beyond this
+}
+
+// Buckets of the application store used by the history.
+const (
+	Bucket     = "history"      // Item ID -> Item
+	MetaBucket = "history_meta" // "settings" -> Settings
+)
+
+const (
+	DefaultMaxItems = 10   // Unpinned items kept unless configured otherwise
+	MaxItemsLimit   = 1000 // Largest allowed MaxItems
+)
+
+// History is the scan history. Its methods are safe for concurrent use.
+type History struct {
+	mu       sync.Mutex
+	store    storage.Store // Nil if the store is unavailable; the history is then not persistent
+	log      events.Logger
+	items    []Item // Most recent first
+	settings Settings
+}
+
+// Open loads the scan history from store, which may be nil.
+func Open(store storage.Store, log events.Logger) *History {
+	h := &History{store: store, log: log, items: []Item{}, settings: Settings{MaxItems: DefaultMaxItems}}
+	if store == nil {
+		log.Warning("Data store not available, scan history will not be persistent.")
+		return h
+	}
+	if err := storage.GetJSON(store, MetaBucket, "settings", &h.settings); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Error(fmt.Sprintf("Error loading history settings: %v", err))
+	}
+	if h.settings.MaxItems < 1 {
+		h.settings.MaxItems = DefaultMaxItems
+	}
+
+	// Each item is stored under its own ID; order is restored from the timestamps
+	err := store.ForEach(Bucket, func(key string, value []byte) error {
+		var item Item
+		if err := json.Unmarshal(value, &item); err != nil {
+			log.Error(fmt.Sprintf("Skipping corrupt scan history item %s: %v", key, err))
+			return nil
+		}
+		h.items = append(h.items, item)
+		return nil
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("Error loading scan history: %v", err))
+	}
+	sort.SliceStable(h.items, func(i, j int) bool { return h.items[i].Timestamp.After(h.items[j].Timestamp) })
+	log.Info(fmt.Sprintf("Loaded %d items from scan history.", len(h.items)))
+
+	// Ensure history doesn't exceed max items (e.g. after the setting was lowered)
+	h.trimLocked()
+	return h
+}
+
+// saveLocked writes one history item to the store. The caller must hold h.mu.
+func (h *History) saveLocked(item Item) {
+	if h.store == nil {
+		return // Persistence not available
+	}
+	if err := storage.PutJSON(h.store, Bucket, item.ID, item); err != nil {
+		h.log.Error(fmt.Sprintf("Error saving scan history: %v", err))
+		return
+	}
+	h.log.Debug(fmt.Sprintf("Saved scan history item %s", item.ID))
+}
+
+// deleteLocked removes an item from the store. The caller must hold h.mu.
+func (h *History) deleteLocked(id string) {
+	if h.store == nil {
+		return
+	}
+	if err := h.store.Delete(Bucket, id); err != nil {
+		h.log.Error(fmt.Sprintf("Error deleting scan history item %s: %v", id, err))
+	}
+}
+
+// trimLocked evicts the oldest unpinned items beyond settings.MaxItems. Pinned items are
+// never evicted and do not count towards the limit. The caller must hold h.mu.
+func (h *History) trimLocked() {
+	unpinned := 0
+	kept := h.items[:0]
+	for _, item := range h.items {
+		if !item.Pinned {
+			unpinned++
+			if unpinned > h.settings.MaxItems {
+				h.deleteLocked(item.ID)
+				continue
+			}
+		}
+		kept = append(kept, item)
+	}
+	h.items = kept
+}
+
+// findLocked returns the index of the item with the given ID, or -1. The caller must hold h.mu.
+func (h *History) findLocked(id string) int {
+	for i := range h.items {
+		if h.items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add records a scan of the range. Re-running a range already in the history moves it to the
+// top, keeping its ID, name and pin.
+func (h *History) Add(startIP, endIP string) Item {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	newItem := Item{ID: storage.NewID(), StartIP: startIP, EndIP: endIP}
+	rest := make([]Item, 0, len(h.items))
+	for _, item := range h.items {
+		if item.StartIP == startIP && item.EndIP == endIP {
+			newItem = item
+			continue
+		}
+		rest = append(rest, item)
+	}
+	newItem.Timestamp = time.Now()
+	h.log.Debug(fmt.Sprintf("Adding to history: %+v", newItem))
+
+	// Add to the beginning of the slice (most recent first)
+	h.items = append([]Item{newItem}, rest...)
+
+	// Save the new item, then trim to the configured size
+	h.saveLocked(newItem)
+	h.trimLocked()
+	return newItem
+}
+
+// LinkScan points the entry for the range at its latest stored scan result.
+func (h *History) LinkScan(startIP, endIP, scanID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range h.items {
+		if h.items[i].StartIP == startIP && h.items[i].EndIP == endIP {
+			h.items[i].LastScanID = scanID
+			h.saveLocked(h.items[i])
+			return
+		}
+	}
+}
+
+// List returns a copy of the history, most recent first.
+func (h *History) List() []Item {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Item{}, h.items...) // Never nil, for consistent JSON marshalling
+}
+
+// Delete removes one entry.
+func (h *History) Delete(id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := h.findLocked(id)
+	if i < 0 {
+		return fmt.Errorf("history item %s not found", id)
+	}
+	h.items = append(h.items[:i], h.items[i+1:]...)
+	h.deleteLocked(id)
+	h.log.Debug(fmt.Sprintf("Deleted history item %s", id))
+	return nil
+}
+
+// Clear removes every entry, or only the unpinned ones if keepPinned is set. It returns the
+// number of entries removed.
+func (h *History) Clear(keepPinned bool) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	kept := []Item{}
+	for _, item := range h.items {
+		if keepPinned && item.Pinned {
+			kept = append(kept, item)
+			continue
+		}
+		h.deleteLocked(item.ID)
+	}
+	removed := len(h.items) - len(kept)
+	h.items = kept
+	return removed
+}
+
+// Pin pins or unpins an entry. Pinned entries are never evicted.
+func (h *History) Pin(id string, pinned bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := h.findLocked(id)
+	if i < 0 {
+		return fmt.Errorf("history item %s not found", id)
+	}
+	h.items[i].Pinned = pinned
+	h.saveLocked(h.items[i])
+	if !pinned {
+		h.trimLocked() // Unpinning may take the history over its limit
+	}
+	return nil
+}
+
+// Rename gives an entry a friendly name. An empty name removes it.
+func (h *History) Rename(id string, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := h.findLocked(id)
+	if i < 0 {
+		return fmt.Errorf("history item %s not found", id)
+	}
+	h.items[i].Name = strings.TrimSpace(name)
+	h.saveLocked(h.items[i])
+	return nil
+}
+
+// Settings returns the history settings.
+func (h *History) Settings() Settings {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.settings
+}
+
+// SaveSettings validates and applies the settings, evicting the oldest unpinned entries if
+// the limit was lowered.
+func (h *History) SaveSettings(settings Settings) error {
+	if settings.MaxItems < 1 || settings.MaxItems > MaxItemsLimit {
+		return fmt.Errorf("history size must be between 1 and %d", MaxItemsLimit)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.settings = settings
+	if h.store != nil {
+		if err := storage.PutJSON(h.store, MetaBucket, "settings", settings); err != nil {
+			return fmt.Errorf("saving history settings: %w", err)
+		}
+	}
+	h.trimLocked()
+	return nil
+}
@@ -0,0 +1,161 @@
+package history
+
+import (
+	"slices"
+	"testing"
+
+	"netview/events"
+	"netview/storage"
+)
+
+// openTestHistory opens a history backed by a store in a temporary directory.
+func openTestHistory(t *testing.T) (*History, *storage.LogStore) {
+	t.Helper()
+	store, err := storage.Open(t.TempDir(), storage.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return Open(store, events.Discard), store
+}
+
+// ranges lists the start addresses of the items, most recent first.
+func ranges(items []Item) []string {
+	var out []string
+	for _, item := range items {
+		out = append(out, item.StartIP)
+	}
+	return out
+}
+
+func TestAddMovesRangeToTop(t *testing.T) {
+	h, _ := openTestHistory(t)
+	first := h.Add("10.0.0.1", "10.0.0.9")
+	h.Add("10.0.1.1", "10.0.1.9")
+	if err := h.Rename(first.ID, "Lab"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Pin(first.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	again := h.Add("10.0.0.1", "10.0.0.9")
+	if again.ID != first.ID || again.Name != "Lab" || !again.Pinned || !again.Timestamp.After(first.Timestamp) {
+		t.Errorf("re-added item = %+v, want the existing entry with a new timestamp", again)
+	}
+	if got := ranges(h.List()); !slices.Equal(got, []string{"10.0.0.1", "10.0.1.1"}) {
+		t.Errorf("history = %v", got)
+	}
+	// Same start, different end is another range
+	h.Add("10.0.0.1", "10.0.0.50")
+	if n := len(h.List()); n != 3 {
+		t.Errorf("%d items, want 3", n)
+	}
+}
+
+func TestTrimKeepsPinnedItems(t *testing.T) {
+	h, store := openTestHistory(t)
+	if err := h.SaveSettings(Settings{MaxItems: 2}); err != nil {
+		t.Fatal(err)
+	}
+	oldest := h.Add("10.0.0.1", "10.0.0.1")
+	if err := h.Pin(oldest.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		h.Add(ip, ip)
+	}
+	// Two unpinned items are kept; the pinned one does not count and is never evicted
+	if got := ranges(h.List()); !slices.Equal(got, []string{"10.0.0.4", "10.0.0.3", "10.0.0.1"}) {
+		t.Errorf("history = %v", got)
+	}
+	if keys := store.Keys(Bucket); len(keys) != 3 {
+		t.Errorf("%d items stored, want the evicted one deleted", len(keys))
+	}
+
+	// Unpinning takes the history over its limit, so the oldest goes
+	if err := h.Pin(oldest.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := ranges(h.List()); !slices.Equal(got, []string{"10.0.0.4", "10.0.0.3"}) {
+		t.Errorf("history after unpinning = %v", got)
+	}
+	for _, s := range []Settings{{MaxItems: 0}, {MaxItems: MaxItemsLimit + 1}} {
+		if err := h.SaveSettings(s); err == nil {
+			t.Errorf("settings %+v accepted", s)
+		}
+	}
+
+	// The settings and items survive a restart
+	reopened := Open(store, events.Discard)
+	if reopened.Settings().MaxItems != 2 || len(reopened.List()) != 2 {
+		t.Errorf("reopened: settings %+v, items %v", reopened.Settings(), ranges(reopened.List()))
+	}
+}
+
+func TestRenameDeleteClear(t *testing.T) {
+	h, store := openTestHistory(t)
+	a := h.Add("10.0.0.1", "10.0.0.9")
+	b := h.Add("10.0.1.1", "10.0.1.9")
+	c := h.Add("10.0.2.1", "10.0.2.9")
+
+	if err := h.Rename(a.ID, "  Office VLAN 20 "); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Rename("missing", "x"); err == nil {
+		t.Error("renamed a missing item")
+	}
+	var stored Item
+	if err := storage.GetJSON(store, Bucket, a.ID, &stored); err != nil || stored.Name != "Office VLAN 20" {
+		t.Errorf("stored item = %+v, %v", stored, err)
+	}
+	if err := h.Rename(a.ID, ""); err != nil || h.List()[2].Name != "" {
+		t.Errorf("name not removed: %v", err)
+	}
+
+	if err := h.Delete(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Delete(b.ID); err == nil {
+		t.Error("deleted an item twice")
+	}
+	if got := ranges(h.List()); !slices.Equal(got, []string{"10.0.2.1", "10.0.0.1"}) {
+		t.Errorf("history after delete = %v", got)
+	}
+
+	if err := h.Pin(c.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.Clear(true); n != 1 || len(h.List()) != 1 || h.List()[0].ID != c.ID {
+		t.Errorf("Clear(true) removed %d, left %v", n, ranges(h.List()))
+	}
+	if n := h.Clear(false); n != 1 || len(h.List()) != 0 || len(store.Keys(Bucket)) != 0 {
+		t.Errorf("Clear(false) removed %d, left %v and %d stored", n, ranges(h.List()), len(store.Keys(Bucket)))
+	}
+	if h.List() == nil {
+		t.Error("empty history listed as nil")
+	}
+}
+
+func TestLinkScan(t *testing.T) {
+	h, store := openTestHistory(t)
+	item := h.Add("10.0.0.1", "10.0.0.9")
+	h.Add("10.0.0.1", "10.0.0.50")
+
+	h.LinkScan("10.0.0.1", "10.0.0.9", "scan-1")
+	h.LinkScan("10.0.9.1", "10.0.9.9", "scan-2") // Not in the history
+	var stored Item
+	if err := storage.GetJSON(store, Bucket, item.ID, &stored); err != nil || stored.LastScanID != "scan-1" {
+		t.Errorf("stored item = %+v, %v", stored, err)
+	}
+	for _, i := range h.List() {
+		if want := map[bool]string{true: "scan-1"}[i.ID == item.ID]; i.LastScanID != want {
+			t.Errorf("%s-%s linked to %q, want %q", i.StartIP, i.EndIP, i.LastScanID, want)
+		}
+	}
+
+	// Scanning the range again keeps the link until the new scan is stored
+	if again := h.Add("10.0.0.1", "10.0.0.9"); again.LastScanID != "scan-1" {
+		t.Errorf("re-added item = %+v", again)
+	}
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/alerting"
+	"netview/events"
+	"netview/scanner"
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Known-device statuses. New devices start as pending until approved or ignored.
+const (
+	deviceStatusPending  = "pending"
+	deviceStatusApproved = "approved"
+	deviceStatusIgnored  = "ignored"
+)
+
+// KnownDevice is an inventory entry. Devices are keyed by MAC address; devices whose MAC
+// could not be determined (e.g. behind a router) fall back to their IP address.
+type KnownDevice struct {
+	Key        string    `json:"key"`
+	MACAddress string    `json:"macAddress,omitempty"`
+	IPAddress  string    `json:"ipAddress"`
+	Hostname   string    `json:"hostname,omitempty"`
+	Vendor     string    `json:"vendor,omitempty"`
+	DeviceType string    `json:"deviceType,omitempty"`
+	Name       string    `json:"name,omitempty"` // Friendly name given on approval
+	Status     string    `json:"status"`         // pending, approved or ignored
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+// InventorySettings controls new-device detection and the periodic background sweep.
+type InventorySettings struct {
+	AlertOnNewDevice     bool   `json:"alertOnNewDevice"`
+	SweepEnabled         bool   `json:"sweepEnabled"`
+	SweepStartIP         string `json:"sweepStartIp"`
+	SweepEndIP           string `json:"sweepEndIp"`
+	SweepIntervalMinutes int    `json:"sweepIntervalMinutes"`
+}
+
+const defaultSweepInterval = 30 * time.Minute
+const maxSweepAddresses = 4096 // Background sweeps are limited to a /20
+
+// inventory is the known-device inventory. Hosts found by scans and the background sweep are
+// recorded in it, and those not seen before are reported as new devices.
+type inventory struct {
+	store  storage.Store // Nil if the data store is not available
+	events wailsEvents
+	alerts *alerter
+	sweep  func(ctx context.Context, startIP, endIP string) // Runs one background sweep of the range
+
+	mu          sync.Mutex
+	devices     map[string]*KnownDevice // Key -> device
+	settings    InventorySettings
+	baselined   bool
+	dirty       map[string]bool    // Keys of devices changed since the last save, flushed when a scan completes
+	sweepCancel context.CancelFunc // Stops the running background sweep, if any
+}
+
+// newInventory returns an empty inventory with the default settings.
+func newInventory(store storage.Store, ev wailsEvents, alerts *alerter) *inventory {
+	return &inventory{
+		store:    store,
+		events:   ev,
+		alerts:   alerts,
+		devices:  make(map[string]*KnownDevice),
+		settings: InventorySettings{AlertOnNewDevice: true, SweepIntervalMinutes: int(defaultSweepInterval / time.Minute)},
+		dirty:    make(map[string]bool),
+	}
+}
+
+// openInventory loads the known-device inventory from store. sweep runs one sweep of the
+// configured range once the background sweep is started.
+func openInventory(ctx context.Context, store storage.Store, ev wailsEvents, alerts *alerter, sweep func(ctx context.Context, startIP, endIP string)) *inventory {
+	inv := newInventory(store, ev, alerts)
+	inv.sweep = sweep
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if store == nil {
+		runtime.LogError(ctx, "Data store not available, the inventory will not be saved.")
+		return inv
+	}
+	err := store.ForEach(inventoryBucket, func(key string, value []byte) error {
+		var device KnownDevice
+		if err := json.Unmarshal(value, &device); err != nil {
+			runtime.LogError(ctx, fmt.Sprintf("Skipping corrupt inventory entry %s: %v", key, err))
+			return nil
+		}
+		inv.devices[device.Key] = &device
+		return nil
+	})
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error loading inventory: %v", err))
+	}
+	if err := storage.GetJSON(store, inventoryMetaBucket, "settings", &inv.settings); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		runtime.LogError(ctx, fmt.Sprintf("Error loading inventory settings: %v", err))
+	}
+	if err := storage.GetJSON(store, inventoryMetaBucket, "baselined", &inv.baselined); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		runtime.LogError(ctx, fmt.Sprintf("Error loading inventory baseline state: %v", err))
+	}
+	if !inv.baselined {
+		runtime.LogInfo(ctx, "Inventory baseline not yet established; the first scan will establish it.")
+	}
+	runtime.LogInfo(ctx, fmt.Sprintf("Loaded %d devices from inventory.", len(inv.devices)))
+	return inv
+}
+
+// startSweep starts the background sweep if enabled.
+func (inv *inventory) startSweep(ctx context.Context) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.restartSweepLocked(ctx)
+}
+
+// saveLocked persists the settings, baseline state and every changed device. The caller must
+// hold inv.mu.
+func (inv *inventory) saveLocked(ctx context.Context) {
+	if inv.store == nil {
+		runtime.LogWarning(ctx, "Data store not available, skipping inventory save.")
+		return
+	}
+	if err := storage.PutJSON(inv.store, inventoryMetaBucket, "settings", inv.settings); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error saving inventory settings: %v", err))
+	}
+	if err := storage.PutJSON(inv.store, inventoryMetaBucket, "baselined", inv.baselined); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error saving inventory baseline state: %v", err))
+	}
+	for key := range inv.dirty {
+		var err error
+		if d, ok := inv.devices[key]; ok {
+			err = storage.PutJSON(inv.store, inventoryBucket, key, d)
+		} else {
+			err = inv.store.Delete(inventoryBucket, key) // Removed or re-keyed
+		}
+		if err != nil {
+			runtime.LogError(ctx, fmt.Sprintf("Error saving inventory device %s: %v", key, err))
+			continue // Stays dirty for the next save
+		}
+		delete(inv.dirty, key)
+	}
+}
+
+// snapshot returns a copy of the devices with the given status ("" for all), sorted by IP
+// address.
+func (inv *inventory) snapshot(status string) []KnownDevice {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	devices := make([]KnownDevice, 0, len(inv.devices))
+	for _, d := range inv.devices {
+		if status == "" || d.Status == status {
+			devices = append(devices, *d)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		a, errA := scanner.IPToUint32(devices[i].IPAddress)
+		b, errB := scanner.IPToUint32(devices[j].IPAddress)
+		if errA != nil || errB != nil {
+			return devices[i].IPAddress < devices[j].IPAddress
+		}
+		return a < b
+	})
+	return devices
+}
+
+// inventoryKey returns the inventory key for a host: its MAC address, or its IP as a fallback.
+func inventoryKey(macAddress, ipAddress string) string {
+	if mac := normalizeMAC(macAddress); mac != "" {
+		return mac
+	}
+	return "ip:" + ipAddress
+}
+
+// normalizeMAC upper-cases a MAC address and uses colons as separators.
+func normalizeMAC(mac string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(mac), "-", ":"))
+}
+
+// findDeviceLocked looks a host up by MAC, falling back to IP. A device first seen without a
+// MAC is re-keyed to its MAC once one is known. The caller must hold inv.mu.
+func (inv *inventory) findDeviceLocked(host Host) *KnownDevice {
+	key := inventoryKey(host.MACAddress, host.IPAddress)
+	if d, ok := inv.devices[key]; ok {
+		return d
+	}
+	if host.MACAddress == "" {
+		// No MAC this time: match a MAC-keyed device last seen at this IP
+		for _, d := range inv.devices {
+			if d.IPAddress == host.IPAddress {
+				return d
+			}
+		}
+		return nil
+	}
+	if d, ok := inv.devices["ip:"+host.IPAddress]; ok {
+		delete(inv.devices, d.Key)
+		inv.dirty[d.Key] = true
+		d.Key = key
+		d.MACAddress = normalizeMAC(host.MACAddress)
+		inv.devices[key] = d
+		inv.dirty[key] = true
+		return d
+	}
+	return nil
+}
+
+// observe records a discovered host in the inventory. Hosts not seen before are added as
+// pending, reported with a newDeviceDetected event and sent down the alerting path. Until the
+// first scan has completed, new hosts form the baseline and are approved silently.
+func (inv *inventory) observe(ctx context.Context, host Host) {
+	inv.mu.Lock()
+	now := time.Now()
+	device := inv.recordHostLocked(host, now)
+	if device != nil {
+		inv.saveLocked(ctx)
+	}
+	alert := inv.settings.AlertOnNewDevice
+	inv.mu.Unlock()
+
+	if device == nil {
+		return
+	}
+	runtime.LogInfo(ctx, fmt.Sprintf("New device detected: %s (%s)", device.IPAddress, device.Key))
+	inv.events.Emit(events.NewDeviceDetected, *device)
+	if alert {
+		inv.alerts.raise(newDeviceAlert(*device, now))
+	}
+}
+
+// recordHostLocked updates or adds the inventory entry for host and returns a copy of the
+// device if it is new and should be reported. The caller must hold inv.mu.
+func (inv *inventory) recordHostLocked(host Host, now time.Time) *KnownDevice {
+	if d := inv.findDeviceLocked(host); d != nil {
+		d.LastSeen = now
+		d.IPAddress = host.IPAddress
+		if host.Hostname != "" {
+			d.Hostname = host.Hostname
+		}
+		inv.dirty[d.Key] = true
+		return nil
+	}
+
+	device := &KnownDevice{
+		Key:        inventoryKey(host.MACAddress, host.IPAddress),
+		MACAddress: normalizeMAC(host.MACAddress),
+		IPAddress:  host.IPAddress,
+		Hostname:   host.Hostname,
+		Vendor:     lookupVendor(host.MACAddress),
+		DeviceType: host.DeviceType,
+		Status:     deviceStatusPending,
+		FirstSeen:  now,
+		LastSeen:   now,
+	}
+	inv.devices[device.Key] = device
+	inv.dirty[device.Key] = true
+
+	if !inv.baselined {
+		device.Status = deviceStatusApproved
+		return nil
+	}
+	reported := *device
+	return &reported
+}
+
+// flush saves pending inventory updates. The first completed scan also marks the baseline as
+// established, so later unknown hosts are reported as new.
+func (inv *inventory) flush(ctx context.Context) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if !inv.baselined {
+		inv.baselined = true
+		runtime.LogInfo(ctx, fmt.Sprintf("Inventory baseline established with %d devices.", len(inv.devices)))
+		inv.saveLocked(ctx)
+		return
+	}
+	if len(inv.dirty) > 0 {
+		inv.saveLocked(ctx)
+	}
+}
+
+// lookupVendor returns the OUI vendor for a MAC address, or "" if unknown.
+func lookupVendor(macAddress string) string {
+	if macAddress == "" || macDB == nil {
+		return ""
+	}
+	vendor, err := macDB.VendorLookup(macAddress)
+	if err != nil {
+		return ""
+	}
+	return vendor
+}
+
+// newDeviceAlert builds the alert for a device not in the inventory.
+func newDeviceAlert(device KnownDevice, now time.Time) alerting.Event {
+	event := alerting.Event{
+		Type:       alerting.EventNewDevice,
+		Timestamp:  now,
+		IPAddress:  device.IPAddress,
+		Hostname:   device.Hostname,
+		MACAddress: device.MACAddress,
+		DeviceType: device.DeviceType,
+	}
+	details := []string{}
+	if device.MACAddress != "" {
+		details = append(details, "MAC "+device.MACAddress)
+	}
+	if device.Vendor != "" {
+		details = append(details, device.Vendor)
+	}
+	event.Message = event.Title()
+	if len(details) > 0 {
+		event.Message += " [" + strings.Join(details, ", ") + "]"
+	}
+	return event
+}
+
+// restartSweepLocked stops any running background sweep and starts a new one if enabled.
+// The caller must hold inv.mu.
+func (inv *inventory) restartSweepLocked(ctx context.Context) {
+	if inv.sweepCancel != nil {
+		inv.sweepCancel()
+		inv.sweepCancel = nil
+	}
+	s := inv.settings
+	if !s.SweepEnabled {
+		return
+	}
+	interval := time.Duration(s.SweepIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+
+	sweepCtx, cancel := context.WithCancel(ctx)
+	inv.sweepCancel = cancel
+	go func() {
+		runtime.LogInfo(sweepCtx, fmt.Sprintf("Background device sweep of %s - %s every %s started.", s.SweepStartIP, s.SweepEndIP, interval))
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			inv.sweep(sweepCtx, s.SweepStartIP, s.SweepEndIP)
+			select {
+			case <-sweepCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runInventorySweep quietly probes every address in the range and feeds live hosts to the
+// inventory. Unlike a GUI scan it does not emit hostFound events or touch scan history.
+func (a *App) runInventorySweep(ctx context.Context, startIP, endIP string) {
+	params := ScanRange{StartIP: startIP, EndIP: endIP}
+	target, err := params.Range()
+	if err != nil {
+		runtime.LogWarning(ctx, fmt.Sprintf("Skipping background sweep: invalid range %s - %s", startIP, endIP))
+		return
+	}
+
+	runtime.LogDebug(ctx, fmt.Sprintf("Background sweep of %s - %s starting.", startIP, endIP))
+	_, completed := a.scanner.Run(ctx, []scanner.Range{target}, params, scanner.ObserverFunc(func(host Host) {
+		a.observeHost(ctx, host, params.ServicePorts())
+	}))
+	if !completed {
+		return
+	}
+	a.flushObservations(ctx)
+	runtime.LogDebug(ctx, "Background sweep finished.")
+}
+
+// GetInventory returns every device in the known-device inventory.
+func (a *App) GetInventory() []KnownDevice {
+	return a.inventory.snapshot("")
+}
+
+// GetPendingDevices returns the devices detected as new and awaiting approval.
+func (a *App) GetPendingDevices() []KnownDevice {
+	return a.inventory.snapshot(deviceStatusPending)
+}
+
+// ApproveDevice marks a device as known and trusted, optionally giving it a friendly name.
+func (a *App) ApproveDevice(key string, name string) error {
+	return a.setDeviceStatus(key, deviceStatusApproved, name)
+}
+
+// IgnoreDevice moves a device into the inventory as ignored: it is known, so it no longer
+// triggers new-device alerts, but it is not marked as trusted.
+func (a *App) IgnoreDevice(key string) error {
+	return a.setDeviceStatus(key, deviceStatusIgnored, "")
+}
+
+// setDeviceStatus updates a device's status and (if non-empty) its name.
+func (a *App) setDeviceStatus(key, status, name string) error {
+	a.inventory.mu.Lock()
+	defer a.inventory.mu.Unlock()
+
+	d, ok := a.inventory.devices[key]
+	if !ok {
+		return fmt.Errorf("device %s not found in inventory", key)
+	}
+	d.Status = status
+	if name = strings.TrimSpace(name); name != "" {
+		d.Name = name
+	}
+	a.inventory.dirty[key] = true
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Device %s marked %s.", key, status))
+	a.inventory.saveLocked(a.ctx)
+	return nil
+}
+
+// RemoveInventoryDevice deletes a device from the inventory; it will be reported as new if seen again.
+func (a *App) RemoveInventoryDevice(key string) error {
+	a.inventory.mu.Lock()
+	defer a.inventory.mu.Unlock()
+
+	if _, ok := a.inventory.devices[key]; !ok {
+		return fmt.Errorf("device %s not found in inventory", key)
+	}
+	delete(a.inventory.devices, key)
+	a.inventory.dirty[key] = true
+	a.inventory.saveLocked(a.ctx)
+	return nil
+}
+
+// GetInventorySettings returns the new-device detection settings.
+func (a *App) GetInventorySettings() InventorySettings {
+	a.inventory.mu.Lock()
+	defer a.inventory.mu.Unlock()
+	return a.inventory.settings
+}
+
+// SaveInventorySettings validates and applies the new-device detection settings, restarting
+// the background sweep as needed.
+func (a *App) SaveInventorySettings(settings InventorySettings) error {
+	if settings.SweepEnabled {
+		startNum, errStart := scanner.IPToUint32(settings.SweepStartIP)
+		endNum, errEnd := scanner.IPToUint32(settings.SweepEndIP)
+		if errStart != nil || errEnd != nil {
+			return fmt.Errorf("invalid sweep range: %v %v", errStart, errEnd)
+		}
+		if startNum > endNum {
+			return fmt.Errorf("sweep start IP cannot be greater than end IP")
+		}
+		if endNum-startNum >= maxSweepAddresses {
+			return fmt.Errorf("sweep range is limited to %d addresses", maxSweepAddresses)
+		}
+		if settings.SweepIntervalMinutes < 1 {
+			return fmt.Errorf("sweep interval must be at least one minute")
+		}
+	}
+
+	a.inventory.mu.Lock()
+	defer a.inventory.mu.Unlock()
+
+	a.inventory.settings = settings
+	a.inventory.saveLocked(a.ctx)
+	a.inventory.restartSweepLocked(a.ctx)
+	return nil
+}
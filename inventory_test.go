@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"netview/alerting"
+)
+
+// testInventory returns an empty inventory without a store, baselined or not.
+func testInventory(baselined bool) *inventory {
+	inv := newInventory(nil, wailsEvents{}, nil)
+	inv.baselined = baselined
+	return inv
+}
+
+// recordInventoryHost feeds one discovered host to the inventory and returns the device to report, if any.
+func recordInventoryHost(inv *inventory, at time.Time, host Host) *KnownDevice {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return inv.recordHostLocked(host, at)
+}
+
+func TestInventoryFirstScanIsSilentBaseline(t *testing.T) {
+	inv := testInventory(false)
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, h := range []Host{{IPAddress: "192.168.1.1", MACAddress: "aa-bb-cc-00-00-01"}, {IPAddress: "192.168.1.2"}} {
+		if d := recordInventoryHost(inv, t0, h); d != nil {
+			t.Errorf("%s reported during the baseline scan: %+v", h.IPAddress, d)
+		}
+	}
+	devices := inv.snapshot("")
+	if len(devices) != 2 || devices[0].Key != "AA:BB:CC:00:00:01" || devices[1].Key != "ip:192.168.1.2" {
+		t.Fatalf("baseline = %+v", devices)
+	}
+	for _, d := range devices {
+		if d.Status != deviceStatusApproved || !d.FirstSeen.Equal(t0) {
+			t.Errorf("baseline device = %+v", d)
+		}
+	}
+}
+
+func TestInventoryNewDeviceAfterBaseline(t *testing.T) {
+	inv := testInventory(true)
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordInventoryHost(inv, t0, Host{IPAddress: "192.168.1.2"}) // Known before the MAC was
+
+	host := Host{IPAddress: "192.168.1.30", MACAddress: "aa:bb:cc:00:00:30", Hostname: "tablet", DeviceType: "Tablet"}
+	device := recordInventoryHost(inv, t0.Add(time.Hour), host)
+	if device == nil {
+		t.Fatal("new device not reported")
+	}
+	if device.Key != "AA:BB:CC:00:00:30" || device.Status != deviceStatusPending || device.Hostname != "tablet" || !device.FirstSeen.Equal(t0.Add(time.Hour)) {
+		t.Errorf("reported device = %+v", device)
+	}
+	event := newDeviceAlert(*device, device.FirstSeen)
+	if event.Type != alerting.EventNewDevice || event.IPAddress != "192.168.1.30" || event.Message != event.Title()+" [MAC AA:BB:CC:00:00:30]" {
+		t.Errorf("alert = %+v", event)
+	}
+
+	// Seen again it is only updated, and an IP-keyed device is re-keyed to its MAC
+	if d := recordInventoryHost(inv, t0.Add(2*time.Hour), Host{IPAddress: "192.168.1.31", MACAddress: "AA:BB:CC:00:00:30"}); d != nil {
+		t.Errorf("known device reported again: %+v", d)
+	}
+	if d := recordInventoryHost(inv, t0.Add(2*time.Hour), Host{IPAddress: "192.168.1.2", MACAddress: "aa:bb:cc:00:00:02"}); d != nil {
+		t.Errorf("device gaining a MAC reported as new: %+v", d)
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if d := inv.devices["AA:BB:CC:00:00:30"]; d.IPAddress != "192.168.1.31" || d.Hostname != "tablet" || d.Status != deviceStatusPending {
+		t.Errorf("updated device = %+v", d)
+	}
+	if _, ok := inv.devices["ip:192.168.1.2"]; ok || inv.devices["AA:BB:CC:00:00:02"] == nil || !inv.dirty["ip:192.168.1.2"] {
+		t.Errorf("device not re-keyed: %v", inv.devices)
+	}
+}
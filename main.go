@@ -3,10 +3,15 @@ package main
 import (
 	"context"
 	"embed"
+	"fmt"
+	"os"
 
-	// "fmt" // No longer needed here
-	// "net" // No longer needed here
+	"netview/events"
+	"netview/history"
+	"netview/monitor"
 	"netview/ouidb"
+	"netview/scanner"
+	"netview/storage"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -25,7 +30,22 @@ var macDB *ouidb.OuiDb
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx         context.Context
+	events      wailsEvents      // Events and log messages for the frontend and API clients
+	store       storage.Store    // Nil if the data store could not be opened
+	scanner     *scanner.Scanner // Shared by GUI scans, the inventory sweep and monitoring
+	history     *history.History // Recently scanned ranges
+	monitor     *monitor.Monitor // Host status monitoring
+	metrics     *appMetrics      // Prometheus metrics served on /metrics
+	scanResults *scanResults     // Stored scan results
+	scanJobs    *scanJobs        // Scans started through the API
+	alerts      *alerter         // Alert destinations
+	inventory   *inventory       // Known devices
+	portWatch   *portWatch       // Per-host port baseline
+	arpWatch    *arpWatch        // IP/MAC bindings
+	dhcpCheck   *dhcpCheck       // Rogue DHCP check
+	api         *apiServer       // REST API server
+	mqtt        *mqttBridge      // MQTT publisher
 }
 
 // NewApp creates a new App application struct
@@ -37,49 +57,109 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.events = wailsEvents{ctx: ctx, stream: events.NewHub()}
 	// Initialize the OUI database
 	initOuiDatabase(ctx)
+	// Open the data store, importing the JSON files of earlier versions on first launch
+	a.store = openAppStore(ctx)
+	// Load scan history
+	a.history = history.Open(a.store, a.events)
+	// Register the metrics before the scanner and monitor record into them
+	a.metrics = newAppMetrics(a)
+	// Load the index of stored scan results
+	a.scanResults = openScanResults(ctx, a.store)
+	a.scanJobs = &scanJobs{}
+	// Load alert destinations before monitoring can raise alerts
+	a.alerts = openAlerter(ctx)
+	// Load the per-host port state used to detect port changes between scans
+	a.portWatch = openPortWatch(ctx, a.events, a.alerts)
+	// Load the IP/MAC bindings used to detect ARP spoofing and IP conflicts
+	a.arpWatch = openARPWatch(ctx, a.events, a.alerts)
 	// Initialize the scanner with the context and the OUI database
-	//InitScanner(ctx, macDB)
-	// Initialize and load scan history
-	initHistory(ctx) // Pass context for logging
+	a.scanner = a.newAppScanner()
+	// Load the known-device inventory and start the background sweep if enabled
+	a.inventory = openInventory(ctx, a.store, a.events, a.alerts, a.runInventorySweep)
+	a.inventory.startSweep(ctx)
+	// Load the rogue DHCP check settings
+	a.dhcpCheck = openDHCPCheck(ctx, a.events, a.alerts)
 	// Initialize monitoring components
-	a.InitializeMonitor()
+	a.monitor = a.newAppMonitor(ctx)
+	// Resume the monitoring session that was active when NetView last exited
+	a.monitor.Resume()
+	// Start the REST API server if enabled, now that the services it exposes are ready
+	a.api = openAPIServer(ctx, a)
+	// Start publishing to the MQTT broker if enabled
+	a.mqtt = openMQTTBridge(ctx, a)
 	runtime.LogInfo(ctx, "Application startup complete.")
 }
 
+// shutdown is called when the app is closing. The monitoring session is saved
+// so it can be resumed with its last known state on the next launch.
+func (a *App) shutdown(ctx context.Context) {
+	if a.api != nil {
+		a.api.stop(ctx)
+	}
+	if a.mqtt != nil {
+		a.mqtt.stop()
+	}
+	if a.monitor != nil {
+		a.monitor.Persist()
+	}
+	a.closeStore(ctx)
+}
+
 // initOuiDatabase initializes the OUI database from the embedded assets/oui.txt file.
 func initOuiDatabase(ctx context.Context) {
-	file, err := ouiData.Open("macdb/oui.txt")
+	db, err := loadOuiDatabase()
 	if err != nil {
-		runtime.LogError(ctx, "Failed to open oui.txt: "+err.Error())
+		runtime.LogError(ctx, err.Error())
 		return
 	}
+	macDB = db // Assign the global only once fully loaded
+}
+
+// loadOuiDatabase parses the embedded OUI list.
+func loadOuiDatabase() (*ouidb.OuiDb, error) {
+	file, err := ouiData.Open("macdb/oui.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open oui.txt: %w", err)
+	}
 	defer file.Close()
-	macDB := &ouidb.OuiDb{}
-	if err := macDB.Load(file); err != nil {
-		runtime.LogError(ctx, "Failed to initialize OUI database: "+err.Error())
+	db := &ouidb.OuiDb{}
+	if err := db.Load(file); err != nil {
+		return nil, fmt.Errorf("failed to initialize OUI database: %w", err)
 	}
+	return db, nil
 }
 
-// ScanNetwork now accepts a pointer to ScanRange, which may include IPs and/or Ports.
-// If scanRange is nil, or if fields within are zero-valued, PerformScan handles defaults.
+// ScanNetwork accepts a pointer to ScanRange, which may include IPs and/or Ports.
+// Zero-valued fields such as Ports fall back to the defaults; a nil scanRange (e.g. a
+// frontend call with `null`) is reported as an invalid range.
 func (a *App) ScanNetwork(scanRange *ScanRange) error {
-	// If scanRange is nil (e.g., from a frontend call with `null`), PerformScan should handle it.
-	// Or, if scanRange is an empty struct (e.g., `{}` from frontend), specific fields like Ports might be set.
-	return PerformScan(a.ctx, scanRange) // Pass context to PerformScan
+	if scanRange == nil {
+		scanRange = &ScanRange{}
+	}
+	_, err := a.performScan(*scanRange)
+	return err
 }
 
-// GetScanHistory is defined in history.go as a method of *App.
-// It will be automatically bound when `app` instance of `*App` is bound.
-
-// StartMonitoring, StopMonitoring, IsMonitoringActive will be defined in monitor.go
-// and bound automatically as methods of *App.
-
 func main() {
+	// Subcommands such as `netview scan` run headless and never open the window
+	if len(os.Args) > 1 {
+		if command, ok := cliCommands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
+	// When resuming a monitoring session the user can choose to start minimised, purely as a monitor
+	windowStartState := options.Normal
+	if shouldStartMinimised() {
+		windowStartState = options.Minimised
+	}
+
 	// Create application with options
 	err := wails.Run(&options.App{
 		Title:     "NetView - Network Scanner",
@@ -92,7 +172,9 @@ func main() {
 		CSSDragProperty:  "widows",
 		CSSDragValue:     "1",
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1}, // Dark background, can be adjusted
+		WindowStartState: windowStartState,
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app, // Binding the app instance makes all its methods available to the frontend.
 		},
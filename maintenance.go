@@ -0,0 +1,16 @@
+package main
+
+// ListMaintenanceWindows returns all maintenance windows with their current state.
+func (a *App) ListMaintenanceWindows() []MaintenanceWindowStatus {
+	return a.monitor.MaintenanceWindows()
+}
+
+// SaveMaintenanceWindow creates a window (empty ID) or replaces the window with the same ID.
+func (a *App) SaveMaintenanceWindow(window MaintenanceWindow) (MaintenanceWindow, error) {
+	return a.monitor.SaveMaintenanceWindow(window)
+}
+
+// DeleteMaintenanceWindow removes a maintenance window.
+func (a *App) DeleteMaintenanceWindow(id string) error {
+	return a.monitor.DeleteMaintenanceWindow(id)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"netview/metrics"
+	"netview/monitor"
+	"netview/scanner"
+)
+
+// appMetrics are served at /metrics by the API server, so Prometheus can scrape NetView as a
+// blackbox exporter for the LAN.
+type appMetrics struct {
+	registry             *metrics.Registry
+	probes               *metrics.Counter
+	scans                *metrics.Counter
+	hostsFound           *metrics.Counter
+	scanDuration         *metrics.Histogram
+	monitorRTT           *metrics.Histogram
+	monitorCheckDuration *metrics.Histogram
+}
+
+// newAppMetrics registers the app's metrics. The gauges read the monitor and the stored scans
+// of a when scraped.
+func newAppMetrics(a *App) *appMetrics {
+	r := metrics.NewRegistry()
+	m := &appMetrics{
+		registry: r,
+		probes: r.NewCounter("netview_scanner_probes_total",
+			"Probes sent by the scanner for scans, sweeps and monitoring, by type: ping, tcp, udp, dns or neighbor.", "type"),
+		scans: r.NewCounter("netview_scans_total",
+			"Scans started from the window or the API, by how they ended: completed or cancelled.", "state"),
+		hostsFound: r.NewCounter("netview_scan_hosts_found_total",
+			"Live hosts found by scans started from the window or the API."),
+		scanDuration: r.NewHistogram("netview_scan_duration_seconds",
+			"Duration of completed scans.", []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800}),
+		monitorRTT: r.NewHistogram("netview_monitor_rtt_seconds",
+			"Round-trip time of answered monitor checks.", nil, "ip"),
+		monitorCheckDuration: r.NewHistogram("netview_monitor_check_duration_seconds",
+			"Duration of monitor checks, answered or not.", nil, "ip"),
+	}
+
+	r.NewGaugeFunc("netview_monitor_active", "Whether host monitoring is running.", nil,
+		func(set func(float64, ...string)) {
+			set(boolMetric(a.monitor.IsActive()))
+		})
+	r.NewGaugeFunc("netview_monitor_host_up", "Whether a monitored host answered its last check.", []string{"ip", "hostname"},
+		func(set func(float64, ...string)) {
+			for _, s := range a.monitor.Hosts() {
+				set(boolMetric(s.IsOnline), s.Host.IPAddress, s.Host.Hostname)
+			}
+		})
+	r.NewGaugeFunc("netview_monitor_host_unreachable", "Whether a monitored host is down because a host it depends on is down.", []string{"ip"},
+		func(set func(float64, ...string)) {
+			for _, s := range a.monitor.Hosts() {
+				set(boolMetric(s.Status == monitor.StatusUnreachable), s.Host.IPAddress)
+			}
+		})
+	r.NewGaugeFunc("netview_monitor_host_in_maintenance", "Whether a maintenance window covers a monitored host.", []string{"ip"},
+		func(set func(float64, ...string)) {
+			for _, s := range a.monitor.Hosts() {
+				set(boolMetric(s.MaintenanceWindow != ""), s.Host.IPAddress)
+			}
+		})
+	r.NewCounterFunc("netview_monitor_host_flaps_total", "Online/offline transitions of a monitored host outside maintenance windows.", []string{"ip"},
+		func(set func(float64, ...string)) {
+			for _, s := range a.monitor.Hosts() {
+				set(float64(s.FlapCount), s.Host.IPAddress)
+			}
+		})
+	r.NewGaugeFunc("netview_tls_cert_expiry_days", "Days until the certificate of a monitored host's TLS service expires, as seen by the latest stored scan that read one; negative once expired.", []string{"ip", "port", "subject"},
+		func(set func(float64, ...string)) {
+			var ips []string
+			for _, s := range a.monitor.Hosts() {
+				ips = append(ips, s.Host.IPAddress)
+			}
+			now := time.Now()
+			for ip, services := range a.scanResults.latestTLSServices(ips) {
+				for _, svc := range services {
+					set(svc.TLS.NotAfter.Sub(now).Hours()/24, ip, strconv.Itoa(svc.Port), svc.TLS.Subject)
+				}
+			}
+		})
+	return m
+}
+
+// boolMetric returns 1 for true and 0 for false.
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// observeMonitorCheck records the timing of a monitor check.
+func (m *appMetrics) observeMonitorCheck(ip string, online bool, rtt, took time.Duration) {
+	m.monitorCheckDuration.Observe(took.Seconds(), ip)
+	if online && rtt >= 0 {
+		m.monitorRTT.Observe(rtt.Seconds(), ip)
+	}
+}
+
+// observeScanCompletion records how a scan started from the window or the API ended.
+func (m *appMetrics) observeScanCompletion(c scanner.Completion) {
+	if c.Cancelled {
+		m.scans.Inc(scanStateCancelled)
+		return
+	}
+	m.scans.Inc(scanStateCompleted)
+	m.scanDuration.Observe(time.Since(c.StartedAt).Seconds())
+}
+
+// serveMetrics writes the metrics in the Prometheus text format. Check timings of hosts no
+// longer monitored are dropped first.
+func (h apiHandler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	monitored := make(map[string]bool)
+	for _, s := range h.app.ListMonitoredHosts() {
+		monitored[s.Host.IPAddress] = true
+	}
+	keep := func(labelValues []string) bool { return monitored[labelValues[0]] }
+	h.app.metrics.monitorRTT.Retain(keep)
+	h.app.metrics.monitorCheckDuration.Retain(keep)
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	h.app.metrics.registry.WriteTo(w)
+}
+
+// countingProber counts the probes the scanner sends.
+type countingProber struct {
+	scanner.Prober
+	probes *metrics.Counter
+}
+
+func (p countingProber) Ping(ctx context.Context, ip string, timeout time.Duration) (time.Duration, error) {
+	p.probes.Inc("ping")
+	return p.Prober.Ping(ctx, ip, timeout)
+}
+
+func (p countingProber) DialTCP(ctx context.Context, ip string, port int, timeout time.Duration) (net.Conn, error) {
+	p.probes.Inc("tcp")
+	return p.Prober.DialTCP(ctx, ip, port, timeout)
+}
+
+func (p countingProber) UDPExchange(ctx context.Context, ip string, port int, payload []byte, timeout time.Duration) ([]byte, error) {
+	p.probes.Inc("udp")
+	return p.Prober.UDPExchange(ctx, ip, port, payload, timeout)
+}
+
+func (p countingProber) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	p.probes.Inc("dns")
+	return p.Prober.LookupAddr(ctx, ip)
+}
+
+func (p countingProber) Neighbors(ctx context.Context, ip string) ([]string, error) {
+	p.probes.Inc("neighbor")
+	return p.Prober.Neighbors(ctx, ip)
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	probes := r.NewCounter("netview_probes_total", "Probes sent.", "type")
+	rtt := r.NewHistogram("netview_rtt_seconds", "Round-trip time.", []float64{0.1, 0.01}, "ip")
+	r.NewGaugeFunc("netview_up", "Whether the host is up.", []string{"ip", "name"}, func(set func(float64, ...string)) {
+		set(1, "10.0.0.2", `say "hi"\`)
+		set(0, "10.0.0.1", "line\nbreak")
+	})
+	scans := r.NewCounter("netview_scans_total", "Scans\nrun.")
+
+	probes.Inc("tcp")
+	probes.Add(2, "tcp")
+	probes.Inc("ping")
+	rtt.Observe(0.005, "10.0.0.1")
+	rtt.Observe(0.01, "10.0.0.1") // Upper bounds are inclusive
+	rtt.Observe(0.5, "10.0.0.1")
+	scans.Inc()
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP netview_probes_total Probes sent.
+# TYPE netview_probes_total counter
+netview_probes_total{type="ping"} 1
+netview_probes_total{type="tcp"} 3
+# HELP netview_rtt_seconds Round-trip time.
+# TYPE netview_rtt_seconds histogram
+netview_rtt_seconds_bucket{ip="10.0.0.1",le="0.01"} 2
+netview_rtt_seconds_bucket{ip="10.0.0.1",le="0.1"} 2
+netview_rtt_seconds_bucket{ip="10.0.0.1",le="+Inf"} 3
+netview_rtt_seconds_sum{ip="10.0.0.1"} 0.515
+netview_rtt_seconds_count{ip="10.0.0.1"} 3
+# HELP netview_up Whether the host is up.
+# TYPE netview_up gauge
+netview_up{ip="10.0.0.1",name="line\nbreak"} 0
+netview_up{ip="10.0.0.2",name="say \"hi\"\\"} 1
+# HELP netview_scans_total Scans\nrun.
+# TYPE netview_scans_total counter
+netview_scans_total 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramRetain(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("h_seconds", "h", nil, "ip")
+	h.Observe(1, "a")
+	h.Observe(1, "b")
+	h.Retain(func(values []string) bool { return values[0] == "b" })
+
+	var b strings.Builder
+	r.WriteTo(&b)
+	if strings.Contains(b.String(), `ip="a"`) || !strings.Contains(b.String(), `h_seconds_count{ip="b"} 1`) {
+		t.Errorf("output after Retain:\n%s", b.String())
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for a missing label value")
+		}
+	}()
+	NewRegistry().NewCounter("c_total", "c", "type").Inc()
+}
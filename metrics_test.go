@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"netview/scanner"
+	"netview/storage"
+)
+
+func TestLatestTLSServices(t *testing.T) {
+	store, err := storage.Open(t.TempDir(), storage.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	stored := &scanResults{store: store}
+	// storeResults writes results as save does and lists them most recent first
+	storeResults := func(results ...ScanResult) {
+		t.Helper()
+		stored.index = nil
+		for _, r := range results {
+			if err := storage.PutJSON(store, scanResultsBucket, r.ID, r); err != nil {
+				t.Fatal(err)
+			}
+			stored.index = append(stored.index, r.ScanResultInfo)
+		}
+	}
+	web := func(expires time.Time) Host {
+		return Host{IPAddress: "192.168.1.10", OpenPorts: []int{443},
+			Services: []ServiceInfo{{Port: 443, Name: "https", TLS: &scanner.TLSInfo{Subject: "nas.lan", NotAfter: expires}}}}
+	}
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiring, renewed := t0.AddDate(0, 0, 10), t0.AddDate(0, 3, 0)
+	monitored := []string{"192.168.1.10", "192.168.1.20"}
+
+	storeResults(
+		scanResult("b", t0.Add(time.Hour), nil, Host{IPAddress: "192.168.1.10"}), // No certificate read
+		scanResult("a", t0, nil, web(expiring)),
+	)
+	got := stored.latestTLSServices(monitored)
+	if len(got) != 1 || len(got["192.168.1.10"]) != 1 || !got["192.168.1.10"][0].TLS.NotAfter.Equal(expiring) {
+		t.Fatalf("before renewal: %+v", got)
+	}
+
+	// A later scan reads the renewed certificate
+	storeResults(
+		scanResult("c", t0.Add(2*time.Hour), nil, web(renewed)),
+		scanResult("b", t0.Add(time.Hour), nil, Host{IPAddress: "192.168.1.10"}),
+		scanResult("a", t0, nil, web(expiring)),
+	)
+	got = stored.latestTLSServices(monitored)
+	if len(got["192.168.1.10"]) != 1 || !got["192.168.1.10"][0].TLS.NotAfter.Equal(renewed) {
+		t.Errorf("after renewal: %+v", got)
+	}
+	if got := stored.latestTLSServices(nil); len(got) != 0 {
+		t.Errorf("no monitored hosts: %+v", got)
+	}
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime" // To pick the traceroute command for the OS
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tracerouteTimeout     = 30 * time.Second // Timeout for a single traceroute command
+	tracerouteMaxHops     = 15
+	tracerouteConcurrency = 8 // Parallel traceroutes when inferring parents
+)
+
+var tracerouteHopRegex = regexp.MustCompile(`^\s*(\d+)\s+(.*)$`)
+var ipv4Regex = regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})\b`)
+
+// parentDownLocked reports whether the parent of ip is currently not online, returning the
+// parent's IP. Parents are checked before their children, so the parent's status is from
+// the current cycle. The caller must hold m.mu.
+func (m *Monitor) parentDownLocked(ip string) (string, bool) {
+	state, ok := m.hosts[ip]
+	if !ok || state.ParentIP == "" {
+		return "", false
+	}
+	parent, ok := m.hosts[state.ParentIP]
+	if !ok {
+		return "", false
+	}
+	return parent.Host.IPAddress, parent.Status != StatusOnline
+}
+
+// dependencyDepthLocked returns how many monitored ancestors ip has. A cycle (which SetParent
+// prevents, but a hand-edited session file might contain) stops the walk.
+// The caller must hold m.mu.
+func (m *Monitor) dependencyDepthLocked(ip string) int {
+	depth := 0
+	seen := map[string]bool{ip: true}
+	for {
+		state, ok := m.hosts[ip]
+		if !ok || state.ParentIP == "" || seen[state.ParentIP] {
+			return depth
+		}
+		if _, parentMonitored := m.hosts[state.ParentIP]; !parentMonitored {
+			return depth
+		}
+		ip = state.ParentIP
+		seen[ip] = true
+		depth++
+	}
+}
+
+// sortByDependencyDepthLocked orders ips so that parents come before their children.
+// The caller must hold m.mu.
+func (m *Monitor) sortByDependencyDepthLocked(ips []string) {
+	depths := make(map[string]int, len(ips))
+	for _, ip := range ips {
+		depths[ip] = m.dependencyDepthLocked(ip)
+	}
+	sort.SliceStable(ips, func(i, j int) bool { return depths[ips[i]] < depths[ips[j]] })
+}
+
+// setParentLocked validates and records a parent relationship. An empty parentIP clears it.
+// The caller must hold m.mu.
+func (m *Monitor) setParentLocked(ipAddress, parentIP string) error {
+	state, ok := m.hosts[ipAddress]
+	if !ok {
+		return fmt.Errorf("host %s is not monitored", ipAddress)
+	}
+	if parentIP == "" {
+		state.ParentIP = ""
+		return nil
+	}
+	if parentIP == ipAddress {
+		return fmt.Errorf("host %s cannot be its own parent", ipAddress)
+	}
+	if _, ok := m.hosts[parentIP]; !ok {
+		return fmt.Errorf("parent %s is not monitored", parentIP)
+	}
+	// Walk up from the proposed parent; reaching ipAddress would create a cycle
+	for ancestor := parentIP; ancestor != ""; {
+		if ancestor == ipAddress {
+			return fmt.Errorf("making %s the parent of %s would create a dependency cycle", parentIP, ipAddress)
+		}
+		next, ok := m.hosts[ancestor]
+		if !ok {
+			break
+		}
+		ancestor = next.ParentIP
+	}
+	state.ParentIP = parentIP
+	return nil
+}
+
+// SetParent declares that ipAddress depends on parentIP (for example a server behind a
+// switch). While the parent is down, the child is recorded as unreachable and its alerts are
+// suppressed. An empty parentIP removes the relationship.
+func (m *Monitor) SetParent(ipAddress string, parentIP string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.setParentLocked(ipAddress, parentIP); err != nil {
+		return err
+	}
+	m.log.Info(fmt.Sprintf("Parent of monitored host %s set to %q.", ipAddress, parentIP))
+	m.hostsChangedLocked()
+	return nil
+}
+
+// InferParents runs a traceroute to every monitored host and makes the last monitored hop
+// before each host its parent. Only routed hops are visible to traceroute, so layer-2 devices
+// such as switches still have to be declared with SetParent. Hosts whose path contains no
+// monitored hop keep their current parent. Returns the inferred child -> parent map.
+func (m *Monitor) InferParents(ctx context.Context) (map[string]string, error) {
+	m.mu.Lock()
+	targets := make([]string, 0, len(m.hosts))
+	for ip := range m.hosts {
+		targets = append(targets, ip)
+	}
+	m.mu.Unlock()
+
+	if len(targets) == 0 {
+		return map[string]string{}, nil
+	}
+	m.log.Info(fmt.Sprintf("Inferring monitor dependencies via traceroute for %d hosts.", len(targets)))
+
+	paths := make(map[string][]string, len(targets))
+	var pathsMutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, tracerouteConcurrency)
+	for _, ip := range targets {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(target string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			hops, err := traceroute(ctx, target)
+			if err != nil {
+				m.log.Warning(fmt.Sprintf("Traceroute to %s failed: %v", target, err))
+				return
+			}
+			pathsMutex.Lock()
+			paths[target] = hops
+			pathsMutex.Unlock()
+		}(ip)
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inferred := make(map[string]string)
+	for target, hops := range paths {
+		// Walk the path backwards from the hop before the target
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := hops[i]
+			if hop == target {
+				continue
+			}
+			if _, monitored := m.hosts[hop]; !monitored {
+				continue
+			}
+			if err := m.setParentLocked(target, hop); err != nil {
+				m.log.Warning(fmt.Sprintf("Not using inferred parent %s for %s: %v", hop, target, err))
+				break
+			}
+			inferred[target] = hop
+			break
+		}
+	}
+	m.log.Info(fmt.Sprintf("Inferred %d parent relationship(s): %v", len(inferred), inferred))
+	m.hostsChangedLocked()
+	return inferred, nil
+}
+
+// traceroute returns the responding hop addresses on the path to target, in order.
+// Hops that did not answer are omitted.
+func traceroute(ctx context.Context, target string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, tracerouteTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	maxHops := fmt.Sprint(tracerouteMaxHops)
+	switch runtime.GOOS {
+	case "linux", "darwin":
+		cmd = exec.CommandContext(ctx, "traceroute", "-n", "-q", "1", "-w", "1", "-m", maxHops, target)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "tracert", "-d", "-h", maxHops, "-w", "1000", target)
+	default:
+		return nil, fmt.Errorf("traceroute not supported on %s", runtime.GOOS)
+	}
+
+	output, err := cmd.Output()
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+	return parseTracerouteOutput(string(output)), nil
+}
+
+// parseTracerouteOutput extracts the hop addresses from traceroute (-n) or tracert (-d) output.
+// Both print one line per hop starting with the hop number; the first IPv4 address on the line
+// is the responding router.
+func parseTracerouteOutput(output string) []string {
+	var hops []string
+	lines := bufio.NewScanner(strings.NewReader(output))
+	for lines.Scan() {
+		m := tracerouteHopRegex.FindStringSubmatch(lines.Text())
+		if m == nil {
+			continue // Header or blank line
+		}
+		if ip := ipv4Regex.FindString(m[2]); ip != "" {
+			hops = append(hops, ip)
+		}
+	}
+	return hops
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"netview/events"
+	"netview/scanner"
+)
+
+// Add adds a host to the running monitoring session without restarting it. If monitoring is
+// not active, a new session is started with the current check settings.
+func (m *Monitor) Add(host scanner.Host) error {
+	if net.ParseIP(host.IPAddress) == nil {
+		return fmt.Errorf("invalid IP address: %q", host.IPAddress)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.hosts[host.IPAddress]; exists {
+		return fmt.Errorf("host %s is already monitored", host.IPAddress)
+	}
+
+	m.hosts[host.IPAddress] = newHostState(host)
+	m.sessionWanted = true
+	m.log.Info(fmt.Sprintf("Added %s to monitoring (%d hosts monitored).", host.IPAddress, len(m.hosts)))
+
+	if !m.active {
+		m.startLoopLocked() // The loop's initial check covers the new host
+	} else {
+		// Check the new host right away instead of waiting for the next tick
+		checkCtx, wg := m.loop, m.loopWG
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.checkHosts(checkCtx, []string{host.IPAddress})
+		}()
+	}
+
+	m.hostsChangedLocked()
+	return nil
+}
+
+// newHostState returns the initial state for a newly monitored host. It is assumed online
+// until the first check verifies it; its ports were just found by a scan.
+func newHostState(host scanner.Host) *HostState {
+	return &HostState{Host: host, IsOnline: true, Status: StatusOnline, LastPortScan: time.Now()}
+}
+
+// Remove stops monitoring a single host. The rest of the session keeps running; removing the
+// last host stops the monitoring loop.
+func (m *Monitor) Remove(ipAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.hosts[ipAddress]; !exists {
+		return fmt.Errorf("host %s is not monitored", ipAddress)
+	}
+	delete(m.hosts, ipAddress)
+	// Children of the removed host no longer have a monitored parent
+	for _, state := range m.hosts {
+		if state.ParentIP == ipAddress {
+			state.ParentIP = ""
+		}
+	}
+	m.log.Info(fmt.Sprintf("Removed %s from monitoring (%d hosts monitored).", ipAddress, len(m.hosts)))
+
+	if len(m.hosts) == 0 && m.active {
+		m.log.Debug("Last monitored host removed, stopping monitoring loop.")
+		m.stopLoopLocked() // An Add while it stops starts a new loop for its host
+	}
+
+	m.hostsChangedLocked()
+	return nil
+}
+
+// Update replaces the stored details of a monitored host (e.g. after a re-scan found new open
+// ports) while keeping its status and history.
+func (m *Monitor) Update(host scanner.Host) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, exists := m.hosts[host.IPAddress]
+	if !exists {
+		return fmt.Errorf("host %s is not monitored", host.IPAddress)
+	}
+	state.Host = host
+	m.log.Debug(fmt.Sprintf("Updated monitored host details for %s: %+v", host.IPAddress, host))
+
+	m.hostsChangedLocked()
+	return nil
+}
+
+// SetGroups assigns a monitored host to groups, which maintenance windows can target.
+func (m *Monitor) SetGroups(ipAddress string, groups []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.hosts[ipAddress]
+	if !ok {
+		return fmt.Errorf("host %s is not monitored", ipAddress)
+	}
+	cleaned := make([]string, 0, len(groups))
+	for _, g := range groups {
+		if g = strings.TrimSpace(g); g != "" {
+			cleaned = append(cleaned, g)
+		}
+	}
+	state.Groups = cleaned
+	m.hostsChangedLocked()
+	return nil
+}
+
+// Hosts returns the monitored hosts with their current state, sorted by IP address.
+func (m *Monitor) Hosts() []HostState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshotLocked()
+}
+
+// hostsChangedLocked persists the session and publishes monitoredHostsChanged after the
+// monitored-host set changed. The caller must hold m.mu.
+func (m *Monitor) hostsChangedLocked() {
+	m.saveSessionLocked()
+	m.events.Emit(events.MonitoredHostsChanged, m.snapshotLocked())
+}
+
+// snapshotLocked returns a copy of the monitored hosts sorted by IP address.
+// The caller must hold m.mu.
+func (m *Monitor) snapshotLocked() []HostState {
+	hosts := make([]HostState, 0, len(m.hosts))
+	for _, state := range m.hosts {
+		hostCopy := *state
+		hostCopy.History = append([]StatusChange(nil), state.History...)
+		hostCopy.Groups = append([]string(nil), state.Groups...)
+		hosts = append(hosts, hostCopy)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		a, errA := scanner.IPToUint32(hosts[i].Host.IPAddress)
+		b, errB := scanner.IPToUint32(hosts[j].Host.IPAddress)
+		if errA != nil || errB != nil {
+			return hosts[i].Host.IPAddress < hosts[j].Host.IPAddress
+		}
+		return a < b
+	})
+	return hosts
+}
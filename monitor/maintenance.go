@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"netview/schedule"
+	"netview/storage"
+)
+
+// MaintenanceWindow silences a set of monitored hosts, either once (Start..End) or on a
+// recurring cron schedule. During a window checks keep running and history is recorded,
+// but notifications and flap counters are suppressed.
+type MaintenanceWindow struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Enabled         bool      `json:"enabled"`
+	Hosts           []string  `json:"hosts,omitempty"`           // Monitored host IPs covered by the window
+	Groups          []string  `json:"groups,omitempty"`          // Host groups covered by the window
+	Start           time.Time `json:"start,omitempty"`           // One-off window start
+	End             time.Time `json:"end,omitempty"`             // One-off window end
+	Cron            string    `json:"cron,omitempty"`            // Recurring window start, e.g. "0 3 * * 0" (Sunday 03:00)
+	DurationMinutes int       `json:"durationMinutes,omitempty"` // Length of each recurring window
+}
+
+// MaintenanceWindowStatus is a window plus its current state, as returned to the frontend.
+type MaintenanceWindowStatus struct {
+	MaintenanceWindow
+	Active    bool      `json:"active"`
+	NextStart time.Time `json:"nextStart,omitempty"` // Zero if the window will not start again
+}
+
+const maxMaintenanceMinutes = 7 * 24 * 60 // Recurring windows are limited to a week
+
+// validate checks that a window is either a valid one-off or a valid recurring window.
+func (w MaintenanceWindow) validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("maintenance window name is required")
+	}
+	if len(w.Hosts) == 0 && len(w.Groups) == 0 {
+		return fmt.Errorf("maintenance window %q must cover at least one host or group", w.Name)
+	}
+	if w.Cron != "" {
+		if _, err := schedule.ParseCron(w.Cron); err != nil {
+			return err
+		}
+		if w.DurationMinutes <= 0 || w.DurationMinutes > maxMaintenanceMinutes {
+			return fmt.Errorf("maintenance window %q: duration must be between 1 and %d minutes", w.Name, maxMaintenanceMinutes)
+		}
+		return nil
+	}
+	if w.Start.IsZero() || w.End.IsZero() || !w.End.After(w.Start) {
+		return fmt.Errorf("maintenance window %q: a one-off window needs a start before its end", w.Name)
+	}
+	return nil
+}
+
+// isActive reports whether the window is open at t. cron is the window's parsed schedule,
+// nil for one-off windows.
+func (w MaintenanceWindow) isActive(t time.Time, cron *schedule.Cron) bool {
+	if !w.Enabled {
+		return false
+	}
+	if w.Cron == "" {
+		return !t.Before(w.Start) && t.Before(w.End)
+	}
+	if cron == nil {
+		return false
+	}
+	_, open := cron.LastStartWithin(t, time.Duration(w.DurationMinutes)*time.Minute)
+	return open
+}
+
+// nextStart returns when the window next opens after t. cron is as for isActive.
+func (w MaintenanceWindow) nextStart(t time.Time, cron *schedule.Cron) time.Time {
+	if !w.Enabled {
+		return time.Time{}
+	}
+	if w.Cron == "" {
+		if t.Before(w.Start) {
+			return w.Start
+		}
+		return time.Time{}
+	}
+	if cron == nil {
+		return time.Time{}
+	}
+	next, _ := cron.Next(t)
+	return next
+}
+
+// cronLocked returns the parsed schedule of a recurring window, parsing it on first use
+// rather than on every check cycle. It returns nil for one-off windows and invalid schedules.
+// The caller must hold m.mu.
+func (m *Monitor) cronLocked(w MaintenanceWindow) *schedule.Cron {
+	if w.Cron == "" {
+		return nil
+	}
+	if cron, ok := m.crons[w.Cron]; ok {
+		return cron
+	}
+	cron, err := schedule.ParseCron(w.Cron)
+	if err != nil {
+		m.log.Warning(fmt.Sprintf("Maintenance window %q has an invalid schedule: %v", w.Name, err))
+	}
+	if m.crons == nil {
+		m.crons = make(map[string]*schedule.Cron)
+	}
+	m.crons[w.Cron] = cron
+	return cron
+}
+
+// covers reports whether the window applies to the given monitored host.
+func (w MaintenanceWindow) covers(state *HostState) bool {
+	for _, ip := range w.Hosts {
+		if ip == state.Host.IPAddress {
+			return true
+		}
+	}
+	for _, g := range w.Groups {
+		for _, hg := range state.Groups {
+			if strings.EqualFold(g, hg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// activeWindowsLocked returns the windows open at t. It is evaluated once per check cycle
+// rather than per host. The caller must hold m.mu.
+func (m *Monitor) activeWindowsLocked(t time.Time) []MaintenanceWindow {
+	var active []MaintenanceWindow
+	for _, w := range m.windows {
+		if w.isActive(t, m.cronLocked(w)) {
+			active = append(active, w)
+		}
+	}
+	return active
+}
+
+// maintenanceWindowFor returns the name of the first open window covering the host, or "".
+func maintenanceWindowFor(state *HostState, active []MaintenanceWindow) string {
+	for _, w := range active {
+		if w.covers(state) {
+			return w.Name
+		}
+	}
+	return ""
+}
+
+// MaintenanceWindows returns all maintenance windows with their current state, sorted by name.
+func (m *Monitor) MaintenanceWindows() []MaintenanceWindowStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	result := make([]MaintenanceWindowStatus, 0, len(m.windows))
+	for _, w := range m.windows {
+		cron := m.cronLocked(w)
+		result = append(result, MaintenanceWindowStatus{MaintenanceWindow: w, Active: w.isActive(now, cron), NextStart: w.nextStart(now, cron)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// SaveMaintenanceWindow creates a window (empty ID) or replaces the window with the same ID.
+func (m *Monitor) SaveMaintenanceWindow(window MaintenanceWindow) (MaintenanceWindow, error) {
+	if err := window.validate(); err != nil {
+		return MaintenanceWindow{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if window.ID == "" {
+		window.ID = storage.NewID()
+		m.windows = append(m.windows, window)
+	} else {
+		found := false
+		for i := range m.windows {
+			if m.windows[i].ID == window.ID {
+				m.windows[i] = window
+				found = true
+				break
+			}
+		}
+		if !found {
+			return MaintenanceWindow{}, fmt.Errorf("maintenance window %s not found", window.ID)
+		}
+	}
+	m.crons = nil // Forget schedules no window uses any more
+	m.log.Info(fmt.Sprintf("Saved maintenance window %q (%s).", window.Name, window.ID))
+	m.saveSessionLocked()
+	return window, nil
+}
+
+// DeleteMaintenanceWindow removes a maintenance window.
+func (m *Monitor) DeleteMaintenanceWindow(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, w := range m.windows {
+		if w.ID == id {
+			m.windows = append(m.windows[:i], m.windows[i+1:]...)
+			m.crons = nil
+			m.log.Info(fmt.Sprintf("Deleted maintenance window %q (%s).", w.Name, id))
+			m.saveSessionLocked()
+			return nil
+		}
+	}
+	return fmt.Errorf("maintenance window %s not found", id)
+}
@@ -0,0 +1,395 @@
+// Package monitor periodically checks a set of hosts and tracks their status: online,
+// offline, or unreachable when a parent host they depend on is down. It supports maintenance
+// windows, periodic port re-scans and a persisted session that survives restarts. Status
+// changes are published through an events.Sink; alerting and other side effects are left to
+// the caller's Hooks.
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"netview/events"
+	"netview/scanner"
+	"netview/schedule"
+)
+
+// HostStatusUpdate matches the TypeScript interface for host status updates.
+type HostStatusUpdate struct {
+	IPAddress string `json:"ipAddress"`
+	IsOnline  bool   `json:"isOnline"`
+	Status    string `json:"status"` // online, offline or unreachable (parent host down)
+
+	MaintenanceWindow string `json:"maintenanceWindow,omitempty"` // Name of the open maintenance window, if any
+}
+
+// Monitored host statuses. A host whose parent is down is recorded as unreachable rather
+// than offline, and its alerts are suppressed.
+const (
+	StatusOnline      = "online"
+	StatusOffline     = "offline"
+	StatusUnreachable = "unreachable"
+)
+
+// HostState is the monitor's view of a single host: the details found by the scan plus the
+// last known status. It is persisted so monitoring can resume after a restart.
+type HostState struct {
+	Host      scanner.Host `json:"host"`      // Host details as found by scan (includes OpenPorts)
+	IsOnline  bool         `json:"isOnline"`  // Last known online status
+	Status    string       `json:"status"`    // Last known status: online, offline or unreachable
+	ParentIP  string       `json:"parentIp"`  // Monitored host this one depends on (e.g. its switch); empty if none
+	Groups    []string     `json:"groups"`    // Host groups, targetable by maintenance windows
+	FlapCount int          `json:"flapCount"` // Online/offline transitions outside maintenance windows
+
+	MaintenanceWindow string    `json:"maintenanceWindow"` // Name of the maintenance window currently covering the host, if any
+	LastChecked       time.Time `json:"lastChecked"`       // Time of the last completed check (zero if never checked)
+	LastChange        time.Time `json:"lastChange"`        // Time the status last changed (zero if it never changed)
+	LastPortScan      time.Time `json:"lastPortScan"`      // Time the host's service ports were last scanned
+
+	History []StatusChange `json:"history"` // Most recent status changes, oldest first
+}
+
+// StatusChange records a single status transition of a monitored host.
+type StatusChange struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsOnline  bool      `json:"isOnline"`
+	Status    string    `json:"status"`
+
+	MaintenanceWindow string `json:"maintenanceWindow,omitempty"` // Set if the change happened during maintenance
+}
+
+const checkInterval = 10 * time.Second        // Interval for checking host statuses
+const tcpPingTimeout = 200 * time.Millisecond // Timeout for individual TCP pings of a host's known open ports
+const maxHistoryPerHost = 50                  // Status changes kept per host
+
+// Hooks let the application react to what the monitor observes. Every hook is optional.
+// StatusAlert is called with the monitor locked, so no hook may call back into the Monitor.
+type Hooks struct {
+	// StatusAlert is called for the status changes worth an alert: genuine online <-> offline
+	// transitions outside maintenance windows. Changes to or from unreachable are explained by
+	// a parent and covered by the parent's own alert.
+	StatusAlert func(host scanner.Host, online bool, previousChange, now time.Time)
+	// PortsRechecked is called with each host whose service ports were re-scanned, and the
+	// ports that were checked.
+	PortsRechecked func(ctx context.Context, host scanner.Host, checked []int)
+	// Checked is called after every liveness check of a host with its result, the round-trip
+	// time of the answer (negative if none) and how long the check took.
+	Checked func(ip string, online bool, rtt, took time.Duration)
+	// CycleDone is called at the end of every check cycle.
+	CycleDone func(ctx context.Context)
+}
+
+// Options configure a Monitor.
+type Options struct {
+	Scanner  *scanner.Scanner // Liveness checks and port re-scans
+	Sessions SessionStore     // Persists the session; nil keeps it in memory only
+	Events   events.Sink
+	Log      events.Logger
+	Hooks    Hooks
+}
+
+// Monitor checks the monitored hosts while monitoring is active. Its methods are safe for
+// concurrent use.
+type Monitor struct {
+	mu     sync.Mutex // Protects everything below
+	ctx    context.Context
+	loop   context.Context    // Context of the current monitoring loop
+	cancel context.CancelFunc // Stops the current monitoring loop
+	loopWG *sync.WaitGroup    // Waits for the current loop's goroutines; each loop has its own
+	active bool               // Flag indicating if monitoring is active
+
+	hosts        map[string]*HostState // IP -> monitored host details and last known status
+	searchHidden bool                  // SearchHiddenHosts setting at the time monitoring started
+	hiddenPorts  []int                 // HiddenHostsPorts setting at the time monitoring started
+
+	windows        []MaintenanceWindow
+	crons          map[string]*schedule.Cron // Parsed schedules of the recurring windows, by expression
+	portRecheck    PortRecheckSettings
+	sessionWanted  bool // True between Start and an explicit Stop
+	startMinimised bool
+
+	scanner  *scanner.Scanner
+	sessions SessionStore
+	events   events.Sink
+	log      events.Logger
+	hooks    Hooks
+}
+
+// New returns an idle Monitor. Monitoring loops run under ctx.
+func New(ctx context.Context, opts Options) *Monitor {
+	return &Monitor{
+		ctx:         ctx,
+		hosts:       make(map[string]*HostState),
+		hiddenPorts: []int{},
+		scanner:     opts.Scanner,
+		sessions:    opts.Sessions,
+		events:      opts.Events,
+		log:         opts.Log,
+		hooks:       opts.Hooks,
+	}
+}
+
+// Start begins periodically checking the status of the given hosts, replacing any running
+// session. searchHidden and hiddenPorts are the scan settings used when a host does not answer
+// on its known open ports.
+func (m *Monitor) Start(hostsToMonitor []scanner.Host, searchHidden bool, hiddenPorts []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active {
+		m.log.Debug("Monitoring already active. Stopping existing monitor first.")
+		m.stopAllLoopsLocked()
+		m.log.Debug("Previous monitoring stopped.")
+	}
+
+	m.log.Debug(fmt.Sprintf("StartMonitoring called with %d hosts. SearchHidden: %t, HiddenPorts: %v", len(hostsToMonitor), searchHidden, hiddenPorts))
+	m.hosts = make(map[string]*HostState)
+	if len(hostsToMonitor) == 0 {
+		m.log.Info("StartMonitoring called with no hosts. Monitoring will not actively run.")
+		m.active = false
+		m.sessionWanted = false
+		m.hostsChangedLocked()
+		return nil
+	}
+
+	// Store details and initial status for monitored hosts
+	for _, h := range hostsToMonitor {
+		m.hosts[h.IPAddress] = newHostState(h)
+	}
+	m.searchHidden = searchHidden
+	m.hiddenPorts = hiddenPorts
+
+	m.startLoopLocked()
+	m.sessionWanted = true
+	m.hostsChangedLocked()
+	return nil
+}
+
+// startLoopLocked launches the monitoring goroutine for the hosts currently in m.hosts.
+// The caller must hold m.mu and must have stopped any previous loop.
+func (m *Monitor) startLoopLocked() {
+	m.loop, m.cancel = context.WithCancel(m.ctx)
+	m.active = true
+	loopCtx := m.loop
+	wg := new(sync.WaitGroup)
+	m.loopWG = wg
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			m.mu.Lock()
+			if m.loopWG == wg { // Not replaced by a newer loop
+				m.active = false
+			}
+			m.log.Debug("Monitoring goroutine fully finished.")
+			m.mu.Unlock()
+		}()
+
+		m.mu.Lock()
+		hostCount := len(m.hosts)
+		m.mu.Unlock()
+		m.log.Info(fmt.Sprintf("Monitoring goroutine started for %d hosts.", hostCount))
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		m.performChecks(loopCtx) // Initial check
+
+		for {
+			select {
+			case <-loopCtx.Done():
+				m.log.Info("Monitoring loop stopping due to context cancellation.")
+				return
+			case <-ticker.C:
+				m.performChecks(loopCtx)
+			}
+		}
+	}()
+}
+
+// stopLoopLocked cancels the monitoring goroutine and waits for it to exit. The caller must
+// hold m.mu; it is released while waiting because the goroutine needs it to finish its
+// current check cycle. The monitor is marked inactive before that, so an Add in the meantime
+// starts a new loop for its host instead of joining the one being stopped.
+func (m *Monitor) stopLoopLocked() {
+	if !m.active {
+		return
+	}
+	m.active = false
+	m.cancel()
+	wg := m.loopWG
+	m.mu.Unlock()
+	wg.Wait()
+	m.mu.Lock()
+}
+
+// stopAllLoopsLocked stops the monitoring loop, and any loop an Add started while the
+// previous one was stopping. The caller must hold m.mu.
+func (m *Monitor) stopAllLoopsLocked() {
+	for m.active {
+		m.stopLoopLocked()
+	}
+}
+
+// Stop cancels monitoring and clears the monitored hosts. An explicit stop also means the
+// session should not resume on the next launch.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active {
+		m.log.Debug("Monitoring is not active, nothing to stop.")
+		return
+	}
+	m.stopAllLoopsLocked()
+	m.hosts = make(map[string]*HostState)
+	m.sessionWanted = false
+	m.hostsChangedLocked()
+	m.log.Info("Monitoring successfully stopped and data cleared.")
+}
+
+// IsActive reports whether monitoring is running.
+func (m *Monitor) IsActive() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// performChecks runs one check cycle over all monitored hosts.
+func (m *Monitor) performChecks(ctx context.Context) {
+	m.mu.Lock()
+	ipsToCheck := make([]string, 0, len(m.hosts))
+	for ip := range m.hosts {
+		ipsToCheck = append(ipsToCheck, ip)
+	}
+	// Check parents before their children so child failures can be attributed to them
+	m.sortByDependencyDepthLocked(ipsToCheck)
+	m.mu.Unlock()
+
+	m.checkHosts(ctx, ipsToCheck)
+	m.recheckPorts(ctx)
+	if m.hooks.CycleDone != nil {
+		m.hooks.CycleDone(ctx)
+	}
+	m.log.Debug("Finished performing status checks cycle.")
+}
+
+// checkHosts checks the online status of the given monitored IPs, updating their state and
+// publishing hostStatusUpdate for every change.
+func (m *Monitor) checkHosts(ctx context.Context, ipsToCheck []string) {
+	m.mu.Lock()
+	// Snapshot of current settings for this check cycle
+	searchHidden := m.searchHidden
+	hiddenPorts := append([]int(nil), m.hiddenPorts...)
+	activeWindows := m.activeWindowsLocked(time.Now())
+	m.mu.Unlock()
+
+	if len(ipsToCheck) == 0 {
+		return
+	}
+	m.log.Debug(fmt.Sprintf("Performing status checks for %d IPs: %v", len(ipsToCheck), ipsToCheck))
+
+	for _, ip := range ipsToCheck {
+		select {
+		case <-ctx.Done():
+			m.log.Debug(fmt.Sprintf("Status check for %s cancelled.", ip))
+			return
+		default:
+		}
+
+		m.mu.Lock()
+		state, exists := m.hosts[ip]
+		if !exists { // Host may have been removed since the snapshot was taken
+			m.mu.Unlock()
+			continue
+		}
+		knownPorts := state.Host.OpenPorts
+		m.mu.Unlock() // Unlock before network ops
+
+		// Priority 1: the known open service ports of this host, where a refusal also counts
+		checkStart := time.Now()
+		isNowOnline := false
+		var rtt time.Duration
+		for _, port := range knownPorts {
+			if rtt, isNowOnline = m.scanner.TCPPing(ctx, ip, port, tcpPingTimeout); isNowOnline {
+				break
+			}
+		}
+		// Priority 2: the general liveness check with the monitoring session's settings
+		if !isNowOnline {
+			rtt, isNowOnline = m.scanner.IsHostAlive(ctx, ip, searchHidden, hiddenPorts)
+		}
+		took := time.Since(checkStart)
+
+		m.mu.Lock()
+		m.recordCheckLocked(ip, isNowOnline, activeWindows, time.Now())
+		m.mu.Unlock()
+		if m.hooks.Checked != nil {
+			m.hooks.Checked(ip, isNowOnline, rtt, took)
+		}
+	}
+}
+
+// recordCheckLocked applies the result of checking ip: the maintenance window in effect, the
+// new status and, if it changed, the history entry, event, alert and saved session.
+// The caller must hold m.mu.
+func (m *Monitor) recordCheckLocked(ip string, isNowOnline bool, activeWindows []MaintenanceWindow, now time.Time) {
+	state, stillMonitored := m.hosts[ip]
+	if !stillMonitored {
+		return
+	}
+
+	state.LastChecked = now
+	window := maintenanceWindowFor(state, activeWindows)
+	if window != state.MaintenanceWindow {
+		if window != "" {
+			m.log.Info(fmt.Sprintf("Host %s entered maintenance window %q; alerts suppressed.", ip, window))
+		} else {
+			m.log.Info(fmt.Sprintf("Host %s left maintenance window %q.", ip, state.MaintenanceWindow))
+		}
+		state.MaintenanceWindow = window
+	}
+
+	newStatus := StatusOnline
+	if !isNowOnline {
+		newStatus = StatusOffline
+		if parentIP, down := m.parentDownLocked(ip); down {
+			newStatus = StatusUnreachable
+			m.log.Debug(fmt.Sprintf("Host %s not responding but parent %s is down, marking unreachable.", ip, parentIP))
+		}
+	}
+
+	previousStatus := state.Status
+	if newStatus == previousStatus {
+		return
+	}
+	previousChange := state.LastChange
+	state.IsOnline = isNowOnline
+	state.Status = newStatus
+	state.LastChange = now
+	state.History = appendStatusChange(state.History, StatusChange{Timestamp: now, IsOnline: isNowOnline, Status: newStatus, MaintenanceWindow: window})
+	m.log.Info(fmt.Sprintf("Host %s status changed: was %s, now %s. Emitting event.", ip, previousStatus, newStatus))
+	m.events.Emit(events.HostStatusUpdate, HostStatusUpdate{IPAddress: ip, IsOnline: isNowOnline, Status: newStatus, MaintenanceWindow: window})
+	// Only genuine online <-> offline transitions alert; anything involving unreachable is
+	// explained by a parent and already covered by the parent's own alert. Maintenance
+	// windows silence both alerts and flap counting.
+	if previousStatus != StatusUnreachable && newStatus != StatusUnreachable && window == "" {
+		state.FlapCount++
+		if m.hooks.StatusAlert != nil {
+			m.hooks.StatusAlert(state.Host, isNowOnline, previousChange, now)
+		}
+	}
+	m.saveSessionLocked() // Persist the new last known state
+}
+
+// appendStatusChange appends a status change to a host's history, dropping the oldest
+// entries beyond maxHistoryPerHost.
+func appendStatusChange(history []StatusChange, change StatusChange) []StatusChange {
+	history = append(history, change)
+	if len(history) > maxHistoryPerHost {
+		history = history[len(history)-maxHistoryPerHost:]
+	}
+	return history
+}
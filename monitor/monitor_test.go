@@ -0,0 +1,609 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"netview/events"
+	"netview/scanner"
+	"netview/scanner/scannertest"
+)
+
+// memSessions is a SessionStore that keeps the last saved session in memory.
+type memSessions struct {
+	mu    sync.Mutex
+	saved *Session
+}
+
+func (s *memSessions) Load() (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved == nil {
+		return nil, nil
+	}
+	session := *s.saved
+	return &session, nil
+}
+
+func (s *memSessions) Save(session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = &session
+	return nil
+}
+
+// statusAlert is a call of the StatusAlert hook.
+type statusAlert struct {
+	ip     string
+	online bool
+}
+
+// testMonitor is a Monitor on a fake network, with everything it reports captured.
+type testMonitor struct {
+	*Monitor
+	network *scannertest.Network
+	rec     *scannertest.Recorder
+
+	mu        sync.Mutex
+	alerts    []statusAlert
+	checks    []checkResult
+	onChecked func(ip string) // Called from the Checked hook, outside tm.mu
+}
+
+// checkResult is a call of the Checked hook.
+type checkResult struct {
+	ip     string
+	online bool
+	rtt    time.Duration
+}
+
+func newTestMonitor(t *testing.T, network *scannertest.Network, sessions *memSessions) *testMonitor {
+	t.Helper()
+	rec := &scannertest.Recorder{}
+	s := scanner.New(rec, rec)
+	s.Prober = network
+	tm := &testMonitor{network: network, rec: rec}
+	tm.Monitor = New(context.Background(), Options{
+		Scanner:  s,
+		Sessions: sessions,
+		Events:   rec,
+		Log:      rec,
+		Hooks: Hooks{
+			StatusAlert: func(host scanner.Host, online bool, _, _ time.Time) {
+				tm.mu.Lock()
+				defer tm.mu.Unlock()
+				tm.alerts = append(tm.alerts, statusAlert{host.IPAddress, online})
+			},
+			Checked: func(ip string, online bool, rtt, _ time.Duration) {
+				tm.mu.Lock()
+				tm.checks = append(tm.checks, checkResult{ip, online, rtt})
+				onChecked := tm.onChecked
+				tm.mu.Unlock()
+				if onChecked != nil {
+					onChecked(ip)
+				}
+			},
+		},
+	})
+	t.Cleanup(tm.Stop)
+	return tm
+}
+
+// watch adds hosts to the monitor without starting the loop, so the test drives every check.
+func (tm *testMonitor) watch(hosts ...scanner.Host) {
+	tm.Monitor.mu.Lock()
+	defer tm.Monitor.mu.Unlock()
+	for _, h := range hosts {
+		tm.hosts[h.IPAddress] = newHostState(h)
+	}
+}
+
+// check runs one check cycle.
+func (tm *testMonitor) check() {
+	tm.performChecks(context.Background())
+}
+
+// state returns the current state of a monitored host.
+func (tm *testMonitor) state(t *testing.T, ip string) HostState {
+	t.Helper()
+	for _, h := range tm.Hosts() {
+		if h.Host.IPAddress == ip {
+			return h
+		}
+	}
+	t.Fatalf("host %s is not monitored", ip)
+	return HostState{}
+}
+
+// checked reports whether a check of ip completed.
+func (tm *testMonitor) checked(ip string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return slices.ContainsFunc(tm.checks, func(c checkResult) bool { return c.ip == ip })
+}
+
+// takeAlerts returns and clears the alerts raised so far.
+func (tm *testMonitor) takeAlerts() []statusAlert {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	alerts := tm.alerts
+	tm.alerts = nil
+	return alerts
+}
+
+// statusUpdates returns the hostStatusUpdate events published so far.
+func (tm *testMonitor) statusUpdates() []HostStatusUpdate {
+	var updates []HostStatusUpdate
+	for _, data := range tm.rec.Named(events.HostStatusUpdate) {
+		updates = append(updates, data.(HostStatusUpdate))
+	}
+	return updates
+}
+
+func TestStatusTransitions(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"})
+	tm := newTestMonitor(t, network, &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1"})
+
+	tm.check()
+	if updates := tm.statusUpdates(); len(updates) != 0 {
+		t.Fatalf("a host that stayed online published %+v", updates)
+	}
+	if s := tm.state(t, "10.0.0.1"); s.Status != StatusOnline || s.LastChecked.IsZero() {
+		t.Fatalf("state after first check = %+v", s)
+	}
+
+	network.SetDown("10.0.0.1", true)
+	tm.check()
+	network.SetDown("10.0.0.1", false)
+	tm.check()
+
+	wantUpdates := []HostStatusUpdate{
+		{IPAddress: "10.0.0.1", IsOnline: false, Status: StatusOffline},
+		{IPAddress: "10.0.0.1", IsOnline: true, Status: StatusOnline},
+	}
+	if got := tm.statusUpdates(); !slices.Equal(got, wantUpdates) {
+		t.Errorf("status updates = %+v, want %+v", got, wantUpdates)
+	}
+	wantAlerts := []statusAlert{{"10.0.0.1", false}, {"10.0.0.1", true}}
+	if got := tm.takeAlerts(); !slices.Equal(got, wantAlerts) {
+		t.Errorf("alerts = %+v, want %+v", got, wantAlerts)
+	}
+	tm.mu.Lock()
+	checks := tm.checks
+	tm.mu.Unlock()
+	if len(checks) != 3 || !checks[0].online || checks[0].rtt < 0 || checks[1].online || checks[1].rtt >= 0 || !checks[2].online {
+		t.Errorf("checks = %+v, want online, offline without a round trip, online", checks)
+	}
+	s := tm.state(t, "10.0.0.1")
+	if s.FlapCount != 2 || len(s.History) != 2 || s.History[0].Status != StatusOffline || s.History[1].Status != StatusOnline {
+		t.Errorf("state = %+v", s)
+	}
+}
+
+func TestHistoryIsBounded(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"})
+	tm := newTestMonitor(t, network, &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1"})
+
+	for i := 0; i < maxHistoryPerHost+10; i++ {
+		network.SetDown("10.0.0.1", i%2 == 0)
+		tm.check()
+	}
+	if got := len(tm.state(t, "10.0.0.1").History); got != maxHistoryPerHost {
+		t.Errorf("history has %d entries, want %d", got, maxHistoryPerHost)
+	}
+}
+
+func TestKnownOpenPortsKeepHostOnline(t *testing.T) {
+	// Ignores pings; only its open service port shows it is up
+	network := scannertest.New(
+		scannertest.Host{IP: "10.0.0.1", NoPing: true, Filtered: true, Ports: map[int]scannertest.Handler{443: nil}},
+		scannertest.Host{IP: "10.0.0.2", NoPing: true, Filtered: true, Ports: map[int]scannertest.Handler{443: nil}},
+	)
+	tm := newTestMonitor(t, network, &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1", OpenPorts: []int{443}}, scanner.Host{IPAddress: "10.0.0.2"})
+
+	tm.check()
+	if s := tm.state(t, "10.0.0.1"); s.Status != StatusOnline {
+		t.Errorf("host with a known open port is %s", s.Status)
+	}
+	if s := tm.state(t, "10.0.0.2"); s.Status != StatusOffline {
+		t.Errorf("host without known ports that ignores pings is %s", s.Status)
+	}
+}
+
+func TestHiddenHostSettingsAreUsed(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1", NoPing: true, Filtered: true, Ports: map[int]scannertest.Handler{3389: nil}})
+	tm := newTestMonitor(t, network, &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1"})
+
+	tm.check()
+	if s := tm.state(t, "10.0.0.1"); s.Status != StatusOffline {
+		t.Fatalf("host ignoring pings is %s without hidden host search", s.Status)
+	}
+	tm.Monitor.mu.Lock()
+	tm.searchHidden, tm.hiddenPorts = true, []int{3389}
+	tm.Monitor.mu.Unlock()
+	tm.check()
+	if s := tm.state(t, "10.0.0.1"); s.Status != StatusOnline {
+		t.Errorf("host answering on a hidden-host port is %s", s.Status)
+	}
+}
+
+func TestUnreachableBehindParent(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.2"}, scannertest.Host{IP: "10.0.0.20"})
+	tm := newTestMonitor(t, network, &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.20"}, scanner.Host{IPAddress: "10.0.0.2"})
+	if err := tm.SetParent("10.0.0.20", "10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The switch fails, taking the server behind it with it
+	network.SetDown("10.0.0.2", true)
+	network.SetDown("10.0.0.20", true)
+	tm.check()
+	if s := tm.state(t, "10.0.0.20"); s.Status != StatusUnreachable {
+		t.Errorf("child of a down parent is %s, want unreachable", s.Status)
+	}
+	if got, want := tm.takeAlerts(), []statusAlert{{"10.0.0.2", false}}; !slices.Equal(got, want) {
+		t.Errorf("alerts = %+v, want only the parent's %+v", got, want)
+	}
+
+	// The switch comes back but the server stays down: unreachable -> offline does not alert
+	network.SetDown("10.0.0.2", false)
+	tm.check()
+	if s := tm.state(t, "10.0.0.20"); s.Status != StatusOffline || s.FlapCount != 0 {
+		t.Errorf("child after parent recovered = %+v", s)
+	}
+	if got, want := tm.takeAlerts(), []statusAlert{{"10.0.0.2", true}}; !slices.Equal(got, want) {
+		t.Errorf("alerts = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetParentValidation(t *testing.T) {
+	tm := newTestMonitor(t, scannertest.New(), &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1"}, scanner.Host{IPAddress: "10.0.0.2"}, scanner.Host{IPAddress: "10.0.0.3"})
+
+	if err := tm.SetParent("10.0.0.2", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.SetParent("10.0.0.3", "10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ child, parent string }{
+		{"10.0.0.1", "10.0.0.3"}, // Cycle
+		{"10.0.0.1", "10.0.0.1"}, // Itself
+		{"10.0.0.1", "10.0.0.9"}, // Parent not monitored
+		{"10.0.0.9", "10.0.0.1"}, // Child not monitored
+	} {
+		if err := tm.SetParent(tc.child, tc.parent); err == nil {
+			t.Errorf("SetParent(%s, %s) succeeded", tc.child, tc.parent)
+		}
+	}
+
+	// Parents are checked before their children
+	ips := []string{"10.0.0.3", "10.0.0.2", "10.0.0.1"}
+	tm.Monitor.mu.Lock()
+	tm.sortByDependencyDepthLocked(ips)
+	tm.Monitor.mu.Unlock()
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !slices.Equal(ips, want) {
+		t.Errorf("check order = %v, want %v", ips, want)
+	}
+
+	// Removing a parent detaches its children
+	if err := tm.Remove("10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if s := tm.state(t, "10.0.0.3"); s.ParentIP != "" {
+		t.Errorf("parent of 10.0.0.3 = %q after its parent was removed", s.ParentIP)
+	}
+}
+
+func TestMaintenanceWindowSuppressesAlerts(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"}, scannertest.Host{IP: "10.0.0.2"})
+	tm := newTestMonitor(t, network, &memSessions{})
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1"}, scanner.Host{IPAddress: "10.0.0.2"})
+	if err := tm.SetGroups("10.0.0.1", []string{" lab ", ""}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	window, err := tm.SaveMaintenanceWindow(MaintenanceWindow{Name: "Patching", Enabled: true, Groups: []string{"LAB"}, Start: now.Add(-time.Minute), End: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window.ID == "" {
+		t.Error("saved window has no ID")
+	}
+
+	network.SetDown("10.0.0.1", true)
+	network.SetDown("10.0.0.2", true)
+	tm.check()
+
+	if got, want := tm.takeAlerts(), []statusAlert{{"10.0.0.2", false}}; !slices.Equal(got, want) {
+		t.Errorf("alerts = %+v, want only the host outside the window %+v", got, want)
+	}
+	s := tm.state(t, "10.0.0.1")
+	if s.Status != StatusOffline || s.FlapCount != 0 || s.MaintenanceWindow != "Patching" || s.History[0].MaintenanceWindow != "Patching" {
+		t.Errorf("host in maintenance = %+v", s)
+	}
+	if updates := tm.statusUpdates(); len(updates) != 2 {
+		t.Errorf("status updates = %+v, want one per host; maintenance still records changes", updates)
+	}
+
+	statuses := tm.MaintenanceWindows()
+	if len(statuses) != 1 || !statuses[0].Active {
+		t.Errorf("windows = %+v", statuses)
+	}
+	if err := tm.DeleteMaintenanceWindow(window.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.DeleteMaintenanceWindow(window.ID); err == nil {
+		t.Error("deleting a deleted window succeeded")
+	}
+}
+
+func TestMaintenanceWindowValidation(t *testing.T) {
+	tm := newTestMonitor(t, scannertest.New(), &memSessions{})
+	now := time.Now()
+	for _, w := range []MaintenanceWindow{
+		{Hosts: []string{"10.0.0.1"}, Start: now, End: now.Add(time.Hour)},                        // No name
+		{Name: "w", Start: now, End: now.Add(time.Hour)},                                          // Covers nothing
+		{Name: "w", Hosts: []string{"10.0.0.1"}, Start: now, End: now},                            // Empty
+		{Name: "w", Hosts: []string{"10.0.0.1"}, Cron: "not cron", DurationMinutes: 60},           // Bad schedule
+		{Name: "w", Hosts: []string{"10.0.0.1"}, Cron: "0 3 * * 0", DurationMinutes: 0},           // No duration
+		{Name: "w", Hosts: []string{"10.0.0.1"}, Cron: "0 3 * * 0", DurationMinutes: 8 * 24 * 60}, // Too long
+		{ID: "missing", Name: "w", Hosts: []string{"10.0.0.1"}, Start: now, End: now.Add(time.Hour)},
+	} {
+		if _, err := tm.SaveMaintenanceWindow(w); err == nil {
+			t.Errorf("SaveMaintenanceWindow(%+v) succeeded", w)
+		}
+	}
+	if _, err := tm.SaveMaintenanceWindow(MaintenanceWindow{Name: "Weekly", Hosts: []string{"10.0.0.1"}, Cron: "0 3 * * 0", DurationMinutes: 120}); err != nil {
+		t.Errorf("valid recurring window rejected: %v", err)
+	}
+}
+
+func TestAddAndRemoveHosts(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"}, scannertest.Host{IP: "10.0.0.10"})
+	tm := newTestMonitor(t, network, &memSessions{})
+
+	if err := tm.Add(scanner.Host{IPAddress: "not an ip"}); err == nil {
+		t.Error("adding an invalid address succeeded")
+	}
+	if err := tm.Add(scanner.Host{IPAddress: "10.0.0.10"}); err != nil {
+		t.Fatal(err)
+	}
+	if !tm.IsActive() {
+		t.Error("adding a host did not start monitoring")
+	}
+	if err := tm.Add(scanner.Host{IPAddress: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.Add(scanner.Host{IPAddress: "10.0.0.1"}); err == nil {
+		t.Error("adding a monitored host twice succeeded")
+	}
+
+	hosts := tm.Hosts()
+	if len(hosts) != 2 || hosts[0].Host.IPAddress != "10.0.0.1" || hosts[1].Host.IPAddress != "10.0.0.10" {
+		t.Errorf("hosts = %+v, want sorted by address", hosts)
+	}
+	if err := tm.Update(scanner.Host{IPAddress: "10.0.0.1", Hostname: "gw"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := tm.state(t, "10.0.0.1"); s.Host.Hostname != "gw" {
+		t.Errorf("updated host = %+v", s.Host)
+	}
+	if err := tm.Update(scanner.Host{IPAddress: "10.0.0.99"}); err == nil {
+		t.Error("updating an unmonitored host succeeded")
+	}
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.10"} {
+		if err := tm.Remove(ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tm.IsActive() {
+		t.Error("monitoring still active after the last host was removed")
+	}
+	if err := tm.Remove("10.0.0.1"); err == nil {
+		t.Error("removing an unmonitored host succeeded")
+	}
+	// Add, Add, Update, Remove, Remove
+	if got := len(tm.rec.Named(events.MonitoredHostsChanged)); got != 5 {
+		t.Errorf("%d monitoredHostsChanged events, want 5", got)
+	}
+}
+
+func TestAddWhileLoopStops(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"}, scannertest.Host{IP: "10.0.0.2"})
+	for _, stop := range []string{"remove", "stop"} {
+		tm := newTestMonitor(t, network, &memSessions{})
+		// The loop's first check of 10.0.0.1 blocks until released, so stopping the loop
+		// has to wait for it
+		entered, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		tm.onChecked = func(ip string) {
+			if ip == "10.0.0.1" {
+				once.Do(func() {
+					close(entered)
+					<-release
+				})
+			}
+		}
+		if err := tm.Add(scanner.Host{IPAddress: "10.0.0.1"}); err != nil {
+			t.Fatal(err)
+		}
+		<-entered
+
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			if stop == "remove" {
+				tm.Remove("10.0.0.1")
+			} else {
+				tm.Stop()
+			}
+		}()
+		time.Sleep(20 * time.Millisecond) // Remove or Stop is now waiting for the loop
+		if err := tm.Add(scanner.Host{IPAddress: "10.0.0.2"}); err != nil {
+			t.Fatal(err)
+		}
+		close(release)
+		<-stopped
+
+		hosts := tm.Hosts()
+		switch {
+		case stop == "remove" && (len(hosts) != 1 || !tm.IsActive()):
+			t.Errorf("after removing the last host during an Add: hosts %+v, active %t; want 10.0.0.2 monitored by a running loop", hosts, tm.IsActive())
+		case stop == "stop" && (len(hosts) != 0 || tm.IsActive()):
+			t.Errorf("after stopping during an Add: hosts %+v, active %t; want none and inactive", hosts, tm.IsActive())
+		}
+		if stop == "remove" {
+			deadline := time.Now().Add(5 * time.Second)
+			for !tm.checked("10.0.0.2") && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if !tm.checked("10.0.0.2") {
+				t.Error("the host added while the loop stopped was never checked")
+			}
+		}
+		tm.Stop()
+	}
+}
+
+func TestSessionSurvivesRestart(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"}, scannertest.Host{IP: "10.0.0.2"})
+	sessions := &memSessions{}
+
+	first := newTestMonitor(t, network, sessions)
+	if err := first.Start([]scanner.Host{{IPAddress: "10.0.0.1"}, {IPAddress: "10.0.0.2"}}, true, []int{22}); err != nil {
+		t.Fatal(err)
+	}
+	first.SetStartMinimised(true)
+	if err := first.SetPortRecheck(PortRecheckSettings{IntervalMinutes: 30}); err != nil {
+		t.Fatal(err)
+	}
+	first.Persist() // As on shutdown; the session stays active
+
+	// The process exits without stopping monitoring
+	first.Monitor.mu.Lock()
+	first.Monitor.sessions = nil
+	first.Monitor.mu.Unlock()
+	first.Stop()
+
+	second := newTestMonitor(t, network, sessions)
+	second.Resume()
+	if !second.IsActive() || !second.StartMinimised() || second.PortRecheck().IntervalMinutes != 30 {
+		t.Errorf("resumed monitor: active %t, start minimised %t, port recheck %+v", second.IsActive(), second.StartMinimised(), second.PortRecheck())
+	}
+	if hosts := second.Hosts(); len(hosts) != 2 {
+		t.Errorf("resumed hosts = %+v", hosts)
+	}
+	if got := len(second.rec.Named(events.MonitoringResumed)); got != 1 {
+		t.Errorf("%d monitoringResumed events, want 1", got)
+	}
+
+	// An explicit stop means the next launch does not resume
+	second.Stop()
+	if changes := second.rec.Named(events.MonitoredHostsChanged); len(changes) == 0 || len(changes[len(changes)-1].([]HostState)) != 0 {
+		t.Errorf("monitoredHostsChanged after Stop = %v, want an empty host list last", changes)
+	}
+	third := newTestMonitor(t, network, sessions)
+	third.Resume()
+	if third.IsActive() {
+		t.Error("a stopped session was resumed")
+	}
+	if !third.StartMinimised() {
+		t.Error("preferences were not restored from an inactive session")
+	}
+}
+
+func TestResumeLegacySession(t *testing.T) {
+	// Sessions saved before statuses existed only have IsOnline
+	sessions := &memSessions{saved: &Session{Version: SessionVersion, Active: true, Hosts: []HostState{
+		{Host: scanner.Host{IPAddress: "10.0.0.1"}, IsOnline: true},
+		{Host: scanner.Host{IPAddress: "10.0.0.2"}, IsOnline: false},
+	}}}
+	tm := newTestMonitor(t, scannertest.New(), sessions)
+	tm.Resume()
+
+	// The event carries the restored state, before the first check
+	resumed := tm.rec.Named(events.MonitoringResumed)
+	if len(resumed) != 1 {
+		t.Fatalf("%d monitoringResumed events, want 1", len(resumed))
+	}
+	statuses := map[string]string{}
+	for _, h := range resumed[0].([]HostState) {
+		statuses[h.Host.IPAddress] = h.Status
+	}
+	if statuses["10.0.0.1"] != StatusOnline || statuses["10.0.0.2"] != StatusOffline {
+		t.Errorf("restored statuses = %v", statuses)
+	}
+}
+
+func TestPortRecheck(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1", Ports: map[int]scannertest.Handler{22: nil, 443: nil}})
+	tm := newTestMonitor(t, network, &memSessions{})
+	var rechecked [][]int
+	tm.hooks.PortsRechecked = func(_ context.Context, host scanner.Host, checked []int) {
+		rechecked = append(rechecked, checked)
+	}
+
+	if err := tm.SetPortRecheck(PortRecheckSettings{IntervalMinutes: -1}); err == nil {
+		t.Error("negative interval accepted")
+	}
+	if err := tm.SetPortRecheck(PortRecheckSettings{IntervalMinutes: 5, Ports: []int{70000}}); err == nil {
+		t.Error("invalid port accepted")
+	}
+	if err := tm.SetPortRecheck(PortRecheckSettings{IntervalMinutes: 5, Ports: []int{22, 80}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tm.watch(scanner.Host{IPAddress: "10.0.0.1", OpenPorts: []int{8080}})
+	tm.check() // Just scanned, so not due yet
+	if len(rechecked) != 0 {
+		t.Fatalf("ports re-checked before the interval passed: %v", rechecked)
+	}
+
+	tm.Monitor.mu.Lock()
+	tm.hosts["10.0.0.1"].LastPortScan = time.Now().Add(-10 * time.Minute)
+	tm.Monitor.mu.Unlock()
+	tm.check()
+
+	// The configured ports plus the previously open ones, so closures are noticed
+	if want := [][]int{{22, 80, 8080}}; len(rechecked) != 1 || !slices.Equal(rechecked[0], want[0]) {
+		t.Errorf("re-checked %v, want %v", rechecked, want)
+	}
+	if s := tm.state(t, "10.0.0.1"); !slices.Equal(s.Host.OpenPorts, []int{22}) || time.Since(s.LastPortScan) > time.Minute {
+		t.Errorf("state after re-check = %+v", s)
+	}
+}
+
+func TestParseTracerouteOutput(t *testing.T) {
+	const linux = `traceroute to 10.0.5.20 (10.0.5.20), 15 hops max, 60 byte packets
+ 1  192.168.1.1  0.512 ms
+ 2  *
+ 3  10.0.5.1  1.204 ms
+ 4  10.0.5.20  1.377 ms
+`
+	const windows = `
+Tracing route to 10.0.5.20 over a maximum of 15 hops
+
+  1    <1 ms    <1 ms    <1 ms  192.168.1.1
+  2     *        *        *     Request timed out.
+  3     1 ms     1 ms     1 ms  10.0.5.1
+  4     1 ms     1 ms     1 ms  10.0.5.20
+
+Trace complete.
+`
+	want := []string{"192.168.1.1", "10.0.5.1", "10.0.5.20"}
+	for name, output := range map[string]string{"traceroute": linux, "tracert": windows} {
+		if got := parseTracerouteOutput(output); !slices.Equal(got, want) {
+			t.Errorf("%s: hops = %v, want %v", name, got, want)
+		}
+	}
+}
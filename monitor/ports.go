@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"netview/scanner"
+)
+
+// PortRecheckSettings controls the monitor's periodic re-scan of service ports, which catches
+// port drift on monitored hosts without running a full scan.
+type PortRecheckSettings struct {
+	IntervalMinutes int   `json:"intervalMinutes"` // How often each host's ports are re-scanned; 0 disables
+	Ports           []int `json:"ports"`           // Service ports to check; empty means the default scan ports
+}
+
+const maxPortRecheckMinutes = 7 * 24 * 60
+
+// recheckPorts re-scans the service ports of online monitored hosts that have not been
+// scanned for a full recheck interval. Each re-scan is reported to the PortsRechecked hook,
+// and the host's known open ports are updated so liveness checks use the current set.
+func (m *Monitor) recheckPorts(ctx context.Context) {
+	m.mu.Lock()
+	settings := m.portRecheck
+	interval := time.Duration(settings.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	var due []scanner.Host
+	for _, state := range m.hosts {
+		if state.Status == StatusOnline && now.Sub(state.LastPortScan) >= interval {
+			due = append(due, state.Host)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+	servicePorts := settings.Ports
+	if len(servicePorts) == 0 {
+		servicePorts = scanner.DefaultPorts
+	}
+	m.log.Debug(fmt.Sprintf("Re-scanning service ports of %d monitored hosts.", len(due)))
+
+	for _, host := range due {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// Also check the ports the host was last known to have open, so closures are noticed
+		// even for ports outside the configured list
+		checked := append(append([]int(nil), servicePorts...), host.OpenPorts...)
+		slices.Sort(checked)
+		checked = slices.Compact(checked)
+		host.OpenPorts = m.scanner.ScanPorts(ctx, host.IPAddress, checked)
+		if m.hooks.PortsRechecked != nil {
+			m.hooks.PortsRechecked(ctx, host, checked)
+		}
+
+		m.mu.Lock()
+		if state, ok := m.hosts[host.IPAddress]; ok {
+			state.Host.OpenPorts = host.OpenPorts
+			state.LastPortScan = time.Now()
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	m.saveSessionLocked()
+	m.mu.Unlock()
+}
+
+// SetPortRecheck configures the periodic port re-scan of monitored hosts.
+// An interval of 0 disables it.
+func (m *Monitor) SetPortRecheck(settings PortRecheckSettings) error {
+	if settings.IntervalMinutes < 0 || settings.IntervalMinutes > maxPortRecheckMinutes {
+		return fmt.Errorf("port recheck interval must be between 0 and %d minutes", maxPortRecheckMinutes)
+	}
+	for _, p := range settings.Ports {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port %d", p)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.portRecheck = settings
+	m.saveSessionLocked()
+	m.log.Info(fmt.Sprintf("Monitor port recheck set to every %d minutes on %v.", settings.IntervalMinutes, settings.Ports))
+	return nil
+}
+
+// PortRecheck returns the periodic port re-scan settings.
+func (m *Monitor) PortRecheck() PortRecheckSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.portRecheck
+}
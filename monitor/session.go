@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"netview/events"
+)
+
+// Session is the persisted form of the monitoring session. It holds the monitored-host set,
+// the check configuration and the last known state of every host so monitoring survives a restart.
+type Session struct {
+	Version        int         `json:"version"`
+	Active         bool        `json:"active"`         // Whether monitoring should resume on startup
+	StartMinimised bool        `json:"startMinimised"` // Start NetView minimised, purely as a monitor
+	SearchHidden   bool        `json:"searchHidden"`
+	HiddenPorts    []int       `json:"hiddenPorts"`
+	SavedAt        time.Time   `json:"savedAt"`
+	Hosts          []HostState `json:"hosts"`
+
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows"`
+	PortRecheck        PortRecheckSettings `json:"portRecheck"`
+}
+
+// SessionVersion is the current Session format version.
+const SessionVersion = 1
+
+// SessionStore persists the monitoring session.
+type SessionStore interface {
+	// Load returns the saved session, or (nil, nil) if none was saved.
+	Load() (*Session, error)
+	Save(session Session) error
+}
+
+// saveSessionLocked persists the current monitored-host set and its last known state.
+// The caller must hold m.mu.
+func (m *Monitor) saveSessionLocked() {
+	if m.sessions == nil {
+		return
+	}
+	session := Session{
+		Version:        SessionVersion,
+		Active:         m.sessionWanted && len(m.hosts) > 0,
+		StartMinimised: m.startMinimised,
+		SearchHidden:   m.searchHidden,
+		HiddenPorts:    m.hiddenPorts,
+		SavedAt:        time.Now(),
+		Hosts:          m.snapshotLocked(),
+
+		MaintenanceWindows: m.windows,
+		PortRecheck:        m.portRecheck,
+	}
+	if err := m.sessions.Save(session); err != nil {
+		m.log.Error(fmt.Sprintf("Error saving monitor session: %v", err))
+		return
+	}
+	m.log.Debug(fmt.Sprintf("Saved monitor session with %d hosts.", len(session.Hosts)))
+}
+
+// Resume restores the persisted session and restarts monitoring if it was active when it was
+// saved. Hosts keep their last known status.
+func (m *Monitor) Resume() {
+	if m.sessions == nil {
+		return
+	}
+	session, err := m.sessions.Load()
+	if err != nil {
+		m.log.Error(fmt.Sprintf("Could not restore monitor session: %v", err))
+		return
+	}
+	if session == nil {
+		m.log.Debug("No saved monitor session found.")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.startMinimised = session.StartMinimised
+	m.windows = session.MaintenanceWindows // Windows are configuration; restore them even if inactive
+	m.portRecheck = session.PortRecheck
+	if !session.Active || len(session.Hosts) == 0 {
+		m.log.Debug("Saved monitor session is inactive, not resuming.")
+		return
+	}
+
+	m.hosts = make(map[string]*HostState, len(session.Hosts))
+	for i := range session.Hosts {
+		state := session.Hosts[i]
+		if state.Status == "" { // Sessions saved before statuses existed only have IsOnline
+			state.Status = StatusOffline
+			if state.IsOnline {
+				state.Status = StatusOnline
+			}
+		}
+		m.hosts[state.Host.IPAddress] = &state
+	}
+	m.searchHidden = session.SearchHidden
+	m.hiddenPorts = session.HiddenPorts
+	if m.hiddenPorts == nil {
+		m.hiddenPorts = []int{}
+	}
+	m.sessionWanted = true
+
+	m.startLoopLocked()
+	m.log.Info(fmt.Sprintf("Resumed monitoring session saved at %s with %d hosts.", session.SavedAt.Format(time.RFC3339), len(m.hosts)))
+	m.events.Emit(events.MonitoringResumed, m.snapshotLocked())
+}
+
+// Persist saves the monitoring session, e.g. on shutdown so the last known state of every
+// host is available on the next launch.
+func (m *Monitor) Persist() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveSessionLocked()
+}
+
+// SetStartMinimised enables or disables starting NetView minimised, purely as a monitor,
+// when a monitoring session is being resumed.
+func (m *Monitor) SetStartMinimised(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.startMinimised = enabled
+	m.saveSessionLocked()
+	m.log.Debug(fmt.Sprintf("StartMinimised set to %t.", enabled))
+}
+
+// StartMinimised returns the StartMinimised preference.
+func (m *Monitor) StartMinimised() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.startMinimised
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"netview/monitor"
+)
+
+// MonitorSession is the on-disk form of the monitoring session.
+type MonitorSession = monitor.Session
+
+const monitorSessionFilename = "monitor_session.json"
+
+// fileSessionStore keeps the monitoring session in monitor_session.json in the app data
+// directory, so it can be read before the Wails runtime and the data store exist.
+type fileSessionStore struct{}
+
+// monitorSessionFilePath returns the full path of the persisted session file.
+func monitorSessionFilePath() (string, error) {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDataDir, monitorSessionFilename), nil
+}
+
+// errCorruptSession marks a session file that exists but cannot be decoded.
+var errCorruptSession = errors.New("corrupt monitor session")
+
+// Load reads the persisted monitoring session. It returns (nil, nil) if none was saved. A
+// corrupt session file is renamed to .bak, so it is kept for inspection but not read again.
+func (fileSessionStore) Load() (*MonitorSession, error) {
+	session, path, err := readMonitorSession()
+	if errors.Is(err, errCorruptSession) {
+		_ = os.Rename(path, path+".bak")
+	}
+	return session, err
+}
+
+// readMonitorSession reads the session file without changing anything on disk. It returns
+// (nil, path, nil) if none was saved.
+func readMonitorSession() (*MonitorSession, string, error) {
+	path, err := monitorSessionFilePath()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, path, nil
+		}
+		return nil, path, fmt.Errorf("reading monitor session file '%s': %w", path, err)
+	}
+	var session MonitorSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, path, fmt.Errorf("%w: unmarshalling '%s': %w", errCorruptSession, path, err)
+	}
+	return &session, path, nil
+}
+
+// Save writes the monitoring session atomically.
+func (fileSessionStore) Save(session MonitorSession) error {
+	path, err := monitorSessionFilePath()
+	if err != nil {
+		return fmt.Errorf("monitor session path unavailable: %w", err)
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling monitor session: %w", err)
+	}
+	return writeFileAtomic(path, data, 0640)
+}
+
+// shouldStartMinimised reports whether NetView should start minimised as a background monitor.
+// It is read in main before the Wails runtime exists, so it goes straight to the session file,
+// leaving a corrupt one for Load to deal with on startup.
+func shouldStartMinimised() bool {
+	session, _, err := readMonitorSession()
+	if err != nil || session == nil {
+		return false
+	}
+	return session.StartMinimised && session.Active && len(session.Hosts) > 0
+}
+
+// SetStartMinimised enables or disables starting NetView minimised, purely as a monitor,
+// when a monitoring session is being resumed.
+func (a *App) SetStartMinimised(enabled bool) error {
+	a.monitor.SetStartMinimised(enabled)
+	return nil
+}
+
+// GetStartMinimised returns the StartMinimised preference.
+func (a *App) GetStartMinimised() bool {
+	return a.monitor.StartMinimised()
+}
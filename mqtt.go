@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"netview/events"
+	"netview/monitor"
+	"netview/mqtt"
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const mqttSettingsKey = "mqtt"                // Key of the MQTT settings in the settings bucket
+const mqttEventBuffer = 256                   // Events buffered for the publisher before it starts losing them
+const mqttTestTimeout = 15 * time.Second      // Upper bound for the "test connection" binding
+const mqttNewDeviceTopic = "event/new_device" // Under the topic prefix
+const mqttScanSummaryTopic = "scan/summary"   // Under the topic prefix; retained
+
+// mqttEvents are the events the MQTT publisher forwards.
+var mqttEvents = []string{
+	events.HostStatusUpdate, events.MonitoredHostsChanged, events.MonitoringResumed,
+	events.NewDeviceDetected, events.ScanSaved,
+}
+
+// mqttBridge publishes monitored host presence and selected events to an MQTT broker.
+type mqttBridge struct {
+	app *App
+
+	mu           sync.Mutex
+	settings     mqtt.Config
+	publisher    *mqtt.Publisher      // Nil while MQTT is disabled
+	subscription *events.Subscription // Events feeding publisher
+}
+
+// openMQTTBridge loads the MQTT settings and starts publishing if enabled. Called on app
+// startup, once the monitor is ready.
+func openMQTTBridge(ctx context.Context, app *App) *mqttBridge {
+	b := &mqttBridge{app: app}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if app.store != nil {
+		if err := storage.GetJSON(app.store, settingsBucket, mqttSettingsKey, &b.settings); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			runtime.LogError(ctx, fmt.Sprintf("Error loading MQTT settings: %v", err))
+		}
+	}
+	if !b.settings.Enabled {
+		return b
+	}
+	if err := b.startLocked(ctx); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Could not start the MQTT publisher: %v", err))
+	}
+	return b
+}
+
+// startLocked starts publishing to the configured broker: the monitored hosts' presence from
+// now on, and the events in mqttEvents as they happen. The caller must hold b.mu and must
+// have stopped any previous publisher.
+func (b *mqttBridge) startLocked(ctx context.Context) error {
+	publisher, err := mqtt.NewPublisher(b.settings, func(format string, args ...any) {
+		runtime.LogInfo(ctx, fmt.Sprintf(format, args...))
+	})
+	if err != nil {
+		return err
+	}
+	// Subscribe before reading the hosts, so no status change falls in between
+	b.subscription = b.app.events.stream.Subscribe(events.Filter{Names: mqttEvents}, mqttEventBuffer)
+	b.publisher = publisher
+	publisher.SetHosts(mqttHosts(b.app.monitor.Hosts()))
+	go forwardMQTTEvents(ctx, b.app, publisher, b.subscription)
+	return nil
+}
+
+// stopLocked marks NetView offline on the broker and disconnects. The caller must hold b.mu.
+func (b *mqttBridge) stopLocked() {
+	if b.publisher == nil {
+		return
+	}
+	b.subscription.Close()
+	b.publisher.Close()
+	b.publisher, b.subscription = nil, nil
+}
+
+// stop stops publishing on app shutdown.
+func (b *mqttBridge) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopLocked()
+}
+
+// forwardMQTTEvents hands events to the publisher until the subscription is closed.
+func forwardMQTTEvents(ctx context.Context, app *App, publisher *mqtt.Publisher, sub *events.Subscription) {
+	var err error
+	for e := range sub.C {
+		switch e.Name {
+		case events.HostStatusUpdate:
+			update, _ := e.Data.(monitor.HostStatusUpdate)
+			if state := monitoredHostState(app, update.IPAddress); state != nil {
+				host := mqttHost(*state)
+				host.Online, host.Status = update.IsOnline, update.Status
+				publisher.UpdateHost(host)
+			}
+		case events.MonitoredHostsChanged, events.MonitoringResumed:
+			states, _ := e.Data.([]monitor.HostState)
+			publisher.SetHosts(mqttHosts(states))
+		case events.NewDeviceDetected:
+			err = publisher.Publish(mqttNewDeviceTopic, e.Data, false)
+		case events.ScanSaved:
+			err = publisher.Publish(mqttScanSummaryTopic, e.Data, true)
+		}
+		if err != nil {
+			runtime.LogError(ctx, fmt.Sprintf("MQTT: could not publish %s: %v", e.Name, err))
+			err = nil
+		}
+	}
+}
+
+// mqttHost returns the presence of a monitored host.
+func mqttHost(state MonitoredHostState) mqtt.Host {
+	return mqtt.Host{
+		MACAddress: state.Host.MACAddress,
+		IPAddress:  state.Host.IPAddress,
+		Hostname:   state.Host.Hostname,
+		Vendor:     state.Host.Vendor,
+		Online:     state.IsOnline,
+		Status:     state.Status,
+	}
+}
+
+func mqttHosts(states []MonitoredHostState) []mqtt.Host {
+	hosts := make([]mqtt.Host, 0, len(states))
+	for _, state := range states {
+		hosts = append(hosts, mqttHost(state))
+	}
+	return hosts
+}
+
+// GetMQTTSettings returns the MQTT publisher settings.
+func (a *App) GetMQTTSettings() mqtt.Config {
+	a.mqtt.mu.Lock()
+	defer a.mqtt.mu.Unlock()
+	return a.mqtt.settings
+}
+
+// SaveMQTTSettings validates, applies and persists the MQTT publisher settings, reconnecting
+// with the new settings if enabled.
+func (a *App) SaveMQTTSettings(config mqtt.Config) error {
+	if config.Enabled {
+		if err := mqtt.ValidateConfig(config); err != nil {
+			return err
+		}
+	}
+	if a.store == nil {
+		return fmt.Errorf("data store is not available")
+	}
+
+	a.mqtt.mu.Lock()
+	defer a.mqtt.mu.Unlock()
+
+	a.mqtt.stopLocked()
+	a.mqtt.settings = config
+	if err := storage.PutJSON(a.store, settingsBucket, mqttSettingsKey, a.mqtt.settings); err != nil {
+		return err
+	}
+	if config.Enabled {
+		if err := a.mqtt.startLocked(a.ctx); err != nil {
+			return err
+		}
+		runtime.LogInfo(a.ctx, fmt.Sprintf("MQTT publisher started for broker %s.", config.Broker))
+	}
+	return nil
+}
+
+// TestMQTTConnection connects to the broker with the given settings and disconnects again,
+// reporting whether the broker accepted the connection.
+func (a *App) TestMQTTConnection(config mqtt.Config) error {
+	ctx, cancel := context.WithTimeout(a.ctx, mqttTestTimeout)
+	defer cancel()
+	return mqtt.CheckConnection(ctx, config)
+}
@@ -0,0 +1,235 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is an in-process stand-in for an MQTT broker. It accepts connections, records
+// CONNECT packets and published messages, keeps retained messages (an empty retained payload
+// deletes one, as with a real broker), answers pings and publishes the will of connections
+// that end without a DISCONNECT.
+type testBroker struct {
+	listener   net.Listener
+	returnCode byte // CONNACK return code; non-zero refuses connections
+
+	mu        sync.Mutex
+	connects  []connectPacket
+	published []Message
+	retained  map[string]string
+	conns     []net.Conn
+}
+
+type connectPacket struct {
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	Will      *Message
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	return newTestBrokerAt(t, "127.0.0.1:0")
+}
+
+// newTestBrokerAt starts a broker listening on address.
+func newTestBrokerAt(t *testing.T, address string) *testBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{listener: listener, retained: make(map[string]string)}
+	t.Cleanup(func() {
+		listener.Close()
+		b.dropConnections()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) addr() string {
+	return b.listener.Addr().String()
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	first, body, err := readRawPacket(r)
+	if err != nil || first>>4 != packetConnect {
+		return
+	}
+	connect, err := parseConnect(body)
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	b.connects = append(b.connects, connect)
+	b.mu.Unlock()
+	conn.Write([]byte{packetConnAck << 4, 2, 0, b.returnCode})
+	if b.returnCode != 0 {
+		return
+	}
+
+	for {
+		first, body, err := readRawPacket(r)
+		if err != nil {
+			if connect.Will != nil {
+				b.publish(*connect.Will)
+			}
+			return
+		}
+		switch first >> 4 {
+		case packetPublish:
+			topicLength := int(binary.BigEndian.Uint16(body))
+			b.publish(Message{
+				Topic:   string(body[2 : 2+topicLength]),
+				Payload: body[2+topicLength:],
+				Retain:  first&0x01 != 0,
+			})
+		case packetPingReq:
+			conn.Write([]byte{packetPingResp << 4, 0})
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func (b *testBroker) publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, m)
+	if m.Retain {
+		if len(m.Payload) == 0 {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = string(m.Payload)
+		}
+	}
+}
+
+// dropConnections cuts every client connection without a DISCONNECT, as a network failure would.
+func (b *testBroker) dropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+}
+
+// forgetRetained drops all retained messages, as a broker restarted without persistence would.
+func (b *testBroker) forgetRetained() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.retained = make(map[string]string)
+}
+
+// retainedMessage returns the retained payload of topic.
+func (b *testBroker) retainedMessage(topic string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	payload, ok := b.retained[topic]
+	return payload, ok
+}
+
+// messages returns every message published to topic, retained or not.
+func (b *testBroker) messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var messages []Message
+	for _, m := range b.published {
+		if m.Topic == topic {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func (b *testBroker) connections() []connectPacket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]connectPacket(nil), b.connects...)
+}
+
+// waitRetained waits until topic's retained payload is want; an empty want waits until
+// there is none.
+func (b *testBroker) waitRetained(t *testing.T, topic, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, ok := b.retainedMessage(topic)
+		if got == want && ok == (want != "") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("retained %s = %q (present %v), want %q", topic, got, ok, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// parseConnect decodes the body of a CONNECT packet.
+func parseConnect(body []byte) (connectPacket, error) {
+	var c connectPacket
+	protocol, rest, err := readTestString(body)
+	if err != nil || protocol != "MQTT" || len(rest) < 4 || rest[0] != 4 {
+		return c, errors.New("not an MQTT 3.1.1 CONNECT")
+	}
+	flags := rest[1]
+	c.KeepAlive = time.Duration(binary.BigEndian.Uint16(rest[2:4])) * time.Second
+	rest = rest[4:]
+	if c.ClientID, rest, err = readTestString(rest); err != nil {
+		return c, err
+	}
+	if flags&0x04 != 0 {
+		c.Will = &Message{Retain: flags&0x20 != 0}
+		var payload string
+		if c.Will.Topic, rest, err = readTestString(rest); err != nil {
+			return c, err
+		}
+		if payload, rest, err = readTestString(rest); err != nil {
+			return c, err
+		}
+		c.Will.Payload = []byte(payload)
+	}
+	if flags&0x80 != 0 {
+		if c.Username, rest, err = readTestString(rest); err != nil {
+			return c, err
+		}
+	}
+	if flags&0x40 != 0 {
+		if c.Password, _, err = readTestString(rest); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+func readTestString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("truncated string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("truncated string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
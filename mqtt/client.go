@@ -0,0 +1,332 @@
+// Package mqtt publishes NetView's view of the network to an MQTT broker for home-automation
+// systems such as Home Assistant: the presence of monitored hosts, new-device events and
+// scan summaries. It implements the small part of MQTT 3.1.1 a publisher needs: connecting
+// with credentials and a last-will message, QoS 0 publishing and keep-alive pings.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Control packet types, in the high nibble of the first byte of each packet.
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPingReq    = 12
+	packetPingResp   = 13
+	packetDisconnect = 14
+)
+
+const maxRemainingLength = 268435455 // Largest length the four-byte encoding can express
+const writeTimeout = 10 * time.Second
+
+// connectReturnCodes explains the CONNACK return codes that refuse a connection.
+var connectReturnCodes = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Message is an application message. It is always sent with QoS 0.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool // The broker keeps the last retained message of a topic for new subscribers
+}
+
+// ClientOptions describe how to connect to a broker.
+type ClientOptions struct {
+	Broker    string      // host:port
+	TLS       *tls.Config // Nil connects without TLS
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // Interval between pings; zero uses 30 seconds
+	Will      *Message      // Published by the broker if the connection is lost without a DISCONNECT
+}
+
+// Client is a connection to a broker. Publish may be called from several goroutines at once.
+type Client struct {
+	conn      net.Conn
+	writeMu   sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error // Why the connection ended; set before done is closed
+}
+
+// Dial connects to the broker and waits for it to accept the connection. The client pings
+// the broker in the background and closes the connection if it stops answering.
+func Dial(ctx context.Context, opts ClientOptions) (*Client, error) {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TLS != nil {
+		config := opts.TLS.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(opts.Broker)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	// The handshake must complete within the context's deadline, or a keep-alive period
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(opts.KeepAlive)
+	}
+	conn.SetDeadline(deadline)
+	reader := bufio.NewReader(conn)
+	if _, err := conn.Write(encodeConnect(opts)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	kind, body, err := readPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("waiting for CONNACK: %w", err)
+	}
+	if kind != packetConnAck || len(body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("broker answered CONNECT with packet type %d", kind)
+	}
+	if code := body[1]; code != 0 {
+		conn.Close()
+		if reason, ok := connectReturnCodes[code]; ok {
+			return nil, fmt.Errorf("broker refused the connection: %s", reason)
+		}
+		return nil, fmt.Errorf("broker refused the connection (code %d)", code)
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{conn: conn, done: make(chan struct{})}
+	go c.readLoop(reader, opts.KeepAlive)
+	go c.pingLoop(opts.KeepAlive)
+	return c, nil
+}
+
+// Publish sends a message.
+func (c *Client) Publish(m Message) error {
+	packet, err := encodePublish(m)
+	if err != nil {
+		return err
+	}
+	return c.write(packet)
+}
+
+// Done is closed when the connection ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, once Done is closed.
+func (c *Client) Err() error {
+	<-c.done
+	return c.err
+}
+
+// Close sends DISCONNECT, so the broker discards the will, and closes the connection.
+func (c *Client) Close() error {
+	c.write([]byte{packetDisconnect << 4, 0})
+	c.shutdown(errors.New("connection closed"))
+	return nil
+}
+
+func (c *Client) write(packet []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(packet); err != nil {
+		c.shutdown(err)
+		return err
+	}
+	return nil
+}
+
+// shutdown closes the connection, recording err as the reason if it is the first.
+func (c *Client) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+// readLoop reads the broker's packets, ignoring all but the ping responses that keep the read
+// deadline from expiring.
+func (c *Client) readLoop(reader *bufio.Reader, keepAlive time.Duration) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		if _, _, err := readPacket(reader); err != nil {
+			c.shutdown(err)
+			return
+		}
+	}
+}
+
+// pingLoop sends PINGREQ often enough that the broker answers within the read deadline.
+func (c *Client) pingLoop(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if c.write([]byte{packetPingReq << 4, 0}) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// encodeConnect builds a CONNECT packet for a clean session.
+func encodeConnect(opts ClientOptions) []byte {
+	var flags byte = 0x02 // Clean session
+	var payload []byte
+	payload = appendString(payload, opts.ClientID)
+	if opts.Will != nil {
+		flags |= 0x04
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+	keepAlive := min(int(opts.KeepAlive/time.Second), 0xFFFF)
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive))
+	body = append(body, payload...)
+	return appendPacket(packetConnect<<4, body)
+}
+
+// encodePublish builds a QoS 0 PUBLISH packet.
+func encodePublish(m Message) ([]byte, error) {
+	if err := ValidateTopic(m.Topic); err != nil {
+		return nil, err
+	}
+	header := byte(packetPublish << 4)
+	if m.Retain {
+		header |= 0x01
+	}
+	body := appendString(nil, m.Topic)
+	body = append(body, m.Payload...)
+	if len(body) > maxRemainingLength {
+		return nil, fmt.Errorf("message for %s is too large", m.Topic)
+	}
+	return appendPacket(header, body), nil
+}
+
+// ValidateTopic checks that topic can be published to: not empty, no wildcards, no NUL
+// characters and within the length limit.
+func ValidateTopic(topic string) error {
+	switch {
+	case topic == "":
+		return errors.New("topic is empty")
+	case len(topic) > 0xFFFF:
+		return errors.New("topic is too long")
+	}
+	for _, r := range topic {
+		if r == '+' || r == '#' || r == 0 {
+			return fmt.Errorf("topic %q contains %q", topic, r)
+		}
+	}
+	return nil
+}
+
+// appendPacket appends the remaining length and body to the fixed header byte.
+func appendPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// appendString appends s with its two-byte length prefix.
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// readPacket reads one control packet and returns its type and body.
+func readPacket(r *bufio.Reader) (kind byte, body []byte, err error) {
+	first, body, err := readRawPacket(r)
+	return first >> 4, body, err
+}
+
+// readRawPacket reads one control packet and returns its first byte and body.
+func readRawPacket(r *bufio.Reader) (first byte, body []byte, err error) {
+	first, err = r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7F) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body = make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return first, body, nil
+}
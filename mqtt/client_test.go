@@ -0,0 +1,101 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialSendsCredentialsAndWill(t *testing.T) {
+	broker := newTestBroker(t)
+	will := &Message{Topic: "netview/status", Payload: []byte("offline"), Retain: true}
+	client, err := Dial(context.Background(), ClientOptions{
+		Broker:    broker.addr(),
+		ClientID:  "netview-test",
+		Username:  "ha",
+		Password:  "secret",
+		KeepAlive: 20 * time.Second,
+		Will:      will,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Publish(Message{Topic: "netview/status", Payload: []byte("online"), Retain: true}); err != nil {
+		t.Fatal(err)
+	}
+	broker.waitRetained(t, "netview/status", "online")
+
+	connects := broker.connections()
+	if len(connects) != 1 {
+		t.Fatalf("%d connections, want 1", len(connects))
+	}
+	c := connects[0]
+	if c.ClientID != "netview-test" || c.Username != "ha" || c.Password != "secret" || c.KeepAlive != 20*time.Second {
+		t.Errorf("CONNECT = %+v", c)
+	}
+	if c.Will == nil || c.Will.Topic != will.Topic || string(c.Will.Payload) != "offline" || !c.Will.Retain {
+		t.Errorf("will = %+v, want %+v", c.Will, will)
+	}
+
+	// A clean disconnect discards the will
+	client.Close()
+	<-client.Done()
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := broker.retainedMessage("netview/status"); got != "online" {
+		t.Errorf("status after DISCONNECT = %q, want online", got)
+	}
+	if err := client.Publish(Message{Topic: "netview/x"}); err == nil {
+		t.Error("Publish after Close succeeded")
+	}
+}
+
+func TestDialRefused(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.returnCode = 4
+	_, err := Dial(context.Background(), ClientOptions{Broker: broker.addr(), ClientID: "netview-test"})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Errorf("Dial error = %v, want a bad credentials error", err)
+	}
+}
+
+func TestWillOnConnectionLoss(t *testing.T) {
+	broker := newTestBroker(t)
+	client, err := Dial(context.Background(), ClientOptions{
+		Broker:   broker.addr(),
+		ClientID: "netview-test",
+		Will:     &Message{Topic: "netview/status", Payload: []byte("offline"), Retain: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.dropConnections()
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not notice the lost connection")
+	}
+	broker.waitRetained(t, "netview/status", "offline")
+}
+
+func TestPacketEncoding(t *testing.T) {
+	// The remaining length uses 7 bits per byte, least significant first
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152} {
+		packet := appendPacket(packetPublish<<4, make([]byte, n))
+		first, body, err := readRawPacket(bufio.NewReader(bytes.NewReader(packet)))
+		if err != nil || first != packetPublish<<4 || len(body) != n {
+			t.Errorf("length %d: read back type %#x, %d bytes, err %v", n, first, len(body), err)
+		}
+	}
+	if got := appendPacket(0x30, make([]byte, 321))[1:3]; !bytes.Equal(got, []byte{0xC1, 0x02}) {
+		t.Errorf("remaining length of 321 encoded as % x, want c1 02", got)
+	}
+
+	for _, topic := range []string{"", "netview/+/state", "netview/#"} {
+		if _, err := encodePublish(Message{Topic: topic}); err == nil {
+			t.Errorf("topic %q accepted", topic)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default topic prefixes.
+const (
+	DefaultTopicPrefix     = "netview"
+	DefaultDiscoveryPrefix = "homeassistant" // Home Assistant's default discovery prefix
+)
+
+// Payloads of the availability topic and of host state topics.
+const (
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+)
+
+const (
+	defaultPort         = "1883"
+	defaultTLSPort      = "8883"
+	connectTimeout      = 15 * time.Second
+	minReconnectDelay   = time.Second
+	maxReconnectDelay   = time.Minute
+	maxQueuedMessages   = 100 // Event messages kept while the broker is unreachable
+	maxClientIDLength   = 23  // Longest client ID every MQTT 3.1.1 broker must accept
+	defaultClientPrefix = "netview-"
+)
+
+// Config describes the broker to publish to and what to publish.
+type Config struct {
+	Enabled         bool   `json:"enabled"`
+	Broker          string `json:"broker"` // host:port; the port defaults to 1883, or 8883 with TLS
+	TLS             bool   `json:"tls"`
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty"`
+	ClientID        string `json:"clientId,omitempty"`        // Empty uses "netview-" and the computer name
+	TopicPrefix     string `json:"topicPrefix,omitempty"`     // Empty means "netview"
+	Discovery       bool   `json:"discovery"`                 // Publish Home Assistant discovery payloads
+	DiscoveryPrefix string `json:"discoveryPrefix,omitempty"` // Empty means "homeassistant"
+}
+
+// Logger receives connection diagnostics.
+type Logger func(format string, args ...any)
+
+// ValidateConfig checks the broker address and topic prefixes.
+func ValidateConfig(config Config) error {
+	if strings.TrimSpace(config.Broker) == "" {
+		return errors.New("mqtt: broker address is required")
+	}
+	if _, err := config.brokerAddress(); err != nil {
+		return err
+	}
+	if err := validatePrefix(config.topicPrefix()); err != nil {
+		return fmt.Errorf("mqtt: invalid topic prefix: %w", err)
+	}
+	if config.Discovery {
+		if err := validatePrefix(config.discoveryPrefix()); err != nil {
+			return fmt.Errorf("mqtt: invalid discovery prefix: %w", err)
+		}
+	}
+	return nil
+}
+
+func validatePrefix(prefix string) error {
+	if strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("%q must not start or end with /", prefix)
+	}
+	return ValidateTopic(prefix)
+}
+
+// brokerAddress returns the broker's host:port, adding the default port if none is given.
+func (c Config) brokerAddress() (string, error) {
+	broker := strings.TrimSpace(c.Broker)
+	if _, _, err := net.SplitHostPort(broker); err == nil {
+		return broker, nil
+	}
+	port := defaultPort
+	if c.TLS {
+		port = defaultTLSPort
+	}
+	address := net.JoinHostPort(strings.Trim(broker, "[]"), port)
+	if _, _, err := net.SplitHostPort(address); err != nil || strings.Trim(broker, "[]") == "" {
+		return "", fmt.Errorf("mqtt: invalid broker address %q: expected host or host:port", c.Broker)
+	}
+	return address, nil
+}
+
+func (c Config) topicPrefix() string {
+	if p := strings.TrimSpace(c.TopicPrefix); p != "" {
+		return p
+	}
+	return DefaultTopicPrefix
+}
+
+func (c Config) discoveryPrefix() string {
+	if p := strings.TrimSpace(c.DiscoveryPrefix); p != "" {
+		return p
+	}
+	return DefaultDiscoveryPrefix
+}
+
+// clientOptions returns the connection options for the configuration, with the availability
+// topic's "offline" message as the will.
+func (c Config) clientOptions() (ClientOptions, error) {
+	broker, err := c.brokerAddress()
+	if err != nil {
+		return ClientOptions{}, err
+	}
+	opts := ClientOptions{
+		Broker:   broker,
+		ClientID: strings.TrimSpace(c.ClientID),
+		Username: c.Username,
+		Password: c.Password,
+		Will:     &Message{Topic: c.topicPrefix() + "/status", Payload: []byte(PayloadOffline), Retain: true},
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = defaultClientID()
+	}
+	if c.TLS {
+		opts.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return opts, nil
+}
+
+// defaultClientID returns "netview-" and the computer name, cut to the length every broker
+// accepts. Brokers disconnect a client when another connects with the same ID, so NetView
+// instances on different computers must not share one.
+func defaultClientID() string {
+	name, _ := os.Hostname()
+	var b strings.Builder
+	b.WriteString(defaultClientPrefix)
+	for _, r := range strings.ToLower(name) {
+		if b.Len() == maxClientIDLength {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// CheckConnection connects to the configured broker and disconnects again, to test the settings.
+func CheckConnection(ctx context.Context, config Config) error {
+	if err := ValidateConfig(config); err != nil {
+		return err
+	}
+	opts, err := config.clientOptions()
+	if err != nil {
+		return err
+	}
+	opts.Will = nil // Testing must not mark a running publisher offline
+	client, err := Dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+// Host is the presence of a monitored host.
+type Host struct {
+	MACAddress string
+	IPAddress  string
+	Hostname   string
+	Vendor     string
+	Online     bool
+	Status     string // online, offline or unreachable (a host it depends on is down)
+}
+
+// hostAttributes is the JSON published to a host's attributes topic. The keys are those Home
+// Assistant shows for router-based device trackers.
+type hostAttributes struct {
+	IPAddress  string `json:"ip"`
+	MACAddress string `json:"mac"`
+	Hostname   string `json:"host_name,omitempty"`
+	Vendor     string `json:"vendor,omitempty"`
+	Status     string `json:"status"`
+}
+
+// discoveryConfig is a Home Assistant MQTT discovery payload for a device tracker.
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	ObjectID            string          `json:"object_id"`
+	StateTopic          string          `json:"state_topic"`
+	PayloadHome         string          `json:"payload_home"`
+	PayloadNotHome      string          `json:"payload_not_home"`
+	SourceType          string          `json:"source_type"`
+	JSONAttributesTopic string          `json:"json_attributes_topic"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	Device              discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string    `json:"identifiers"`
+	Connections  [][2]string `json:"connections"`
+	Name         string      `json:"name"`
+	Manufacturer string      `json:"manufacturer,omitempty"`
+}
+
+// Publisher keeps a broker up to date with the presence of monitored hosts and forwards
+// events to it. Host state is published as retained messages under
+// <prefix>/host/<mac>/state ("online" or "offline") and <prefix>/host/<mac>/attributes
+// (JSON), where <mac> is the lower-case MAC address without separators. <prefix>/status is
+// "online" while the publisher is connected; the broker sets it to "offline" if the
+// connection is lost. With discovery enabled, every host also appears in Home Assistant as a
+// device tracker.
+//
+// The publisher reconnects with backoff whenever the connection is lost and then
+// republishes every host's state. Its methods never block on the network.
+type Publisher struct {
+	config Config
+	opts   ClientOptions
+	logf   Logger
+
+	mu        sync.Mutex
+	hosts     map[string]Host   // Tracked hosts by topic MAC
+	dirty     map[string]uint64 // Hosts whose messages must be (re)published, with a change counter
+	changes   uint64
+	announced map[string]string // Discovery payload last sent for each host on this connection
+	queue     []Message         // Event messages not yet sent
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewPublisher validates the configuration and starts publishing in the background. logf
+// may be nil.
+func NewPublisher(config Config, logf Logger) (*Publisher, error) {
+	if err := ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	opts, err := config.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	p := &Publisher{
+		config: config,
+		opts:   opts,
+		logf:   logf,
+		hosts:  make(map[string]Host),
+		dirty:  make(map[string]uint64),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+// SetHosts replaces the set of tracked hosts. The retained messages of hosts no longer in
+// the set are cleared, which also removes them from Home Assistant. Hosts without a MAC
+// address are skipped.
+func (p *Publisher) SetHosts(hosts []Host) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := make(map[string]Host, len(hosts))
+	for _, h := range hosts {
+		if mac, ok := topicMAC(h.MACAddress); ok {
+			current[mac] = h
+		}
+	}
+	for mac := range p.hosts {
+		if _, ok := current[mac]; !ok {
+			p.markDirtyLocked(mac)
+		}
+	}
+	for mac, h := range current {
+		if old, ok := p.hosts[mac]; !ok || old != h {
+			p.markDirtyLocked(mac)
+		}
+	}
+	p.hosts = current
+	p.signal()
+}
+
+// UpdateHost publishes the state of a host, adding it to the tracked hosts if needed. A
+// host without a MAC address is ignored.
+func (p *Publisher) UpdateHost(h Host) {
+	mac, ok := topicMAC(h.MACAddress)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if old, ok := p.hosts[mac]; ok && old == h {
+		return
+	}
+	p.hosts[mac] = h
+	p.markDirtyLocked(mac)
+	p.signal()
+}
+
+// Publish sends v as JSON to <prefix>/<subtopic>. Messages are queued while the broker is
+// unreachable; beyond a limit the oldest are dropped.
+func (p *Publisher) Publish(subtopic string, v any, retain bool) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m := Message{Topic: p.config.topicPrefix() + "/" + subtopic, Payload: payload, Retain: retain}
+	if err := ValidateTopic(m.Topic); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == maxQueuedMessages {
+		p.queue = p.queue[1:]
+		p.logf("MQTT: queue full, dropped the oldest message")
+	}
+	p.queue = append(p.queue, m)
+	p.signal()
+	return nil
+}
+
+// Close marks the publisher offline, disconnects and waits for the background goroutine to
+// end.
+func (p *Publisher) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+func (p *Publisher) markDirtyLocked(mac string) {
+	p.changes++
+	p.dirty[mac] = p.changes
+}
+
+// signal wakes the background goroutine without blocking.
+func (p *Publisher) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run connects, publishes until the connection is lost, and reconnects with backoff until
+// the publisher is closed.
+func (p *Publisher) run() {
+	defer close(p.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.stop
+		cancel()
+	}()
+
+	delay := minReconnectDelay
+	for {
+		dialCtx, cancelDial := context.WithTimeout(ctx, connectTimeout)
+		client, err := Dial(dialCtx, p.opts)
+		cancelDial()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.logf("MQTT: could not connect to %s: %v", p.opts.Broker, err)
+			select {
+			case <-time.After(delay):
+			case <-p.stop:
+				return
+			}
+			delay = min(delay*2, maxReconnectDelay)
+			continue
+		}
+		delay = minReconnectDelay
+		p.logf("MQTT: connected to %s", p.opts.Broker)
+
+		if err := p.serve(client); err != nil {
+			p.logf("MQTT: connection to %s lost: %v", p.opts.Broker, err)
+			continue
+		}
+		client.Publish(p.availability(PayloadOffline))
+		client.Close()
+		return
+	}
+}
+
+// serve publishes everything pending, then waits for more, until the connection is lost
+// (returning why) or the publisher is closed (returning nil).
+func (p *Publisher) serve(client *Client) error {
+	p.mu.Lock()
+	p.announced = make(map[string]string)
+	for mac := range p.hosts {
+		p.markDirtyLocked(mac) // The broker may have lost retained messages while disconnected
+	}
+	p.mu.Unlock()
+
+	if err := client.Publish(p.availability(PayloadOnline)); err != nil {
+		return err
+	}
+	for {
+		if err := p.flush(client); err != nil {
+			return err
+		}
+		select {
+		case <-p.wake:
+		case <-client.Done():
+			return client.Err()
+		case <-p.stop:
+			return nil
+		}
+	}
+}
+
+// flush publishes the messages of every changed host, then the queued messages.
+func (p *Publisher) flush(client *Client) error {
+	for {
+		mac, change, messages, ok := p.nextHost()
+		if !ok {
+			break
+		}
+		for _, m := range messages {
+			if err := client.Publish(m); err != nil {
+				return err
+			}
+		}
+		p.mu.Lock()
+		if p.dirty[mac] == change { // Not changed again while publishing
+			delete(p.dirty, mac)
+		}
+		p.mu.Unlock()
+	}
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return nil
+		}
+		m := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+		if err := client.Publish(m); err != nil {
+			p.mu.Lock()
+			if len(p.queue) < maxQueuedMessages {
+				p.queue = append([]Message{m}, p.queue...) // Retry after reconnecting
+			}
+			p.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// nextHost returns the messages that bring the broker up to date with one changed host: its
+// state, attributes and discovery payload, or empty retained messages that clear them if the
+// host is no longer tracked.
+func (p *Publisher) nextHost() (mac string, change uint64, messages []Message, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for mac, change = range p.dirty {
+		break
+	}
+	if change == 0 {
+		return "", 0, nil, false
+	}
+	base := p.config.topicPrefix() + "/host/" + mac
+	discoveryTopic := p.config.discoveryPrefix() + "/device_tracker/netview_" + mac + "/config"
+
+	h, tracked := p.hosts[mac]
+	if !tracked {
+		delete(p.announced, mac)
+		return mac, change, []Message{
+			{Topic: discoveryTopic, Retain: true},
+			{Topic: base + "/state", Retain: true},
+			{Topic: base + "/attributes", Retain: true},
+		}, true
+	}
+
+	if p.config.Discovery {
+		config, _ := json.Marshal(p.discoveryConfig(mac, h))
+		if p.announced[mac] != string(config) {
+			messages = append(messages, Message{Topic: discoveryTopic, Payload: config, Retain: true})
+			p.announced[mac] = string(config)
+		}
+	}
+	state := PayloadOffline
+	if h.Online {
+		state = PayloadOnline
+	}
+	attributes, _ := json.Marshal(hostAttributes{
+		IPAddress:  h.IPAddress,
+		MACAddress: strings.ToUpper(colonMAC(mac)),
+		Hostname:   h.Hostname,
+		Vendor:     h.Vendor,
+		Status:     h.Status,
+	})
+	messages = append(messages,
+		Message{Topic: base + "/attributes", Payload: attributes, Retain: true},
+		Message{Topic: base + "/state", Payload: []byte(state), Retain: true},
+	)
+	return mac, change, messages, true
+}
+
+// discoveryConfig returns the Home Assistant device tracker payload for a host.
+func (p *Publisher) discoveryConfig(mac string, h Host) discoveryConfig {
+	base := p.config.topicPrefix() + "/host/" + mac
+	name := h.Hostname
+	if name == "" {
+		name = h.IPAddress
+	}
+	id := "netview_" + mac
+	return discoveryConfig{
+		Name:                name,
+		UniqueID:            id,
+		ObjectID:            id,
+		StateTopic:          base + "/state",
+		PayloadHome:         PayloadOnline,
+		PayloadNotHome:      PayloadOffline,
+		SourceType:          "router",
+		JSONAttributesTopic: base + "/attributes",
+		AvailabilityTopic:   p.config.topicPrefix() + "/status",
+		Device: discoveryDevice{
+			Identifiers:  []string{id},
+			Connections:  [][2]string{{"mac", colonMAC(mac)}},
+			Name:         name,
+			Manufacturer: h.Vendor,
+		},
+	}
+}
+
+func (p *Publisher) availability(payload string) Message {
+	return Message{Topic: p.config.topicPrefix() + "/status", Payload: []byte(payload), Retain: true}
+}
+
+// topicMAC returns a MAC address as used in topics: lower-case hex digits without
+// separators. ok is false if mac is not a 48-bit MAC address.
+func topicMAC(mac string) (string, bool) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return "", false
+	}
+	return strings.ReplaceAll(hw.String(), ":", ""), true
+}
+
+// colonMAC formats a topic MAC as aa:bb:cc:dd:ee:ff.
+func colonMAC(mac string) string {
+	var b strings.Builder
+	for i := 0; i < len(mac); i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(mac[i : i+2])
+	}
+	return b.String()
+}
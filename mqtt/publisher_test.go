@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPublisherPresenceAndDiscovery(t *testing.T) {
+	broker := newTestBroker(t)
+	p, err := NewPublisher(Config{Broker: broker.addr(), ClientID: "netview-test", Discovery: true}, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	p.SetHosts([]Host{
+		{MACAddress: "AA-BB-CC-DD-EE-01", IPAddress: "192.168.1.20", Hostname: "phone", Vendor: "Acme", Online: true, Status: "online"},
+		{IPAddress: "10.9.0.1", Online: true, Status: "online"}, // No MAC address: not published
+	})
+	broker.waitRetained(t, "netview/status", "online")
+	broker.waitRetained(t, "netview/host/aabbccddee01/state", "online")
+
+	attributes, _ := broker.retainedMessage("netview/host/aabbccddee01/attributes")
+	var attrs map[string]string
+	if err := json.Unmarshal([]byte(attributes), &attrs); err != nil {
+		t.Fatalf("attributes %q: %v", attributes, err)
+	}
+	if attrs["ip"] != "192.168.1.20" || attrs["mac"] != "AA:BB:CC:DD:EE:01" || attrs["host_name"] != "phone" || attrs["status"] != "online" {
+		t.Errorf("attributes = %v", attrs)
+	}
+
+	discovery, _ := broker.retainedMessage("homeassistant/device_tracker/netview_aabbccddee01/config")
+	var config discoveryConfig
+	if err := json.Unmarshal([]byte(discovery), &config); err != nil {
+		t.Fatalf("discovery payload %q: %v", discovery, err)
+	}
+	if config.StateTopic != "netview/host/aabbccddee01/state" || config.PayloadHome != "online" || config.PayloadNotHome != "offline" ||
+		config.AvailabilityTopic != "netview/status" || config.UniqueID != "netview_aabbccddee01" || config.Name != "phone" ||
+		config.Device.Connections[0] != [2]string{"mac", "aa:bb:cc:dd:ee:01"} {
+		t.Errorf("discovery payload = %+v", config)
+	}
+
+	// A status change updates the state without announcing the host again
+	p.UpdateHost(Host{MACAddress: "aa:bb:cc:dd:ee:01", IPAddress: "192.168.1.20", Hostname: "phone", Vendor: "Acme", Status: "offline"})
+	broker.waitRetained(t, "netview/host/aabbccddee01/state", "offline")
+	if n := len(broker.messages("homeassistant/device_tracker/netview_aabbccddee01/config")); n != 1 {
+		t.Errorf("discovery payload sent %d times, want 1", n)
+	}
+
+	if err := p.Publish("event/new_device", map[string]string{"ipAddress": "192.168.1.77"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Publish("scan/summary", map[string]int{"hostsFound": 12}, true); err != nil {
+		t.Fatal(err)
+	}
+	broker.waitRetained(t, "netview/scan/summary", `{"hostsFound":12}`)
+	if events := broker.messages("netview/event/new_device"); len(events) != 1 || events[0].Retain || string(events[0].Payload) != `{"ipAddress":"192.168.1.77"}` {
+		t.Errorf("new device events = %+v", events)
+	}
+
+	// Hosts no longer tracked are cleared, removing them from Home Assistant
+	p.SetHosts(nil)
+	broker.waitRetained(t, "netview/host/aabbccddee01/state", "")
+	broker.waitRetained(t, "netview/host/aabbccddee01/attributes", "")
+	broker.waitRetained(t, "homeassistant/device_tracker/netview_aabbccddee01/config", "")
+
+	p.Close()
+	broker.waitRetained(t, "netview/status", "offline")
+}
+
+func TestPublisherRepublishesAfterReconnect(t *testing.T) {
+	broker := newTestBroker(t)
+	p, err := NewPublisher(Config{Broker: broker.addr(), ClientID: "netview-test", TopicPrefix: "lan"}, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	p.UpdateHost(Host{MACAddress: "aa:bb:cc:dd:ee:02", IPAddress: "192.168.1.21", Online: true, Status: "online"})
+	broker.waitRetained(t, "lan/host/aabbccddee02/state", "online")
+	if _, ok := broker.retainedMessage("homeassistant/device_tracker/netview_aabbccddee02/config"); ok {
+		t.Error("discovery payload published with discovery disabled")
+	}
+
+	// The broker restarts and loses its retained messages
+	broker.forgetRetained()
+	broker.dropConnections()
+	broker.waitRetained(t, "lan/status", "online")
+	broker.waitRetained(t, "lan/host/aabbccddee02/state", "online")
+	if n := len(broker.connections()); n != 2 {
+		t.Errorf("%d connections, want 2", n)
+	}
+}
+
+func TestPublisherQueuesUntilConnected(t *testing.T) {
+	// Reserve an address with nothing listening on it yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	p, err := NewPublisher(Config{Broker: address, ClientID: "netview-test"}, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	p.UpdateHost(Host{MACAddress: "aa:bb:cc:dd:ee:03", IPAddress: "192.168.1.22", Online: true, Status: "online"})
+	p.Publish("event/new_device", map[string]string{"ipAddress": "192.168.1.22"}, false)
+
+	broker := newTestBrokerAt(t, address)
+	broker.waitRetained(t, "netview/host/aabbccddee03/state", "online")
+	deadline := time.Now().Add(5 * time.Second)
+	for len(broker.messages("netview/event/new_device")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(broker.messages("netview/event/new_device")); n != 1 {
+		t.Errorf("queued event delivered %d times, want 1", n)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	for _, c := range []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{Broker: "broker.lan"}, true},
+		{Config{Broker: "10.0.0.5:1884"}, true},
+		{Config{Broker: "[fd00::5]", TLS: true}, true},
+		{Config{}, false},
+		{Config{Broker: "broker.lan", TopicPrefix: "netview/#"}, false},
+		{Config{Broker: "broker.lan", TopicPrefix: "netview/"}, false},
+		{Config{Broker: "broker.lan", Discovery: true, DiscoveryPrefix: "+"}, false},
+	} {
+		if err := ValidateConfig(c.config); (err == nil) != c.valid {
+			t.Errorf("ValidateConfig(%+v) = %v, want valid %v", c.config, err, c.valid)
+		}
+	}
+
+	opts, _ := Config{Broker: "[fd00::5]", TLS: true}.clientOptions()
+	if opts.Broker != "[fd00::5]:8883" || opts.TLS == nil {
+		t.Errorf("TLS broker without port: %+v", opts)
+	}
+	if id := defaultClientID(); len(id) > maxClientIDLength || id[:len(defaultClientPrefix)] != defaultClientPrefix {
+		t.Errorf("default client ID %q", id)
+	}
+}
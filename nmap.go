@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"netview/events"
+	"netview/nmapxml"
+	"netview/scanner"
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ImportNmapXML imports an nmap XML file (nmap -oX) into the scan result store, so it can be
+// opened, compared and exported like a NetView scan. Only hosts that were up and have an IPv4
+// address are imported, with their open TCP ports.
+func (a *App) ImportNmapXML(path string) (ScanResultInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScanResultInfo{}, err
+	}
+	defer f.Close()
+
+	run, err := nmapxml.Parse(f)
+	if err != nil {
+		return ScanResultInfo{}, err
+	}
+	result, skipped := scanResultFromNmap(run, storage.NewID(), time.Now())
+	if len(result.Hosts) == 0 {
+		return ScanResultInfo{}, fmt.Errorf("no IPv4 hosts that were up found in %s", path)
+	}
+	if err := a.scanResults.save(a.ctx, result); err != nil {
+		return ScanResultInfo{}, err
+	}
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Imported nmap scan %s from %s: %d hosts (%d skipped).", result.ID, path, len(result.Hosts), skipped))
+	a.events.Emit(events.ScanSaved, result.ScanResultInfo)
+	return result.ScanResultInfo, nil
+}
+
+// ImportNmapXMLWithDialog asks for an nmap XML file and imports it. If the dialog is
+// cancelled it returns a ScanResultInfo with an empty ID.
+func (a *App) ImportNmapXMLWithDialog() (ScanResultInfo, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Import nmap XML",
+		Filters: []runtime.FileFilter{{DisplayName: "nmap XML (*.xml)", Pattern: "*.xml"}},
+	})
+	if err != nil || path == "" {
+		return ScanResultInfo{}, err
+	}
+	return a.ImportNmapXML(path)
+}
+
+// scanResultFromNmap converts an nmap run into a scan result. The scan range covers the
+// lowest to highest imported address. It also returns the number of hosts skipped (down or
+// without an IPv4 address).
+func scanResultFromNmap(run *nmapxml.Run, id string, now time.Time) (ScanResult, int) {
+	hosts := []Host{}
+	skipped := 0
+	for _, nh := range run.Hosts {
+		ip := nh.Address(nmapxml.AddrIPv4).Addr
+		if ip == "" || (nh.Status.State != "" && nh.Status.State != "up") {
+			skipped++
+			continue
+		}
+		mac := nh.Address(nmapxml.AddrMAC)
+		host := Host{
+			IPAddress:  ip,
+			Hostname:   nh.Hostname(),
+			MACAddress: normalizeMAC(mac.Addr),
+			Vendor:     mac.Vendor,
+		}
+		if host.Vendor == "" {
+			host.Vendor = lookupVendor(host.MACAddress)
+		}
+		if nh.OS != nil && len(nh.OS.Matches) > 0 {
+			host.OS = nh.OS.Matches[0].Name
+		}
+		for _, p := range nh.Ports.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			host.OpenPorts = append(host.OpenPorts, p.PortID)
+			service := ServiceInfo{Port: p.PortID, Name: scanner.WellKnownPortNames[p.PortID]}
+			if p.Service != nil {
+				if p.Service.Name != "" {
+					service.Name = p.Service.Name
+				}
+				if p.Service.Tunnel == "ssl" && service.Name == "http" {
+					service.Name = "https"
+				}
+				service.Version = p.Service.Describe()
+			}
+			host.Services = append(host.Services, service)
+		}
+		sort.Ints(host.OpenPorts)
+		sort.Slice(host.Services, func(i, j int) bool { return host.Services[i].Port < host.Services[j].Port })
+		host.DeviceType = scanner.ClassifyDevice(host.IPAddress, host.Hostname, host.Vendor, host.OpenPorts)
+		hosts = append(hosts, host)
+	}
+
+	startedAt := now
+	if run.Start > 0 {
+		startedAt = time.Unix(run.Start, 0)
+	}
+	addresses := run.RunStats.Hosts.Total
+	if addresses < len(hosts) {
+		addresses = len(hosts)
+	}
+	result := newScanResult(id, nmapScanRange(hosts), startedAt, addresses, hosts)
+	if ports := nmapCheckedPorts(run); len(ports) > 0 {
+		result.CheckedPorts = formatPortSpec(ports)
+	} else {
+		result.PortsUnknown = true
+	}
+	if run.RunStats.Finished.Time > 0 {
+		result.CompletedAt = time.Unix(run.RunStats.Finished.Time, 0)
+		result.DurationMs = int64(run.RunStats.Finished.Elapsed * 1000)
+	}
+	return result, skipped
+}
+
+// nmapScanRange derives the scan range of an nmap run: the span of the imported addresses.
+func nmapScanRange(hosts []Host) ScanRange {
+	var params ScanRange
+	var low, high uint32
+	for i, h := range hosts {
+		n, err := scanner.IPToUint32(h.IPAddress)
+		if err != nil {
+			continue
+		}
+		if i == 0 || n < low {
+			low, params.StartIP = n, h.IPAddress
+		}
+		if i == 0 || n > high {
+			high, params.EndIP = n, h.IPAddress
+		}
+	}
+	return params
+}
+
+// nmapCheckedPorts returns the TCP ports an nmap run scanned, in ascending order, so that
+// diffs and exports only count ports nmap actually checked. A run without a usable TCP port
+// list is taken to have checked the ports its hosts report individually; nil means the run
+// does not show which ports were checked.
+func nmapCheckedPorts(run *nmapxml.Run) []int {
+	checked := make(map[int]bool)
+	for _, info := range run.ScanInfo {
+		if info.Protocol != "tcp" {
+			continue
+		}
+		ports, err := info.PortList()
+		if err != nil {
+			clear(checked)
+			break
+		}
+		for _, p := range ports {
+			checked[p] = true
+		}
+	}
+	if len(checked) == 0 {
+		for _, nh := range run.Hosts {
+			for _, p := range nh.Ports.Ports {
+				if p.Protocol == "tcp" {
+					checked[p.PortID] = true
+				}
+			}
+		}
+	}
+	if len(checked) == 0 {
+		return nil
+	}
+	return sortedInts(checked)
+}
+
+// renderNmapXML exports a scan in an nmap-compatible XML subset: one host element per host
+// with its addresses, hostname, open ports and services, and the run statistics.
+func renderNmapXML(result ScanResult) ([]byte, error) {
+	ports := result.checkedPorts()
+
+	run := &nmapxml.Run{
+		Scanner:          "netview",
+		Args:             fmt.Sprintf("netview scan %s-%s", result.Parameters.StartIP, result.Parameters.EndIP),
+		Start:            result.StartedAt.Unix(),
+		StartStr:         result.StartedAt.Format(time.ANSIC),
+		XMLOutputVersion: "1.05",
+		ScanInfo:         []nmapxml.ScanInfo{{Type: "connect", Protocol: "tcp", NumServices: len(ports), Services: formatPortSpec(ports)}},
+		RunStats: nmapxml.RunStats{
+			Finished: nmapxml.Finished{
+				Time:    result.CompletedAt.Unix(),
+				TimeStr: result.CompletedAt.Format(time.ANSIC),
+				Elapsed: float64(result.DurationMs) / 1000,
+				Exit:    "success",
+			},
+			Hosts: nmapxml.HostStats{
+				Up:    len(result.Hosts),
+				Down:  max(result.Summary.AddressesScanned-len(result.Hosts), 0),
+				Total: max(result.Summary.AddressesScanned, len(result.Hosts)),
+			},
+		},
+	}
+	run.RunStats.Finished.Summary = fmt.Sprintf("NetView done at %s; %d IP addresses (%d hosts up) scanned in %.2f seconds",
+		run.RunStats.Finished.TimeStr, run.RunStats.Hosts.Total, run.RunStats.Hosts.Up, run.RunStats.Finished.Elapsed)
+
+	for _, h := range result.Hosts {
+		nh := nmapxml.Host{
+			Status:    nmapxml.Status{State: "up", Reason: "netview-probe"},
+			Addresses: []nmapxml.Address{{Addr: h.IPAddress, AddrType: nmapxml.AddrIPv4}},
+		}
+		if h.MACAddress != "" {
+			nh.Addresses = append(nh.Addresses, nmapxml.Address{Addr: normalizeMAC(h.MACAddress), AddrType: nmapxml.AddrMAC, Vendor: hostVendor(h)})
+		}
+		if h.Hostname != "" {
+			nh.Hostnames = []nmapxml.Hostname{{Name: h.Hostname, Type: "PTR"}}
+		}
+		services := servicesByPort(h)
+		for _, p := range h.OpenPorts {
+			s := services[p]
+			service := &nmapxml.Service{Name: s.Name, Method: "table", Conf: 3}
+			if service.Name == "" {
+				service.Name = scanner.WellKnownPortNames[p]
+			}
+			if s.Version != "" {
+				service.Product, service.Method, service.Conf = s.Version, "probed", 10
+			}
+			if service.Name == "" {
+				service = nil // nmap omits the element when it has nothing to say
+			}
+			nh.Ports.Ports = append(nh.Ports.Ports, nmapxml.Port{
+				Protocol: "tcp",
+				PortID:   p,
+				State:    nmapxml.PortState{State: "open", Reason: "syn-ack"},
+				Service:  service,
+			})
+		}
+		if closed := len(ports) - len(h.OpenPorts); closed > 0 {
+			nh.Ports.ExtraPorts = []nmapxml.ExtraPorts{{State: "closed", Count: closed}}
+		}
+		if h.OS != "" {
+			nh.OS = &nmapxml.OS{Matches: []nmapxml.OSMatch{{Name: h.OS, Accuracy: 100}}}
+		}
+		run.Hosts = append(run.Hosts, nh)
+	}
+
+	var buf bytes.Buffer
+	if err := run.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
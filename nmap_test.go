@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"netview/nmapxml"
+)
+
+func TestScanResultFromNmap(t *testing.T) {
+	f, err := os.Open("nmapxml/testdata/scan.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	run, err := nmapxml.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, skipped := scanResultFromNmap(run, "n1", time.Time{})
+	if skipped != 2 || len(result.Hosts) != 2 { // One host down, one IPv6 only
+		t.Fatalf("%d hosts imported, %d skipped", len(result.Hosts), skipped)
+	}
+	if wantParams := (ScanRange{StartIP: "192.168.1.1", EndIP: "192.168.1.5"}); !reflect.DeepEqual(result.Parameters, wantParams) {
+		t.Errorf("parameters = %+v", result.Parameters)
+	}
+	if result.CheckedPorts != "22,80,443,8000-8010" || result.PortsUnknown || len(result.checkedPorts()) != 14 {
+		t.Errorf("checked ports = %q, unknown %v", result.CheckedPorts, result.PortsUnknown)
+	}
+	if !result.StartedAt.Equal(time.Unix(1772366400, 0)) || result.DurationMs != 23450 || result.Summary.AddressesScanned != 8 {
+		t.Errorf("scan info = %+v", result.ScanResultInfo)
+	}
+	gateway := result.Hosts[0]
+	wantServices := []ServiceInfo{{Port: 80, Name: "http", Version: "lighttpd 1.4.59"}, {Port: 443, Name: "https", Version: "lighttpd"}}
+	if gateway.Hostname != "gateway.lan" || gateway.Vendor != "Ubiquiti" || gateway.OS != "Linux 4.15 - 5.8" ||
+		!reflect.DeepEqual(gateway.OpenPorts, []int{80, 443}) || !reflect.DeepEqual(gateway.Services, wantServices) {
+		t.Errorf("gateway = %+v", gateway)
+	}
+	if nas := result.Hosts[1]; !reflect.DeepEqual(nas.OpenPorts, []int{22, 8010}) { // 8005 is filtered
+		t.Errorf("NAS open ports = %v", nas.OpenPorts)
+	}
+
+	// Exported again, the closed counts only cover the ports nmap checked
+	data, err := renderNmapXML(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := nmapxml.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := exported.ScanInfo[0]; info.NumServices != 14 {
+		t.Errorf("exported scaninfo = %+v", info)
+	}
+	for i, want := range []int{12, 12} {
+		if extra := exported.Hosts[i].Ports.ExtraPorts; len(extra) != 1 || extra[0].Count != want {
+			t.Errorf("host %d extraports = %+v, want %d closed", i, extra, want)
+		}
+	}
+}
+
+func TestNmapCheckedPorts(t *testing.T) {
+	hostWithPorts := func(ports ...int) nmapxml.Host {
+		var h nmapxml.Host
+		for _, p := range ports {
+			h.Ports.Ports = append(h.Ports.Ports, nmapxml.Port{Protocol: "tcp", PortID: p})
+		}
+		return h
+	}
+	for _, c := range []struct {
+		name string
+		run  nmapxml.Run
+		want []int
+	}{
+		{"port list", nmapxml.Run{ScanInfo: []nmapxml.ScanInfo{{Protocol: "tcp", Services: "443,22,80"}}}, []int{22, 80, 443}},
+		{"ranges", nmapxml.Run{ScanInfo: []nmapxml.ScanInfo{{Protocol: "tcp", Services: "20-23,80"}}}, []int{20, 21, 22, 23, 80}},
+		{"TCP scans merged, UDP ignored", nmapxml.Run{ScanInfo: []nmapxml.ScanInfo{
+			{Protocol: "tcp", Services: "22,80"}, {Protocol: "udp", Services: "53"}, {Protocol: "tcp", Services: "80,443"},
+		}}, []int{22, 80, 443}},
+		{"no port list", nmapxml.Run{Hosts: []nmapxml.Host{hostWithPorts(443, 22), hostWithPorts(22, 8080)}}, []int{22, 443, 8080}},
+		{"unreadable port list", nmapxml.Run{
+			ScanInfo: []nmapxml.ScanInfo{{Protocol: "tcp", Services: "22,http"}},
+			Hosts:    []nmapxml.Host{hostWithPorts(22)},
+		}, []int{22}},
+		{"no ports at all", nmapxml.Run{Hosts: []nmapxml.Host{hostWithPorts()}}, nil},
+	} {
+		if got := nmapCheckedPorts(&c.run); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: ports %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestScanResultFromNmapPortStorage(t *testing.T) {
+	host := nmapxml.Host{Status: nmapxml.Status{State: "up"}, Addresses: []nmapxml.Address{{Addr: "10.0.0.5", AddrType: nmapxml.AddrIPv4}}}
+
+	// nmap -p- lists every port; the scan index only carries the range
+	all, _ := scanResultFromNmap(&nmapxml.Run{ScanInfo: []nmapxml.ScanInfo{{Protocol: "tcp", Services: "1-65535"}}, Hosts: []nmapxml.Host{host}}, "n1", time.Time{})
+	info, err := json.Marshal(all.ScanResultInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.CheckedPorts != "1-65535" || all.Parameters.Ports != nil || len(info) > 1024 || len(all.checkedPorts()) != 65535 {
+		t.Errorf("-p- import: %d bytes of scan info, checked ports %q", len(info), all.CheckedPorts)
+	}
+
+	// Without any port information no port counts as checked, rather than the defaults
+	unknown, _ := scanResultFromNmap(&nmapxml.Run{Hosts: []nmapxml.Host{host}}, "n2", time.Time{})
+	if !unknown.PortsUnknown || unknown.checkedPorts() != nil {
+		t.Errorf("import without ports: unknown %v, checked %v", unknown.PortsUnknown, unknown.checkedPorts())
+	}
+	rescan := scanResult("s1", time.Time{}, []int{22}, Host{IPAddress: "10.0.0.5", OpenPorts: []int{22}})
+	if diff := diffScanResults(unknown, rescan); len(diff.Changed) == 1 && len(diff.Changed[0].OpenedPorts) > 0 {
+		t.Errorf("port 22 reported as opened against an import that never checked it: %+v", diff.Changed)
+	}
+}
@@ -0,0 +1,238 @@
+// Package nmapxml reads and writes the XML output format of nmap (-oX). Only the elements
+// NetView uses are modelled: hosts with their status, addresses, hostnames, ports, services
+// and OS matches, plus the run statistics. Everything else is ignored when reading.
+package nmapxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Run is the <nmaprun> root element.
+type Run struct {
+	XMLName          xml.Name   `xml:"nmaprun"`
+	Scanner          string     `xml:"scanner,attr"`
+	Args             string     `xml:"args,attr,omitempty"`
+	Start            int64      `xml:"start,attr,omitempty"` // Unix seconds
+	StartStr         string     `xml:"startstr,attr,omitempty"`
+	Version          string     `xml:"version,attr,omitempty"`
+	XMLOutputVersion string     `xml:"xmloutputversion,attr,omitempty"`
+	ScanInfo         []ScanInfo `xml:"scaninfo"`
+	Hosts            []Host     `xml:"host"`
+	RunStats         RunStats   `xml:"runstats"`
+}
+
+// ScanInfo describes one scan type of the run, e.g. a TCP SYN scan of a port list.
+type ScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"` // Port list, e.g. "22,80,443,8000-8100"
+}
+
+// Host is a <host> element.
+type Host struct {
+	StartTime int64      `xml:"starttime,attr,omitempty"`
+	EndTime   int64      `xml:"endtime,attr,omitempty"`
+	Status    Status     `xml:"status"`
+	Addresses []Address  `xml:"address"`
+	Hostnames []Hostname `xml:"hostnames>hostname"`
+	Ports     Ports      `xml:"ports"`
+	OS        *OS        `xml:"os,omitempty"`
+}
+
+// Status is a host's state: "up", "down", "unknown" or "skipped".
+type Status struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+// Address types.
+const (
+	AddrIPv4 = "ipv4"
+	AddrIPv6 = "ipv6"
+	AddrMAC  = "mac"
+)
+
+// Address is an <address> element. Vendor is only set on MAC addresses.
+type Address struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr,omitempty"`
+}
+
+// Hostname is a <hostname> element. Type is "user" (given on the command line) or "PTR".
+type Hostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// Ports is the <ports> element: the ports reported individually, plus counts of the rest.
+type Ports struct {
+	ExtraPorts []ExtraPorts `xml:"extraports"`
+	Ports      []Port       `xml:"port"`
+}
+
+// ExtraPorts summarises ports not listed individually, e.g. 998 closed ports.
+type ExtraPorts struct {
+	State string `xml:"state,attr"`
+	Count int    `xml:"count,attr"`
+}
+
+// Port is a <port> element.
+type Port struct {
+	Protocol string    `xml:"protocol,attr"`
+	PortID   int       `xml:"portid,attr"`
+	State    PortState `xml:"state"`
+	Service  *Service  `xml:"service,omitempty"`
+}
+
+// PortState is a port's state: "open", "closed", "filtered", "open|filtered", ...
+type PortState struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+// Service is the service nmap identified on a port.
+type Service struct {
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr,omitempty"`
+	Version   string `xml:"version,attr,omitempty"`
+	ExtraInfo string `xml:"extrainfo,attr,omitempty"`
+	Tunnel    string `xml:"tunnel,attr,omitempty"` // "ssl" for services wrapped in TLS
+	Method    string `xml:"method,attr"`           // "probed" or "table" (guessed from the port number)
+	Conf      int    `xml:"conf,attr"`             // Confidence, 0-10
+}
+
+// OS holds the OS detection results of a host.
+type OS struct {
+	Matches []OSMatch `xml:"osmatch"`
+}
+
+// OSMatch is one OS guess, most accurate first.
+type OSMatch struct {
+	Name     string `xml:"name,attr"`
+	Accuracy int    `xml:"accuracy,attr"`
+}
+
+// RunStats is the <runstats> element.
+type RunStats struct {
+	Finished Finished  `xml:"finished"`
+	Hosts    HostStats `xml:"hosts"`
+}
+
+// Finished records when and how the run ended.
+type Finished struct {
+	Time    int64   `xml:"time,attr"` // Unix seconds
+	TimeStr string  `xml:"timestr,attr,omitempty"`
+	Elapsed float64 `xml:"elapsed,attr"` // Seconds
+	Summary string  `xml:"summary,attr,omitempty"`
+	Exit    string  `xml:"exit,attr,omitempty"`
+}
+
+// HostStats counts the hosts of the run.
+type HostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// Address returns the host's first address of the given type, or a zero Address.
+func (h Host) Address(addrType string) Address {
+	for _, a := range h.Addresses {
+		if a.AddrType == addrType {
+			return a
+		}
+	}
+	return Address{}
+}
+
+// Hostname returns the host's preferred name: the one given by the user, else the first PTR name.
+func (h Host) Hostname() string {
+	for _, n := range h.Hostnames {
+		if n.Type == "user" {
+			return n.Name
+		}
+	}
+	if len(h.Hostnames) > 0 {
+		return h.Hostnames[0].Name
+	}
+	return ""
+}
+
+// PortList expands the port list of the scan, e.g. "22,80,8000-8002" to 22, 80, 8000, 8001
+// and 8002, in the order given.
+func (s ScanInfo) PortList() ([]int, error) {
+	var ports []int
+	if strings.TrimSpace(s.Services) == "" {
+		return ports, nil
+	}
+	for _, item := range strings.Split(s.Services, ",") {
+		low, high, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		first, err := strconv.Atoi(low)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(high)
+		}
+		if err != nil || first < 1 || last > 65535 || first > last {
+			return nil, fmt.Errorf("invalid port list entry %q", item)
+		}
+		for p := first; p <= last; p++ {
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+// Describe returns the product, version and extra info of a service as one string, e.g.
+// "OpenSSH 9.6p1 (Ubuntu Linux; protocol 2.0)".
+func (s Service) Describe() string {
+	parts := []string{}
+	for _, p := range []string{s.Product, s.Version} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	text := strings.Join(parts, " ")
+	if s.ExtraInfo != "" {
+		text = strings.TrimSpace(text + " (" + s.ExtraInfo + ")")
+	}
+	return text
+}
+
+// Parse reads an nmap XML document. The DOCTYPE and stylesheet instructions nmap writes are
+// accepted and ignored. The output of an interrupted scan, which ends without closing tags, is
+// returned with the hosts completed before the interruption.
+func Parse(r io.Reader) (*Run, error) {
+	var run Run
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		var syntaxErr *xml.SyntaxError
+		if !errors.As(err, &syntaxErr) || syntaxErr.Msg != "unexpected EOF" || len(run.Hosts) == 0 {
+			return nil, fmt.Errorf("parsing nmap XML: %w", err)
+		}
+	}
+	if run.Scanner == "" && len(run.Hosts) == 0 {
+		return nil, fmt.Errorf("parsing nmap XML: no nmaprun element")
+	}
+	return &run, nil
+}
+
+// Write writes the run as an nmap XML document.
+func (run *Run) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
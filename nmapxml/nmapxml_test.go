@@ -0,0 +1,158 @@
+package nmapxml
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/scan.xml is the output of nmap -sS -sV -O -oX, trimmed to a few hosts.
+func parseSample(t *testing.T) *Run {
+	t.Helper()
+	f, err := os.Open("testdata/scan.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	run, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+func TestParseSample(t *testing.T) {
+	run := parseSample(t)
+	if run.Scanner != "nmap" || run.Version != "7.94SVN" || run.Start != 1772366400 || run.XMLOutputVersion != "1.05" {
+		t.Errorf("run attributes = %q %q %d %q", run.Scanner, run.Version, run.Start, run.XMLOutputVersion)
+	}
+	wantInfo := []ScanInfo{{Type: "syn", Protocol: "tcp", NumServices: 14, Services: "22,80,443,8000-8010"}}
+	if !reflect.DeepEqual(run.ScanInfo, wantInfo) {
+		t.Errorf("scaninfo = %+v", run.ScanInfo)
+	}
+	wantStats := RunStats{
+		Finished: Finished{Time: 1772366423, TimeStr: "Sun Mar  1 12:00:23 2026", Elapsed: 23.45, Exit: "success",
+			Summary: "Nmap done at Sun Mar  1 12:00:23 2026; 8 IP addresses (3 hosts up) scanned in 23.45 seconds"},
+		Hosts: HostStats{Up: 3, Down: 5, Total: 8},
+	}
+	if !reflect.DeepEqual(run.RunStats, wantStats) {
+		t.Errorf("runstats = %+v", run.RunStats)
+	}
+	if len(run.Hosts) != 4 { // The hosthint is not a host
+		t.Fatalf("%d hosts", len(run.Hosts))
+	}
+
+	gateway := run.Hosts[0]
+	if gateway.Status.State != "up" || gateway.Address(AddrIPv4).Addr != "192.168.1.1" || gateway.Hostname() != "gateway.lan" {
+		t.Errorf("gateway = %+v", gateway)
+	}
+	if mac := gateway.Address(AddrMAC); mac != (Address{Addr: "AA:BB:CC:00:00:01", AddrType: AddrMAC, Vendor: "Ubiquiti"}) {
+		t.Errorf("gateway MAC = %+v", mac)
+	}
+	wantPorts := Ports{
+		ExtraPorts: []ExtraPorts{{State: "closed", Count: 12}},
+		Ports: []Port{
+			{Protocol: "tcp", PortID: 80, State: PortState{State: "open", Reason: "syn-ack", ReasonTTL: 64},
+				Service: &Service{Name: "http", Product: "lighttpd", Version: "1.4.59", Method: "probed", Conf: 10}},
+			{Protocol: "tcp", PortID: 443, State: PortState{State: "open", Reason: "syn-ack", ReasonTTL: 64},
+				Service: &Service{Name: "http", Product: "lighttpd", Tunnel: "ssl", Method: "probed", Conf: 10}},
+		},
+	}
+	if !reflect.DeepEqual(gateway.Ports, wantPorts) {
+		t.Errorf("gateway ports = %+v", gateway.Ports)
+	}
+	if gateway.OS == nil || !reflect.DeepEqual(gateway.OS.Matches, []OSMatch{{"Linux 4.15 - 5.8", 100}, {"Linux 5.0 - 5.4", 95}}) {
+		t.Errorf("gateway OS = %+v", gateway.OS)
+	}
+
+	nas := run.Hosts[1]
+	if nas.Hostname() != "nas" { // The name given by the user wins over the PTR name
+		t.Errorf("NAS hostname = %q", nas.Hostname())
+	}
+	if got := nas.Ports.Ports[0].Service.Describe(); got != "OpenSSH 9.6p1 (protocol 2.0)" {
+		t.Errorf("ssh service = %q", got)
+	}
+	if nas.Ports.Ports[2].Service != nil || nas.OS != nil {
+		t.Errorf("NAS has a service on 8010 or an OS: %+v", nas)
+	}
+	if run.Hosts[2].Status.State != "down" || run.Hosts[3].Address(AddrIPv4).Addr != "" || run.Hosts[3].Address(AddrIPv6).Addr != "fe80::1" {
+		t.Errorf("other hosts = %+v", run.Hosts[2:])
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	run := parseSample(t)
+	var buf bytes.Buffer
+	if err := run.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE nmaprun>\n<nmaprun scanner=\"nmap\"") {
+		t.Errorf("document starts with %q", buf.String()[:80])
+	}
+	reread, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reread, run) {
+		t.Errorf("written and parsed again:\n%+v\nwant\n%+v", reread, run)
+	}
+}
+
+func TestParseInterrupted(t *testing.T) {
+	data, err := os.ReadFile("testdata/scan.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nmap killed while writing the third host
+	cut := bytes.Index(data, []byte(`<address addr="192.168.1.6"`))
+	run, err := Parse(bytes.NewReader(data[:cut]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Hosts) < 2 || run.Hosts[1].Hostname() != "nas" {
+		t.Errorf("hosts of an interrupted run = %+v", run.Hosts)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"empty":           "",
+		"not XML":         "Nmap scan report for 192.168.1.1\n",
+		"other root":      `<?xml version="1.0"?><rss version="2.0"></rss>`,
+		"no hosts or run": `<nmaprun></nmaprun>`,
+		"truncated start": `<?xml version="1.0"?><nmaprun scanner="nmap">`,
+	} {
+		if _, err := Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestScanInfoPortList(t *testing.T) {
+	for _, c := range []struct {
+		services string
+		want     []int
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"22", []int{22}, false},
+		{"22,80,443", []int{22, 80, 443}, false},
+		{"1-3, 8080", []int{1, 2, 3, 8080}, false},
+		{"65535", []int{65535}, false},
+		{"0", nil, true},
+		{"65536", nil, true},
+		{"80-22", nil, true},
+		{"22,,80", nil, true},
+		{"T:22", nil, true},
+	} {
+		got, err := ScanInfo{Services: c.services}.PortList()
+		if (err != nil) != c.wantErr || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("PortList(%q) = %v, %v", c.services, got, err)
+		}
+	}
+	if ports, _ := (ScanInfo{Services: "1-65535"}).PortList(); len(ports) != 65535 {
+		t.Errorf("all ports expand to %d", len(ports))
+	}
+}
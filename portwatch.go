@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/alerting"
+	"netview/events"
+	"netview/scanner"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// PortChange is emitted as a portsChanged event when a host's open service ports differ
+// from the previous scan of the same host.
+type PortChange struct {
+	IPAddress string    `json:"ipAddress"`
+	Hostname  string    `json:"hostname,omitempty"`
+	Opened    []int     `json:"opened"`
+	Closed    []int     `json:"closed"`
+	Source    string    `json:"source"` // "scan" or "monitor"
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Sources of a port observation.
+const (
+	portSourceScan    = "scan"
+	portSourceMonitor = "monitor"
+)
+
+// portBaselineEntry is the last known port state of one host. Only ports that were actually
+// checked can be compared, so the checked set is kept alongside the open set; changing the
+// scan's port list therefore never reports spurious openings or closures.
+type portBaselineEntry struct {
+	OpenPorts    []int     `json:"openPorts"`
+	CheckedPorts []int     `json:"checkedPorts"`
+	LastScan     time.Time `json:"lastScan"`
+}
+
+const portBaselineFilename = "port_baseline.json"
+
+// portWatch reports hosts whose open ports change between scans or monitor re-scans.
+type portWatch struct {
+	events wailsEvents
+	alerts *alerter
+
+	mu       sync.Mutex
+	baseline map[string]*portBaselineEntry // IP -> last known port state
+	dirty    bool
+}
+
+// openPortWatch loads the per-host port state recorded by previous scans.
+func openPortWatch(ctx context.Context, ev wailsEvents, alerts *alerter) *portWatch {
+	w := &portWatch{events: ev, alerts: alerts, baseline: make(map[string]*portBaselineEntry)}
+	path, err := portBaselineFilePath()
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Port baseline path unavailable: %v", err))
+		return w
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			runtime.LogError(ctx, fmt.Sprintf("Error reading port baseline '%s': %v", path, err))
+		}
+		return w
+	}
+	if err := json.Unmarshal(data, &w.baseline); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error unmarshalling port baseline from '%s': %v. Starting fresh.", path, err))
+		w.baseline = make(map[string]*portBaselineEntry)
+		_ = os.Rename(path, path+".bak")
+		return w
+	}
+	runtime.LogDebug(ctx, fmt.Sprintf("Loaded port baseline for %d hosts.", len(w.baseline)))
+	return w
+}
+
+// portBaselineFilePath returns the full path of the port baseline file.
+func portBaselineFilePath() (string, error) {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDataDir, portBaselineFilename), nil
+}
+
+// flush saves the port baseline if it changed since the last save.
+func (w *portWatch) flush(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.dirty {
+		return
+	}
+	path, err := portBaselineFilePath()
+	if err != nil {
+		runtime.LogWarning(ctx, fmt.Sprintf("Port baseline path unavailable, skipping save: %v", err))
+		return
+	}
+	data, err := json.MarshalIndent(w.baseline, "", "  ")
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error marshalling port baseline: %v", err))
+		return
+	}
+	if err := writeFileAtomic(path, data, 0640); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error saving port baseline: %v", err))
+		return
+	}
+	w.dirty = false
+}
+
+// observe compares the ports found open on host against the previous observation and records
+// the new state. checkedPorts are the ports that were probed this time. Any difference is
+// emitted as a portsChanged event and raised as an alert. The first observation of a host only
+// records its baseline.
+func (w *portWatch) observe(ctx context.Context, host Host, checkedPorts []int, source string) *PortChange {
+	w.mu.Lock()
+	change := w.recordLocked(host, checkedPorts, source, time.Now())
+	w.mu.Unlock()
+
+	if change == nil {
+		return nil
+	}
+	runtime.LogInfo(ctx, fmt.Sprintf("Open ports changed on %s (%s): opened %v, closed %v", host.IPAddress, source, change.Opened, change.Closed))
+	w.events.Emit(events.PortsChanged, *change)
+	w.alerts.raise(portChangeAlert(host, *change))
+	return change
+}
+
+// recordLocked records the ports of host in the baseline and returns the change since the
+// previous observation, or nil if there is none. The caller must hold w.mu.
+func (w *portWatch) recordLocked(host Host, checkedPorts []int, source string, now time.Time) *PortChange {
+	open := intSet(host.OpenPorts)
+	checked := intSet(checkedPorts)
+	for p := range open {
+		checked[p] = true // An open port has evidently been checked
+	}
+
+	entry, known := w.baseline[host.IPAddress]
+	if !known {
+		w.baseline[host.IPAddress] = &portBaselineEntry{OpenPorts: sortedInts(open), CheckedPorts: sortedInts(checked), LastScan: now}
+		w.dirty = true
+		return nil
+	}
+
+	prevOpen := intSet(entry.OpenPorts)
+	prevChecked := intSet(entry.CheckedPorts)
+	change := PortChange{IPAddress: host.IPAddress, Hostname: host.Hostname, Opened: []int{}, Closed: []int{}, Source: source, Timestamp: now}
+	for p := range open {
+		if prevChecked[p] && !prevOpen[p] {
+			change.Opened = append(change.Opened, p)
+		}
+	}
+	for p := range prevOpen {
+		if checked[p] && !open[p] {
+			change.Closed = append(change.Closed, p)
+		}
+	}
+	sort.Ints(change.Opened)
+	sort.Ints(change.Closed)
+
+	// Ports not checked this time keep their previous state
+	for p := range prevOpen {
+		if !checked[p] {
+			open[p] = true
+		}
+	}
+	for p := range prevChecked {
+		checked[p] = true
+	}
+	entry.OpenPorts = sortedInts(open)
+	entry.CheckedPorts = sortedInts(checked)
+	entry.LastScan = now
+	w.dirty = true
+
+	if len(change.Opened) == 0 && len(change.Closed) == 0 {
+		return nil
+	}
+	return &change
+}
+
+// portChangeAlert builds the alert for a port change.
+func portChangeAlert(host Host, change PortChange) alerting.Event {
+	event := alerting.Event{
+		Type:        alerting.EventPortsChanged,
+		Timestamp:   change.Timestamp,
+		IPAddress:   host.IPAddress,
+		Hostname:    host.Hostname,
+		MACAddress:  host.MACAddress,
+		DeviceType:  host.DeviceType,
+		OpenedPorts: change.Opened,
+		ClosedPorts: change.Closed,
+	}
+	var parts []string
+	if len(change.Opened) > 0 {
+		parts = append(parts, "opened "+describePorts(change.Opened))
+	}
+	if len(change.Closed) > 0 {
+		parts = append(parts, "closed "+describePorts(change.Closed))
+	}
+	event.Message = fmt.Sprintf("%s: %s", event.Title(), strings.Join(parts, "; "))
+	return event
+}
+
+// describePorts formats ports with their well-known service names, e.g. "23/telnet, 8443".
+func describePorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+		if name, ok := scanner.WellKnownPortNames[p]; ok {
+			parts[i] += "/" + name
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// intSet returns the values as a set.
+func intSet(values []int) map[int]bool {
+	set := make(map[int]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// sortedInts returns the members of a set in ascending order.
+func sortedInts(set map[int]bool) []int {
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values
+}
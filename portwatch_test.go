@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordPorts(t *testing.T) {
+	w := &portWatch{baseline: make(map[string]*portBaselineEntry)}
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	observe := func(open, checked []int) *PortChange {
+		t.Helper()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.recordLocked(Host{IPAddress: "192.168.1.10", OpenPorts: open}, checked, portSourceScan, t0)
+	}
+
+	// The first observation only records the baseline
+	if change := observe([]int{22, 80}, []int{22, 80, 443}); change != nil {
+		t.Fatalf("first observation reported %+v", change)
+	}
+	if change := observe([]int{22, 80}, []int{22, 80, 443}); change != nil {
+		t.Errorf("unchanged ports reported %+v", change)
+	}
+
+	// 8080 was never checked before, so it cannot have opened; 80 was not checked now, so it cannot have closed
+	change := observe([]int{443, 8080}, []int{22, 443, 8080})
+	if change == nil || !reflect.DeepEqual(change.Opened, []int{443}) || !reflect.DeepEqual(change.Closed, []int{22}) ||
+		change.Source != portSourceScan || !change.Timestamp.Equal(t0) {
+		t.Fatalf("change = %+v", change)
+	}
+	w.mu.Lock()
+	entry := *w.baseline["192.168.1.10"]
+	w.mu.Unlock()
+	if !reflect.DeepEqual(entry.OpenPorts, []int{80, 443, 8080}) || !reflect.DeepEqual(entry.CheckedPorts, []int{22, 80, 443, 8080}) {
+		t.Errorf("baseline = %+v, want 80 kept open", entry)
+	}
+
+	// Now that 80 and 8080 are known, both closing is reported
+	if change := observe(nil, []int{80, 8080}); change == nil || len(change.Opened) != 0 || !reflect.DeepEqual(change.Closed, []int{80, 8080}) {
+		t.Errorf("change = %+v", change)
+	}
+}
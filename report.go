@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"netview/scanner"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+//go:embed templates/report.html
+var reportTemplateSource string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+	"date":     func(t time.Time) string { return t.Local().Format("2006-01-02") },
+	"seconds":  func(ms int64) string { return strconv.FormatFloat(float64(ms)/1000, 'f', 1, 64) },
+	"ports":    joinPortNumbers,
+	"join":     strings.Join,
+}).Parse(reportTemplateSource))
+
+// reportData is the model rendered by templates/report.html.
+type reportData struct {
+	GeneratedAt  time.Time
+	Scan         ScanResult
+	DeviceTypes  []reportCount
+	TopPorts     []reportCount
+	Hosts        []reportHost
+	Certificates []reportCertificate
+	FindingCount int
+	PortsChecked string    // As ranges; empty for a scan of the default ports
+	Diff         *ScanDiff // Nil without a baseline
+}
+
+// reportCount is a bar in one of the breakdown charts.
+type reportCount struct {
+	Label   string
+	Count   int
+	Percent int // Of the hosts found
+}
+
+// reportHost is a host with its services resolved for display.
+type reportHost struct {
+	Host
+	VendorName string
+	Services   []ServiceInfo // One per open port
+}
+
+// reportCertificate is a TLS service and what is wrong with it.
+type reportCertificate struct {
+	IPAddress string
+	Hostname  string
+	Port      int
+	Service   string
+	TLS       *TLSInfo
+	Findings  []TLSFinding
+}
+
+// GenerateReport writes a self-contained HTML report of a stored scan to path. If baselineID
+// is not empty the report also lists the differences from that scan, which must be the older
+// of the two.
+func (a *App) GenerateReport(id string, baselineID string, path string) error {
+	if path == "" {
+		return fmt.Errorf("no report path given")
+	}
+	result, err := a.scanResults.load(id)
+	if err != nil {
+		return err
+	}
+	var diff *ScanDiff
+	if baselineID != "" {
+		baseline, err := a.scanResults.load(baselineID)
+		if err != nil {
+			return fmt.Errorf("loading baseline: %w", err)
+		}
+		if err := checkBaseline(baseline, result); err != nil {
+			return err
+		}
+		d := diffScanResults(baseline, result)
+		diff = &d
+	}
+	data, err := renderReport(result, diff, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Wrote report of scan %s to %s.", id, path))
+	return nil
+}
+
+// GenerateReportWithDialog asks where to save the report, then writes it. It returns the
+// chosen path, or "" if the dialog was cancelled.
+func (a *App) GenerateReportWithDialog(id string, baselineID string) (string, error) {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save Scan Report",
+		DefaultFilename: fmt.Sprintf("netview-report-%s.html", time.Now().Format("2006-01-02-1504")),
+		Filters:         []runtime.FileFilter{{DisplayName: "HTML files (*.html)", Pattern: "*.html;*.htm"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	lower := strings.ToLower(path)
+	if !strings.HasSuffix(lower, ".html") && !strings.HasSuffix(lower, ".htm") {
+		path += ".html"
+	}
+	return path, a.GenerateReport(id, baselineID, path)
+}
+
+// renderReport builds the report model and renders the template.
+func renderReport(result ScanResult, diff *ScanDiff, now time.Time) ([]byte, error) {
+	data := reportData{GeneratedAt: now, Scan: result, Diff: diff}
+	switch {
+	case result.PortsUnknown:
+		data.PortsChecked = "not recorded"
+	case result.CheckedPorts != "":
+		data.PortsChecked = strings.ReplaceAll(result.CheckedPorts, ",", ", ")
+	case len(result.Parameters.Ports) > 0:
+		data.PortsChecked = strings.ReplaceAll(formatPortSpec(result.Parameters.Ports), ",", ", ")
+	}
+
+	found := len(result.Hosts)
+	percent := func(n int) int {
+		if found == 0 {
+			return 0
+		}
+		return n * 100 / found
+	}
+	for label, count := range result.Summary.DeviceTypes {
+		data.DeviceTypes = append(data.DeviceTypes, reportCount{Label: label, Count: count, Percent: percent(count)})
+	}
+	if unknown := found - sumCounts(result.Summary.DeviceTypes); unknown > 0 {
+		data.DeviceTypes = append(data.DeviceTypes, reportCount{Label: "unknown", Count: unknown, Percent: percent(unknown)})
+	}
+	sortCounts(data.DeviceTypes)
+	for port, count := range result.Summary.PortCounts {
+		label := strconv.Itoa(port)
+		if name := scanner.WellKnownPortNames[port]; name != "" {
+			label += " (" + name + ")"
+		}
+		data.TopPorts = append(data.TopPorts, reportCount{Label: label, Count: count, Percent: percent(count)})
+	}
+	sortCounts(data.TopPorts)
+
+	// Certificates are judged as of the scan, which is what the report documents
+	for _, h := range result.Hosts {
+		rh := reportHost{Host: h, VendorName: hostVendor(h)}
+		services := servicesByPort(h)
+		for _, p := range h.OpenPorts {
+			s := services[p]
+			s.Port = p
+			if s.Name == "" {
+				s.Name = scanner.WellKnownPortNames[p]
+			}
+			rh.Services = append(rh.Services, s)
+			if s.TLS != nil {
+				cert := reportCertificate{IPAddress: h.IPAddress, Hostname: h.Hostname, Port: p, Service: s.Name, TLS: s.TLS, Findings: scanner.TLSFindings(s.TLS, result.CompletedAt)}
+				data.FindingCount += len(cert.Findings)
+				data.Certificates = append(data.Certificates, cert)
+			}
+		}
+		data.Hosts = append(data.Hosts, rh)
+	}
+	// Certificates with findings first
+	sort.SliceStable(data.Certificates, func(i, j int) bool {
+		return len(data.Certificates[i].Findings) > len(data.Certificates[j].Findings)
+	})
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("rendering report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+// sortCounts orders a breakdown by count, largest first, then by label.
+func sortCounts(counts []reportCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Label < counts[j].Label
+	})
+}
+
+// joinPortNumbers formats a port list as "22, 80, 443".
+func joinPortNumbers(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ", ")
+}
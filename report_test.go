@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportTestScan has two TLS services, one of them with problems, and a hostile banner.
+func reportTestScan() ScanResult {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	good := &TLSInfo{Version: "TLS 1.3", Subject: "CN=nas.lan", Issuer: "CN=Home CA", DNSNames: []string{"nas.lan", "nas"},
+		NotBefore: t0.AddDate(-1, 0, 0), NotAfter: t0.AddDate(1, 0, 0), KeyType: "ECDSA", KeyBits: 256, SignatureAlgorithm: "ECDSA-SHA256"}
+	bad := &TLSInfo{Version: "TLS 1.0", Subject: "CN=printer", Issuer: "CN=printer", SelfSigned: true,
+		NotBefore: t0.AddDate(-5, 0, 0), NotAfter: t0.AddDate(0, 0, -1), KeyType: "RSA", KeyBits: 1024, SignatureAlgorithm: "SHA1-RSA"}
+	result := newScanResult("r1", ScanRange{StartIP: "192.168.1.1", EndIP: "192.168.1.254", Ports: []int{22, 443, 8443}}, t0, 254, []Host{
+		{IPAddress: "192.168.1.10", Hostname: "nas", DeviceType: "NAS", OpenPorts: []int{22, 443},
+			Services: []ServiceInfo{{Port: 22, Name: "ssh", Banner: "<script>alert(1)</script>"}, {Port: 443, Name: "https", TLS: good}}},
+		{IPAddress: "192.168.1.20", DeviceType: "Printer", OpenPorts: []int{443, 8443},
+			Services: []ServiceInfo{{Port: 8443, TLS: bad}}},
+		{IPAddress: "192.168.1.2", Hostname: "phone"},
+	})
+	result.CompletedAt = t0.Add(90 * time.Second)
+	return result
+}
+
+// wantInOrder checks that each part appears in html after the previous one.
+func wantInOrder(t *testing.T, html string, parts ...string) {
+	t.Helper()
+	rest := html
+	for _, p := range parts {
+		i := strings.Index(rest, p)
+		if i < 0 {
+			t.Errorf("%q missing, or not after %q", p, parts[0])
+			return
+		}
+		rest = rest[i+len(p):]
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	data, err := renderReport(reportTestScan(), nil, time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+
+	wantInOrder(t, html, "<title>NetView scan report - 192.168.1.1 to 192.168.1.254</title>", "scan ID <code>r1</code>")
+	wantInOrder(t, html, `Hosts found</div><div class="value">3</div>`, `Open ports</div><div class="value">4</div>`,
+		`TLS services</div><div class="value">2</div>`, `TLS findings</div><div class="value high">5</div>`)
+	wantInOrder(t, html, "Ports checked: 22, 443, 8443")
+
+	// Breakdowns: largest first, then by label, with hosts without a type counted as unknown
+	wantInOrder(t, html, "<h2>Device types</h2>", "<td>NAS</td><td class=\"num\">1</td>", "width: 33%",
+		"<td>Printer</td>", "<td>unknown</td>", "<h2>Open ports</h2>", "<td>443 (https)</td><td class=\"num\">2</td>", "width: 66%",
+		"<td>22 (ssh)</td>", "<td>8443</td>")
+
+	// Hosts in address order, linked to their services when they have any
+	wantInOrder(t, html, "<h2>Hosts</h2>", "<td>192.168.1.2</td>", `<a href="#host-192.168.1.10">192.168.1.10</a>`, `<a href="#host-192.168.1.20">`)
+	wantInOrder(t, html, `<div class="host" id="host-192.168.1.20">`, `<td class="num">443</td><td>https</td>`, `<td class="num">8443</td><td> <span class="tag">TLS</span>`)
+
+	// Text from the network is escaped
+	if strings.Contains(html, "<script>alert") || !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("banner not escaped")
+	}
+
+	// Certificates with findings first, judged as of the scan rather than the report date
+	wantInOrder(t, html, "<h2>TLS certificates</h2>", "192.168.1.20:8443", "self-signed",
+		"Certificate expired on 2026-02-28", "Certificate is self-signed", "Deprecated protocol TLS 1.0", "Weak 1024-bit RSA key",
+		"Weak signature algorithm SHA1-RSA", "192.168.1.10:443", "CN=Home CA", "nas.lan, nas", `<span class="ok">None</span>`)
+
+	if strings.Contains(html, "Changes since baseline") {
+		t.Error("changes section without a baseline")
+	}
+}
+
+func TestRenderReportWithBaseline(t *testing.T) {
+	scan := reportTestScan()
+	baseline := scanResult("b0", scan.StartedAt.Add(-24*time.Hour), []int{22, 443, 8443},
+		Host{IPAddress: "192.168.1.10", Hostname: "old-nas", OpenPorts: []int{22}},
+		Host{IPAddress: "192.168.1.30", MACAddress: "AA:BB:CC:00:00:30", OpenPorts: []int{80}},
+		Host{IPAddress: "192.168.1.2", Hostname: "phone"},
+		Host{IPAddress: "192.168.1.20", DeviceType: "Printer", OpenPorts: []int{443, 8443}},
+	)
+	diff := diffScanResults(baseline, scan)
+	data, err := renderReport(scan, &diff, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	wantInOrder(t, html, "Changes vs. baseline", `<div class="value">0 / 1 / 1</div>`)
+	wantInOrder(t, html, "<h2>Changes since baseline</h2>", "From scan <code>b0</code>", "to scan <code>r1</code>", "2 hosts unchanged.",
+		"<h3>Appeared (0)</h3>", "None.",
+		"<h3>Disappeared (1)</h3>", `<tr class="removed"><td>192.168.1.30</td>`, "<td>80</td>",
+		"<h3>Changed (1)</h3>", "192.168.1.10", "hostname: <span class=\"removed\">old-nas</span> &rarr; <span class=\"added\">nas</span>",
+		`<div class="added">Opened: 443</div>`)
+}
+
+func TestRenderReportCheckedPorts(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		modify func(*ScanResult)
+		want   string
+	}{
+		{"custom ports as ranges", func(r *ScanResult) { r.Parameters.Ports = []int{8002, 22, 8000, 8001} }, "Ports checked: 22, 8000-8002"},
+		{"imported", func(r *ScanResult) { r.CheckedPorts = "1-65535" }, "Ports checked: 1-65535"},
+		{"imported without ports", func(r *ScanResult) { r.PortsUnknown = true }, "Ports checked: not recorded"},
+	} {
+		scan := newScanResult("p1", ScanRange{StartIP: "10.0.0.1", EndIP: "10.0.0.9"}, time.Now(), 9, nil)
+		c.modify(&scan)
+		data, err := renderReport(scan, nil, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), c.want) {
+			t.Errorf("%s: %q missing", c.name, c.want)
+		}
+	}
+}
+
+func TestRenderReportEmptyScan(t *testing.T) {
+	scan := newScanResult("e1", ScanRange{StartIP: "10.0.0.1", EndIP: "10.0.0.9"}, time.Now(), 9, nil)
+	data, err := renderReport(scan, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, want := range []string{`<td colspan="6" class="muted">No hosts found.</td>`, "No open ports found.", "No services found.", "No TLS services found."} {
+		if !strings.Contains(html, want) {
+			t.Errorf("%q missing", want)
+		}
+	}
+	if strings.Contains(html, "Ports checked") {
+		t.Error("ports listed for a scan of the default ports")
+	}
+}
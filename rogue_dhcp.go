@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/alerting"
+	"netview/dhcp"
+	"netview/events"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// DHCPServerOffer is an offer collected by the rogue DHCP check, marked against the allowlist.
+type DHCPServerOffer struct {
+	dhcp.Offer
+	Allowed bool `json:"allowed"`
+}
+
+// DHCPCheckResult is the outcome of one rogue DHCP check.
+type DHCPCheckResult struct {
+	Interface    string            `json:"interface"`
+	Timestamp    time.Time         `json:"timestamp"`
+	Offers       []DHCPServerOffer `json:"offers"`
+	RogueServers []string          `json:"rogueServers"` // Server IDs not on the allowlist
+}
+
+// DHCPCheckSettings configures the rogue DHCP check.
+type DHCPCheckSettings struct {
+	Interface       string   `json:"interface"`       // Interface to probe on
+	AllowedServers  []string `json:"allowedServers"`  // Legitimate DHCP server IDs
+	IntervalMinutes int      `json:"intervalMinutes"` // Periodic check while monitoring; 0 disables
+	TimeoutSeconds  int      `json:"timeoutSeconds"`  // How long to collect offers
+}
+
+// NetworkInterfaceInfo describes a local interface the DHCP check can probe on.
+type NetworkInterfaceInfo struct {
+	Name       string   `json:"name"`
+	MACAddress string   `json:"macAddress"`
+	Addresses  []string `json:"addresses"`
+}
+
+const dhcpCheckFilename = "dhcp_check.json"
+const defaultDHCPTimeout = 5 * time.Second
+const maxDHCPTimeoutSeconds = 60
+
+// dhcpCheck is the rogue DHCP check: its settings and the state of the periodic check.
+type dhcpCheck struct {
+	events wailsEvents
+	alerts *alerter
+
+	mu          sync.Mutex
+	settings    DHCPCheckSettings
+	lastCheck   time.Time       // Time of the last periodic check
+	running     bool            // A periodic check is in progress
+	knownRogues map[string]bool // Rogue servers already alerted on, so each is reported once while present
+}
+
+// openDHCPCheck loads the rogue DHCP check settings. Called on app startup.
+func openDHCPCheck(ctx context.Context, ev wailsEvents, alerts *alerter) *dhcpCheck {
+	c := &dhcpCheck{
+		events:      ev,
+		alerts:      alerts,
+		settings:    DHCPCheckSettings{AllowedServers: []string{}, TimeoutSeconds: int(defaultDHCPTimeout / time.Second)},
+		knownRogues: make(map[string]bool),
+	}
+	path, err := dhcpCheckFilePath()
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("DHCP check settings path unavailable: %v", err))
+		return c
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			runtime.LogError(ctx, fmt.Sprintf("Error reading DHCP check settings '%s': %v", path, err))
+		}
+		return c
+	}
+	if err := json.Unmarshal(data, &c.settings); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error unmarshalling DHCP check settings from '%s': %v", path, err))
+	}
+	return c
+}
+
+// dhcpCheckFilePath returns the full path of the DHCP check settings file.
+func dhcpCheckFilePath() (string, error) {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDataDir, dhcpCheckFilename), nil
+}
+
+// runDHCPCheck probes for DHCP servers on the named interface and marks each offer against
+// the allowlist. No lease is ever requested.
+func runDHCPCheck(ctx context.Context, interfaceName string, allowed []string, timeout time.Duration) (DHCPCheckResult, error) {
+	result := DHCPCheckResult{Interface: interfaceName, Timestamp: time.Now(), Offers: []DHCPServerOffer{}, RogueServers: []string{}}
+	iface, err := net.InterfaceByName(interfaceName)
+	if err != nil {
+		return result, fmt.Errorf("interface %q: %w", interfaceName, err)
+	}
+	runtime.LogInfo(ctx, fmt.Sprintf("Probing for DHCP servers on %s (%s).", iface.Name, timeout))
+	offers, err := dhcp.Discover(ctx, iface, timeout)
+	if err != nil {
+		return result, err
+	}
+
+	rogues := make(map[string]bool)
+	for _, offer := range offers {
+		isAllowed := slices.Contains(allowed, offer.ServerID)
+		result.Offers = append(result.Offers, DHCPServerOffer{Offer: offer, Allowed: isAllowed})
+		if !isAllowed {
+			rogues[offer.ServerID] = true
+		}
+	}
+	for server := range rogues {
+		result.RogueServers = append(result.RogueServers, server)
+	}
+	sort.Strings(result.RogueServers)
+	runtime.LogInfo(ctx, fmt.Sprintf("DHCP check on %s: %d offer(s), rogue servers: %v", iface.Name, len(result.Offers), result.RogueServers))
+	return result, nil
+}
+
+// runScheduled runs the periodic rogue DHCP check when it is due. It is called on every
+// monitor cycle and runs the probe in the background so host checks are not delayed.
+func (c *dhcpCheck) runScheduled(ctx context.Context) {
+	c.mu.Lock()
+	settings := c.settings
+	interval := time.Duration(settings.IntervalMinutes) * time.Minute
+	if interval <= 0 || settings.Interface == "" || c.running || time.Since(c.lastCheck) < interval {
+		c.mu.Unlock()
+		return
+	}
+	c.running = true
+	c.lastCheck = time.Now()
+	c.mu.Unlock()
+
+	go func() { // Ends with ctx, i.e. when monitoring stops
+		result, err := runDHCPCheck(ctx, settings.Interface, settings.AllowedServers, dhcpTimeout(settings))
+		if err != nil {
+			c.mu.Lock()
+			c.running = false
+			c.mu.Unlock()
+			runtime.LogWarning(ctx, fmt.Sprintf("Periodic DHCP check failed: %v", err))
+			return
+		}
+
+		// Work out which rogues are new under the lock; emit and alert once it is released
+		var alerts []alerting.Event
+		c.mu.Lock()
+		c.running = false
+		current := make(map[string]bool)
+		for _, server := range result.RogueServers {
+			current[server] = true
+		}
+		for _, offer := range result.Offers {
+			if !offer.Allowed && !c.knownRogues[offer.ServerID] {
+				c.knownRogues[offer.ServerID] = true
+				alerts = append(alerts, rogueDHCPAlert(offer, result))
+			}
+		}
+		for server := range c.knownRogues {
+			if !current[server] {
+				delete(c.knownRogues, server) // Gone; alert again if it comes back
+			}
+		}
+		c.mu.Unlock()
+
+		if len(current) > 0 {
+			c.events.Emit(events.RogueDHCPDetected, result)
+		}
+		for _, event := range alerts {
+			c.alerts.raise(event)
+		}
+	}()
+}
+
+// dhcpTimeout returns the offer collection window for the settings.
+func dhcpTimeout(settings DHCPCheckSettings) time.Duration {
+	if settings.TimeoutSeconds <= 0 {
+		return defaultDHCPTimeout
+	}
+	return time.Duration(settings.TimeoutSeconds) * time.Second
+}
+
+// rogueDHCPAlert builds the alert for an offer from a server not on the allowlist.
+func rogueDHCPAlert(offer DHCPServerOffer, result DHCPCheckResult) alerting.Event {
+	event := alerting.Event{
+		Type:      alerting.EventRogueDHCP,
+		Timestamp: result.Timestamp,
+		IPAddress: offer.ServerID,
+	}
+	event.Message = fmt.Sprintf("%s on %s offered %s (gateway %s, DNS %s, lease %ds)", event.Title(), result.Interface,
+		offer.OfferedIP, strings.Join(offer.Gateways, ", "), strings.Join(offer.DNSServers, ", "), offer.LeaseSeconds)
+	return event
+}
+
+// ListNetworkInterfaces returns the local interfaces that can be probed for DHCP servers.
+func (a *App) ListNetworkInterfaces() ([]NetworkInterfaceInfo, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	result := []NetworkInterfaceInfo{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		info := NetworkInterfaceInfo{Name: iface.Name, MACAddress: strings.ToUpper(iface.HardwareAddr.String()), Addresses: []string{}}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				info.Addresses = append(info.Addresses, addr.String())
+			}
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// RunDHCPCheck broadcasts a DHCPDISCOVER on the given interface (or the configured one if empty)
+// and returns every offer received, flagging servers that are not on the allowlist. No lease is
+// accepted. This usually requires administrator privileges.
+func (a *App) RunDHCPCheck(interfaceName string) (DHCPCheckResult, error) {
+	a.dhcpCheck.mu.Lock()
+	settings := a.dhcpCheck.settings
+	a.dhcpCheck.mu.Unlock()
+
+	if interfaceName == "" {
+		interfaceName = settings.Interface
+	}
+	if interfaceName == "" {
+		return DHCPCheckResult{}, fmt.Errorf("no interface selected for the DHCP check")
+	}
+	return runDHCPCheck(a.ctx, interfaceName, settings.AllowedServers, dhcpTimeout(settings))
+}
+
+// GetDHCPCheckSettings returns the rogue DHCP check settings.
+func (a *App) GetDHCPCheckSettings() DHCPCheckSettings {
+	a.dhcpCheck.mu.Lock()
+	defer a.dhcpCheck.mu.Unlock()
+	return a.dhcpCheck.settings
+}
+
+// SaveDHCPCheckSettings validates and stores the rogue DHCP check settings. The periodic check
+// runs as part of monitoring, so it only takes effect while monitoring is active.
+func (a *App) SaveDHCPCheckSettings(settings DHCPCheckSettings) error {
+	for _, server := range settings.AllowedServers {
+		if net.ParseIP(server).To4() == nil {
+			return fmt.Errorf("invalid DHCP server address %q", server)
+		}
+	}
+	if settings.TimeoutSeconds < 0 || settings.TimeoutSeconds > maxDHCPTimeoutSeconds {
+		return fmt.Errorf("timeout must be between 0 and %d seconds", maxDHCPTimeoutSeconds)
+	}
+	if settings.IntervalMinutes < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	if settings.IntervalMinutes > 0 {
+		if settings.Interface == "" {
+			return fmt.Errorf("an interface is required for the periodic DHCP check")
+		}
+		if len(settings.AllowedServers) == 0 {
+			return fmt.Errorf("add the legitimate DHCP servers to the allowlist before enabling the periodic check")
+		}
+	}
+	if settings.Interface != "" {
+		if _, err := net.InterfaceByName(settings.Interface); err != nil {
+			return fmt.Errorf("interface %q: %w", settings.Interface, err)
+		}
+	}
+	if settings.AllowedServers == nil {
+		settings.AllowedServers = []string{}
+	}
+
+	a.dhcpCheck.mu.Lock()
+	defer a.dhcpCheck.mu.Unlock()
+
+	path, err := dhcpCheckFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0640); err != nil {
+		return err
+	}
+	a.dhcpCheck.settings = settings
+	a.dhcpCheck.lastCheck = time.Time{} // Run the periodic check at the next monitor cycle
+	runtime.LogInfo(a.ctx, fmt.Sprintf("DHCP check settings saved: interface %s, %d allowed server(s), every %d minutes.", settings.Interface, len(settings.AllowedServers), settings.IntervalMinutes))
+	return nil
+}
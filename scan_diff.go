@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"netview/scanner"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// FieldChange is a single changed attribute of a host between two scans.
+type FieldChange struct {
+	Field  string `json:"field"` // hostname, macAddress, vendor or deviceType
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ServiceChange is a service whose announced version changed on a port open in both scans.
+type ServiceChange struct {
+	Port   int    `json:"port"`
+	Name   string `json:"name,omitempty"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// HostDiff lists what changed on a host present in both scans.
+type HostDiff struct {
+	IPAddress      string          `json:"ipAddress"`
+	Hostname       string          `json:"hostname,omitempty"` // Hostname in the newer scan
+	Changes        []FieldChange   `json:"changes"`
+	OpenedPorts    []int           `json:"openedPorts"`
+	ClosedPorts    []int           `json:"closedPorts"`
+	ServiceChanges []ServiceChange `json:"serviceChanges"`
+}
+
+// ScanDiff is the difference between two stored scans, from the older (A) to the newer (B).
+type ScanDiff struct {
+	ScanA       ScanResultInfo `json:"scanA"`
+	ScanB       ScanResultInfo `json:"scanB"`
+	Appeared    []Host         `json:"appeared"`    // Hosts only in B
+	Disappeared []Host         `json:"disappeared"` // Hosts only in A
+	Changed     []HostDiff     `json:"changed"`     // Hosts in both with differences
+	Unchanged   int            `json:"unchanged"`   // Hosts in both without differences
+}
+
+// DiffScans compares two stored scans and returns the hosts that appeared, disappeared or
+// changed between the baseline idA and the later scan idB. It fails if idA started after idB
+// rather than reporting every change backwards.
+func (a *App) DiffScans(idA string, idB string) (ScanDiff, error) {
+	scanA, err := a.scanResults.load(idA)
+	if err != nil {
+		return ScanDiff{}, err
+	}
+	scanB, err := a.scanResults.load(idB)
+	if err != nil {
+		return ScanDiff{}, err
+	}
+	if err := checkBaseline(scanA, scanB); err != nil {
+		return ScanDiff{}, err
+	}
+	diff := diffScanResults(scanA, scanB)
+	runtime.LogDebug(a.ctx, fmt.Sprintf("DiffScans %s -> %s: %d appeared, %d disappeared, %d changed, %d unchanged.",
+		scanA.ID, scanB.ID, len(diff.Appeared), len(diff.Disappeared), len(diff.Changed), diff.Unchanged))
+	return diff, nil
+}
+
+// checkBaseline reports an error if baseline started after the scan it is compared with.
+func checkBaseline(baseline, scan ScanResult) error {
+	if scan.StartedAt.Before(baseline.StartedAt) {
+		return fmt.Errorf("baseline scan %s (%s) is newer than scan %s (%s)", baseline.ID,
+			baseline.StartedAt.Format(time.DateTime), scan.ID, scan.StartedAt.Format(time.DateTime))
+	}
+	return nil
+}
+
+// diffScanResults compares two scans, matching hosts by IP address. Hosts and ports are only
+// compared where both scans covered them, so scans of different ranges or port lists do not
+// report what only one of them probed as appeared, disappeared, opened or closed.
+func diffScanResults(older, newer ScanResult) ScanDiff {
+	diff := ScanDiff{
+		ScanA:       older.ScanResultInfo,
+		ScanB:       newer.ScanResultInfo,
+		Appeared:    []Host{},
+		Disappeared: []Host{},
+		Changed:     []HostDiff{},
+	}
+
+	olderChecked, newerChecked := intSet(older.checkedPorts()), intSet(newer.checkedPorts())
+	olderRange, errOlder := older.Parameters.Range()
+	newerRange, errNewer := newer.Parameters.Range()
+	inBothRanges := func(ip string) bool { // A scan whose range cannot be read is taken to cover every address
+		n, err := scanner.IPToUint32(ip)
+		if err != nil {
+			return false
+		}
+		return (errOlder != nil || olderRange.Start <= n && n <= olderRange.End) &&
+			(errNewer != nil || newerRange.Start <= n && n <= newerRange.End)
+	}
+	before := make(map[string]Host, len(older.Hosts))
+	for _, h := range older.Hosts {
+		before[h.IPAddress] = h
+	}
+	seen := make(map[string]bool, len(newer.Hosts))
+	for _, h := range newer.Hosts {
+		seen[h.IPAddress] = true
+		old, ok := before[h.IPAddress]
+		if !ok {
+			if inBothRanges(h.IPAddress) {
+				diff.Appeared = append(diff.Appeared, h)
+			}
+			continue
+		}
+		if hd, changed := diffHosts(old, h, olderChecked, newerChecked); changed {
+			diff.Changed = append(diff.Changed, hd)
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for _, h := range older.Hosts {
+		if !seen[h.IPAddress] && inBothRanges(h.IPAddress) {
+			diff.Disappeared = append(diff.Disappeared, h)
+		}
+	}
+
+	byIP := func(hosts []Host) {
+		sort.Slice(hosts, func(i, j int) bool {
+			a, _ := scanner.IPToUint32(hosts[i].IPAddress)
+			b, _ := scanner.IPToUint32(hosts[j].IPAddress)
+			return a < b
+		})
+	}
+	byIP(diff.Appeared)
+	byIP(diff.Disappeared)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		a, _ := scanner.IPToUint32(diff.Changed[i].IPAddress)
+		b, _ := scanner.IPToUint32(diff.Changed[j].IPAddress)
+		return a < b
+	})
+	return diff
+}
+
+// diffHosts compares two observations of the same IP. prevChecked and curChecked are the
+// ports each scan probed; a port open in one scan is only reported as opened or closed if the
+// other scan checked it too.
+func diffHosts(prev, cur Host, prevChecked, curChecked map[int]bool) (HostDiff, bool) {
+	hd := HostDiff{IPAddress: cur.IPAddress, Hostname: cur.Hostname, Changes: []FieldChange{}, OpenedPorts: []int{}, ClosedPorts: []int{}, ServiceChanges: []ServiceChange{}}
+
+	compare := func(field, before, after string) {
+		if before != after {
+			hd.Changes = append(hd.Changes, FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	compare("hostname", prev.Hostname, cur.Hostname)
+	compare("macAddress", normalizeMAC(prev.MACAddress), normalizeMAC(cur.MACAddress))
+	compare("vendor", hostVendor(prev), hostVendor(cur))
+	compare("deviceType", prev.DeviceType, cur.DeviceType)
+
+	oldPorts, newPorts := intSet(prev.OpenPorts), intSet(cur.OpenPorts)
+	for p := range newPorts {
+		if !oldPorts[p] && prevChecked[p] {
+			hd.OpenedPorts = append(hd.OpenedPorts, p)
+		}
+	}
+	for p := range oldPorts {
+		if !newPorts[p] && curChecked[p] {
+			hd.ClosedPorts = append(hd.ClosedPorts, p)
+		}
+	}
+	sort.Ints(hd.OpenedPorts)
+	sort.Ints(hd.ClosedPorts)
+
+	// Versions are only comparable when both scans identified the service on the port
+	oldServices := make(map[int]ServiceInfo, len(prev.Services))
+	for _, s := range prev.Services {
+		oldServices[s.Port] = s
+	}
+	for _, s := range cur.Services {
+		earlier, ok := oldServices[s.Port]
+		if !ok || earlier.Version == "" || s.Version == "" || earlier.Version == s.Version {
+			continue
+		}
+		hd.ServiceChanges = append(hd.ServiceChanges, ServiceChange{Port: s.Port, Name: s.Name, Before: earlier.Version, After: s.Version})
+	}
+	sort.Slice(hd.ServiceChanges, func(i, j int) bool { return hd.ServiceChanges[i].Port < hd.ServiceChanges[j].Port })
+
+	changed := len(hd.Changes) > 0 || len(hd.OpenedPorts) > 0 || len(hd.ClosedPorts) > 0 || len(hd.ServiceChanges) > 0
+	return hd, changed
+}
+
+// hostVendor returns the host's vendor, looking it up from the MAC for results stored before
+// vendors were recorded.
+func hostVendor(h Host) string {
+	if h.Vendor != "" {
+		return h.Vendor
+	}
+	return lookupVendor(h.MACAddress)
+}
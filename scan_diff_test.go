@@ -0,0 +1,124 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scanResult(id string, started time.Time, ports []int, hosts ...Host) ScanResult {
+	return ScanResult{
+		ScanResultInfo: ScanResultInfo{ID: id, StartedAt: started, Parameters: ScanRange{StartIP: "192.168.1.1", EndIP: "192.168.1.254", Ports: ports}},
+		Hosts:          hosts,
+	}
+}
+
+func TestDiffScanResults(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	older := scanResult("a", t0, []int{22, 80, 443},
+		Host{IPAddress: "192.168.1.10", Hostname: "nas", MACAddress: "aa-bb-cc-00-00-10", Vendor: "Acme", OpenPorts: []int{22, 80},
+			Services: []ServiceInfo{{Port: 22, Name: "ssh", Version: "OpenSSH_9.6"}}},
+		Host{IPAddress: "192.168.1.11", Vendor: "Acme", OpenPorts: []int{443}},
+		Host{IPAddress: "192.168.1.12", Vendor: "Acme"},
+	)
+	newer := scanResult("b", t0.Add(time.Hour), []int{22, 80, 443},
+		Host{IPAddress: "192.168.1.10", Hostname: "nas-2", MACAddress: "AA:BB:CC:00:00:10", Vendor: "Acme", OpenPorts: []int{22, 443},
+			Services: []ServiceInfo{{Port: 22, Name: "ssh", Version: "OpenSSH_9.7"}}},
+		Host{IPAddress: "192.168.1.11", Vendor: "Acme", OpenPorts: []int{443}},
+		Host{IPAddress: "192.168.1.2", Vendor: "Acme"},
+	)
+
+	diff := diffScanResults(older, newer)
+	if diff.ScanA.ID != "a" || diff.ScanB.ID != "b" || diff.Unchanged != 1 {
+		t.Errorf("scans %s -> %s, %d unchanged", diff.ScanA.ID, diff.ScanB.ID, diff.Unchanged)
+	}
+	if len(diff.Appeared) != 1 || diff.Appeared[0].IPAddress != "192.168.1.2" {
+		t.Errorf("appeared = %+v", diff.Appeared)
+	}
+	if len(diff.Disappeared) != 1 || diff.Disappeared[0].IPAddress != "192.168.1.12" {
+		t.Errorf("disappeared = %+v", diff.Disappeared)
+	}
+	want := []HostDiff{{
+		IPAddress:      "192.168.1.10",
+		Hostname:       "nas-2",
+		Changes:        []FieldChange{{Field: "hostname", Before: "nas", After: "nas-2"}}, // MAC spelling is not a change
+		OpenedPorts:    []int{443},
+		ClosedPorts:    []int{80},
+		ServiceChanges: []ServiceChange{{Port: 22, Name: "ssh", Before: "OpenSSH_9.6", After: "OpenSSH_9.7"}},
+	}}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("changed = %+v, want %+v", diff.Changed, want)
+	}
+}
+
+func TestDiffScanResultsOnlyComparesCommonPorts(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name                   string
+		olderPorts, newerPorts []int
+		olderOpen, newerOpen   []int
+		opened, closed         []int
+	}{
+		{"newer scan checked fewer ports", []int{22, 80, 3389}, []int{22, 80}, []int{22, 3389}, []int{80}, []int{80}, []int{22}},
+		{"newer scan checked more ports", []int{22, 80}, []int{22, 80, 3389}, []int{22}, []int{3389}, []int{}, []int{22}},
+		{"default ports against a custom list", nil, []int{22, 8443}, []int{22, 80, 8080}, []int{8443}, []int{}, []int{22}},
+		{"port open in both scans", []int{22}, []int{80}, []int{22, 80}, []int{22, 80}, []int{}, []int{}},
+	} {
+		older := scanResult("a", t0, c.olderPorts, Host{IPAddress: "192.168.1.10", OpenPorts: c.olderOpen})
+		newer := scanResult("b", t0.Add(time.Hour), c.newerPorts, Host{IPAddress: "192.168.1.10", OpenPorts: c.newerOpen})
+		diff := diffScanResults(older, newer)
+
+		opened, closed := []int{}, []int{}
+		if len(diff.Changed) == 1 {
+			opened, closed = diff.Changed[0].OpenedPorts, diff.Changed[0].ClosedPorts
+		}
+		if !reflect.DeepEqual(opened, c.opened) || !reflect.DeepEqual(closed, c.closed) {
+			t.Errorf("%s: opened %v, closed %v; want %v, %v", c.name, opened, closed, c.opened, c.closed)
+		}
+		if wantChanged := len(c.opened)+len(c.closed) > 0; (len(diff.Changed) == 1) != wantChanged {
+			t.Errorf("%s: changed = %+v", c.name, diff.Changed)
+		}
+	}
+}
+
+func TestDiffScanResultsOnlyComparesCommonAddresses(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	older := scanResult("a", t0, nil, // 192.168.1.1 - 192.168.1.254
+		Host{IPAddress: "192.168.1.10"}, Host{IPAddress: "192.168.1.20"}, Host{IPAddress: "192.168.1.60"}, Host{IPAddress: "192.168.1.70"})
+	newer := scanResult("b", t0.Add(time.Hour), nil,
+		Host{IPAddress: "192.168.1.10"}, Host{IPAddress: "192.168.1.30"}, Host{IPAddress: "192.168.1.70"}, Host{IPAddress: "192.168.2.5"})
+	newer.Parameters.StartIP, newer.Parameters.EndIP = "192.168.1.1", "192.168.1.50"
+	newer.Hosts[2].Hostname = "found outside its own range" // As an nmap import of several targets may be
+
+	diff := diffScanResults(older, newer)
+	ips := func(hosts []Host) []string {
+		var out []string
+		for _, h := range hosts {
+			out = append(out, h.IPAddress)
+		}
+		return out
+	}
+	// .60 was outside the rescan and 192.168.2.5 outside the baseline; .70 is in both results and compared
+	if !reflect.DeepEqual(ips(diff.Appeared), []string{"192.168.1.30"}) || !reflect.DeepEqual(ips(diff.Disappeared), []string{"192.168.1.20"}) {
+		t.Errorf("appeared %v, disappeared %v", ips(diff.Appeared), ips(diff.Disappeared))
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].IPAddress != "192.168.1.70" || diff.Unchanged != 1 {
+		t.Errorf("changed %+v, %d unchanged", diff.Changed, diff.Unchanged)
+	}
+}
+
+func TestCheckBaseline(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	older := scanResult("a", t0, nil)
+	newer := scanResult("b", t0.Add(time.Hour), nil)
+	if err := checkBaseline(older, newer); err != nil {
+		t.Errorf("older baseline rejected: %v", err)
+	}
+	if err := checkBaseline(older, older); err != nil {
+		t.Errorf("scan compared with itself rejected: %v", err)
+	}
+	if err := checkBaseline(newer, older); err == nil || !strings.Contains(err.Error(), "newer than") {
+		t.Errorf("newer baseline: error %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"netview/events"
+	"netview/storage"
+)
+
+// Scan job states.
+const (
+	scanStateRunning   = "running"
+	scanStateCompleted = "completed"
+	scanStateCancelled = "cancelled"
+)
+
+// ScanJob describes a scan started during this session, from the GUI or the API.
+type ScanJob struct {
+	ID         string    `json:"id"` // Also the ID of the stored result, once completed
+	Parameters ScanRange `json:"parameters"`
+	State      string    `json:"state"` // running, completed or cancelled
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+	HostsFound int       `json:"hostsFound"`
+	Stored     bool      `json:"stored"` // The result was stored and can be read with GetScanResult
+}
+
+// scanJob tracks a running or recently finished scan and the hosts it found, so they can be
+// streamed to API clients as they arrive.
+type scanJob struct {
+	mu      sync.Mutex
+	info    ScanJob
+	hosts   []Host
+	changed chan struct{} // Closed and replaced whenever a host is found or the job ends
+	cancel  context.CancelFunc
+}
+
+const maxFinishedScanJobs = 20 // Finished jobs kept for listing; their results stay in the store
+
+// scanJobs lists the scans started during this session.
+type scanJobs struct {
+	mu   sync.Mutex
+	jobs []*scanJob // Oldest first
+}
+
+// newScanJob returns a running job for params and the context its scan runs under, which
+// attributes the scan's events to the job.
+func newScanJob(ctx context.Context, params ScanRange) (*scanJob, context.Context) {
+	scanCtx, cancel := context.WithCancel(ctx)
+	job := &scanJob{
+		info:    ScanJob{ID: storage.NewID(), Parameters: params, State: scanStateRunning, StartedAt: time.Now()},
+		changed: make(chan struct{}),
+		cancel:  cancel,
+	}
+	return job, events.WithScanID(scanCtx, job.info.ID)
+}
+
+// addHost records a host found by the job's scan.
+func (j *scanJob) addHost(host Host) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.hosts = append(j.hosts, host)
+	j.info.HostsFound = len(j.hosts)
+	j.notifyLocked()
+}
+
+// finish marks the job as ended in the given state.
+func (j *scanJob) finish(state string, stored bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.State = state
+	j.info.Stored = stored
+	j.info.FinishedAt = time.Now()
+	j.cancel() // Release the context
+	j.notifyLocked()
+}
+
+// notifyLocked wakes everyone waiting for the job to change. The caller must hold j.mu.
+func (j *scanJob) notifyLocked() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// snapshot returns the job's current description.
+func (j *scanJob) snapshot() ScanJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+// hostsFrom returns the hosts found after the first n, whether the job has ended, and a
+// channel that is closed on the next change.
+func (j *scanJob) hostsFrom(n int) ([]Host, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	hosts := append([]Host(nil), j.hosts[min(n, len(j.hosts)):]...)
+	return hosts, j.info.State != scanStateRunning, j.changed
+}
+
+// register adds a job to the list, dropping the oldest finished jobs beyond
+// maxFinishedScanJobs.
+func (l *scanJobs) register(job *scanJob) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.jobs = append(l.jobs, job)
+	finished := 0
+	for _, j := range l.jobs {
+		if j.snapshot().State != scanStateRunning {
+			finished++
+		}
+	}
+	kept := l.jobs[:0]
+	for _, j := range l.jobs {
+		if finished > maxFinishedScanJobs && j.snapshot().State != scanStateRunning {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	l.jobs = kept
+}
+
+// find returns the job with the given ID, or nil.
+func (l *scanJobs) find(id string) *scanJob {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, j := range l.jobs {
+		if j.info.ID == id { // IDs never change, so j.mu is not needed
+			return j
+		}
+	}
+	return nil
+}
+
+// list returns the jobs of this session, most recent first.
+func (l *scanJobs) list() []ScanJob {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	jobs := make([]ScanJob, 0, len(l.jobs))
+	for i := len(l.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, l.jobs[i].snapshot())
+	}
+	return jobs
+}
+
+// cancel stops a running scan. The job is marked cancelled once the scan has wound down.
+func (l *scanJobs) cancel(id string) error {
+	job := l.find(id)
+	if job == nil {
+		return fmt.Errorf("scan %s not found", id)
+	}
+	if job.snapshot().State != scanStateRunning {
+		return fmt.Errorf("scan %s is not running", id)
+	}
+	job.cancel()
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netview/scanner"
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ScanSummary holds the headline numbers of a completed scan.
+type ScanSummary struct {
+	AddressesScanned int            `json:"addressesScanned"`
+	HostsFound       int            `json:"hostsFound"`
+	OpenPorts        int            `json:"openPorts"`   // Open ports across all hosts
+	DeviceTypes      map[string]int `json:"deviceTypes"` // Device type -> host count
+	PortCounts       map[int]int    `json:"portCounts"`  // Port -> number of hosts with it open
+}
+
+// ScanResultInfo describes a stored scan without its host list, for listing.
+type ScanResultInfo struct {
+	ID          string      `json:"id"`
+	Parameters  ScanRange   `json:"parameters"`
+	StartedAt   time.Time   `json:"startedAt"`
+	CompletedAt time.Time   `json:"completedAt"`
+	DurationMs  int64       `json:"durationMs"`
+	Summary     ScanSummary `json:"summary"`
+	// Imported nmap scans may have checked all 65535 ports, so their ports are kept as ranges
+	// ("22,80,8000-8010") instead of in Parameters, or marked unknown if nmap did not list them.
+	CheckedPorts string `json:"checkedPorts,omitempty"`
+	PortsUnknown bool   `json:"portsUnknown,omitempty"`
+}
+
+// checkedPorts returns the ports the scan probed on each live host, or nil if they are unknown.
+func (info ScanResultInfo) checkedPorts() []int {
+	if info.PortsUnknown {
+		return nil
+	}
+	if info.CheckedPorts != "" {
+		ports, _ := parsePortSpec(info.CheckedPorts)
+		return ports
+	}
+	return info.Parameters.ServicePorts()
+}
+
+// ScanResult is a completed scan: its parameters, timing, summary and every host found.
+type ScanResult struct {
+	ScanResultInfo
+	Hosts []Host `json:"hosts"`
+}
+
+const maxStoredScanResults = 50 // Oldest results are deleted beyond this (a retention policy on the store)
+
+// scanResults stores completed scans and keeps an index of them for listing.
+type scanResults struct {
+	store storage.Store // Nil if the data store is not available
+
+	mu    sync.Mutex
+	index []ScanResultInfo // Stored results, most recent first
+
+	// The certificates that stored scans last read from the monitored hosts, for the
+	// certificate expiry metric. The monitor's copy of a host is taken when it is added and
+	// never refreshed, so a renewed certificate only shows up in later scans.
+	tlsMu       sync.Mutex
+	tlsKey      string                   // Stored scan IDs and monitored IPs tlsServices was built from
+	tlsServices map[string][]ServiceInfo // IP -> TLS services with a certificate
+}
+
+// openScanResults loads the index of the scan results in store. Called on app startup.
+func openScanResults(ctx context.Context, store storage.Store) *scanResults {
+	r := &scanResults{store: store, index: []ScanResultInfo{}}
+	if store == nil {
+		runtime.LogError(ctx, "Scan results will not be stored: data store not available")
+		return r
+	}
+	err := store.ForEach(scanIndexBucket, func(key string, value []byte) error {
+		var info ScanResultInfo
+		if err := json.Unmarshal(value, &info); err != nil {
+			runtime.LogError(ctx, fmt.Sprintf("Skipping corrupt scan result index entry %s: %v", key, err))
+			return nil
+		}
+		r.index = append(r.index, info)
+		return nil
+	})
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error reading scan results index: %v", err))
+	}
+	sort.Slice(r.index, func(i, j int) bool { return r.index[i].StartedAt.After(r.index[j].StartedAt) })
+	runtime.LogInfo(ctx, fmt.Sprintf("Loaded index of %d stored scan results.", len(r.index)))
+	return r
+}
+
+// save stores a completed scan. The store's retention policy drops the oldest results beyond
+// maxStoredScanResults.
+func (r *scanResults) save(ctx context.Context, result ScanResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.store == nil {
+		return fmt.Errorf("scan result storage is not available")
+	}
+	if err := storage.PutJSON(r.store, scanResultsBucket, result.ID, result); err != nil {
+		return err
+	}
+	if err := storage.PutJSON(r.store, scanIndexBucket, result.ID, result.ScanResultInfo); err != nil {
+		return err
+	}
+
+	// Imported scans may be older than those already stored, so keep the index ordered by
+	// start time and in step with what the retention policy left in the store
+	stored := make(map[string]bool, maxStoredScanResults)
+	for _, id := range r.store.Keys(scanIndexBucket) {
+		stored[id] = true
+	}
+	index := []ScanResultInfo{result.ScanResultInfo}
+	for _, info := range r.index {
+		if stored[info.ID] && info.ID != result.ID {
+			index = append(index, info)
+		}
+	}
+	sort.SliceStable(index, func(i, j int) bool { return index[i].StartedAt.After(index[j].StartedAt) })
+	r.index = index
+	runtime.LogDebug(ctx, fmt.Sprintf("Stored scan result %s.", result.ID))
+	return nil
+}
+
+// load reads a stored scan result.
+func (r *scanResults) load(id string) (ScanResult, error) {
+	var result ScanResult
+	if r.store == nil {
+		return result, fmt.Errorf("scan result %s not found", id)
+	}
+	if err := storage.GetJSON(r.store, scanResultsBucket, id, &result); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return result, fmt.Errorf("scan result %s not found", id)
+		}
+		return result, fmt.Errorf("reading scan result %s: %w", id, err)
+	}
+	return result, nil
+}
+
+// list returns the stored scans, most recent first.
+func (r *scanResults) list() []ScanResultInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ScanResultInfo{}, r.index...)
+}
+
+// latestTLSServices returns, for each of ips, the TLS services of the most recent stored scan
+// that read a certificate from that host. The result is cached until the stored scans or ips
+// change.
+func (r *scanResults) latestTLSServices(ips []string) map[string][]ServiceInfo {
+	var ids []string
+	for _, info := range r.list() {
+		ids = append(ids, info.ID)
+	}
+	ips = slices.Sorted(slices.Values(ips))
+	key := strings.Join(ids, ",") + "|" + strings.Join(ips, ",")
+
+	r.tlsMu.Lock()
+	defer r.tlsMu.Unlock()
+	if r.tlsServices != nil && r.tlsKey == key {
+		return r.tlsServices
+	}
+
+	services := make(map[string][]ServiceInfo, len(ips))
+	wanted := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		wanted[ip] = true
+	}
+	for _, id := range ids { // Most recent first
+		if len(wanted) == 0 {
+			break
+		}
+		result, err := r.load(id)
+		if err != nil {
+			continue
+		}
+		for _, h := range result.Hosts {
+			if !wanted[h.IPAddress] {
+				continue
+			}
+			for _, svc := range h.Services {
+				if svc.TLS != nil && !svc.TLS.NotAfter.IsZero() {
+					services[h.IPAddress] = append(services[h.IPAddress], svc)
+				}
+			}
+			if len(services[h.IPAddress]) > 0 {
+				delete(wanted, h.IPAddress)
+			}
+		}
+	}
+	r.tlsKey, r.tlsServices = key, services
+	return services
+}
+
+// newScanResult assembles the result of a finished scan. Hosts are sorted by IP address.
+func newScanResult(id string, params ScanRange, startedAt time.Time, addresses int, hosts []Host) ScanResult {
+	completedAt := time.Now()
+	sorted := append([]Host{}, hosts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := scanner.IPToUint32(sorted[i].IPAddress)
+		b, _ := scanner.IPToUint32(sorted[j].IPAddress)
+		return a < b
+	})
+
+	summary := ScanSummary{AddressesScanned: addresses, HostsFound: len(sorted), DeviceTypes: map[string]int{}, PortCounts: map[int]int{}}
+	for _, h := range sorted {
+		summary.OpenPorts += len(h.OpenPorts)
+		if h.DeviceType != "" {
+			summary.DeviceTypes[h.DeviceType]++
+		}
+		for _, p := range h.OpenPorts {
+			summary.PortCounts[p]++
+		}
+	}
+
+	return ScanResult{
+		ScanResultInfo: ScanResultInfo{
+			ID:          id,
+			Parameters:  params,
+			StartedAt:   startedAt,
+			CompletedAt: completedAt,
+			DurationMs:  completedAt.Sub(startedAt).Milliseconds(),
+			Summary:     summary,
+		},
+		Hosts: sorted,
+	}
+}
+
+// GetScanResult returns a stored scan with all its hosts, so a past scan can be shown
+// without re-scanning.
+func (a *App) GetScanResult(id string) (ScanResult, error) {
+	return a.scanResults.load(id)
+}
+
+// ListScanResults returns the stored scans, most recent first, without their host lists.
+func (a *App) ListScanResults() []ScanResultInfo {
+	return a.scanResults.list()
+}
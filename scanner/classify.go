@@ -0,0 +1,64 @@
+package scanner
+
+import "strings"
+
+// ClassifyDevice guesses the device type from the address, hostname, MAC vendor and open
+// ports of a host.
+func ClassifyDevice(ipAddress, hostname, vendor string, openPorts []int) string {
+	lowerHostname := strings.ToLower(hostname)
+
+	if strings.Contains(lowerHostname, "printer") || containsAny(openPorts, []int{631, 9100, 515}) {
+		return "printer"
+	}
+	if strings.Contains(lowerHostname, "router") || strings.Contains(lowerHostname, "gateway") ||
+		strings.Contains(lowerHostname, "firewall") || strings.Contains(lowerHostname, "switch") ||
+		ipAddress == "192.168.1.1" || ipAddress == "192.168.0.1" || ipAddress == "10.0.0.1" {
+		return "router_firewall"
+	}
+
+	if strings.Contains(lowerHostname, "macbook") || strings.Contains(lowerHostname, "imac") || strings.Contains(lowerHostname, "apple") || (containsAny(openPorts, []int{22, 548, 445}) && !strings.Contains(lowerHostname, "linux")) {
+		return "macos_pc"
+	}
+
+	lowerVendor := strings.ToLower(vendor)
+	if strings.Contains(lowerVendor, "apple") {
+		return "macos_pc"
+	} else if strings.Contains(lowerVendor, "raspberry") {
+		return "raspberry_pi"
+	}
+
+	if containsAny(openPorts, []int{135, 137, 138, 139, 445}) {
+		return "windows_pc"
+	}
+
+	hasSSH := containsAny(openPorts, []int{22})
+	if hasSSH {
+		if strings.Contains(lowerHostname, "server") || strings.Contains(lowerHostname, "nas") ||
+			strings.Contains(lowerHostname, "ubuntu-server") || strings.Contains(lowerHostname, "centos") ||
+			strings.Contains(lowerHostname, "debian") || containsAny(openPorts, []int{5000, 5001, 8080, 8000, 3000}) {
+			return "linux_server"
+		}
+		return "linux_pc"
+	}
+
+	if strings.Contains(lowerHostname, "android") {
+		return "android_mobile"
+	}
+	if strings.Contains(lowerHostname, "iphone") || strings.Contains(lowerHostname, "ipad") {
+		return "ios_mobile"
+	}
+
+	return "generic_device"
+}
+
+// containsAny checks if a slice of ints contains any of the elements from another slice.
+func containsAny(slice []int, elements []int) bool {
+	for _, s := range slice {
+		for _, e := range elements {
+			if s == e {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package scanner
+
+import "testing"
+
+func TestClassifyDevice(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		ip       string
+		hostname string
+		vendor   string
+		ports    []int
+		want     string
+	}{
+		{"printer by name", "10.0.0.5", "office-printer", "", nil, "printer"},
+		{"printer by JetDirect port", "10.0.0.5", "", "", []int{9100}, "printer"},
+		{"printer by IPP port wins over SSH", "10.0.0.5", "", "", []int{22, 631}, "printer"},
+		{"router by name", "10.0.0.5", "core-switch", "", nil, "router_firewall"},
+		{"router by gateway address", "192.168.1.1", "", "", []int{80}, "router_firewall"},
+		{"mac by name", "10.0.0.5", "Janes-MacBook-Pro", "", nil, "macos_pc"},
+		{"mac by SSH without linux name", "10.0.0.5", "workstation", "", []int{22}, "macos_pc"},
+		{"mac by vendor", "10.0.0.5", "", "Apple, Inc.", []int{80}, "macos_pc"},
+		{"raspberry pi by vendor", "10.0.0.5", "", "Raspberry Pi Trading Ltd", nil, "raspberry_pi"},
+		{"windows by RPC port", "10.0.0.5", "", "", []int{135}, "windows_pc"},
+		{"linux server by name", "10.0.0.5", "linux-server", "", []int{22}, "linux_server"},
+		{"linux server by web port", "10.0.0.5", "linux-box", "", []int{22, 8080}, "linux_server"},
+		{"linux pc", "10.0.0.5", "linux-laptop", "", []int{22}, "linux_pc"},
+		{"android by name", "10.0.0.5", "android-1234", "", nil, "android_mobile"},
+		{"iphone by name", "10.0.0.5", "Janes-iPhone", "", nil, "ios_mobile"},
+		{"nothing known", "10.0.0.5", "", "", []int{80}, "generic_device"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ClassifyDevice(tc.ip, tc.hostname, tc.vendor, tc.ports); got != tc.want {
+				t.Errorf("ClassifyDevice(%q, %q, %q, %v) = %q, want %q", tc.ip, tc.hostname, tc.vendor, tc.ports, got, tc.want)
+			}
+		})
+	}
+}
@@ -1,13 +1,12 @@
-
-package main
+package scanner
 
 import (
 	"fmt"
 	"net"
 )
 
-// ipToUint32 converts an IP string to its uint32 representation.
-func ipToUint32(ipStr string) (uint32, error) {
+// IPToUint32 converts an IP string to its uint32 representation.
+func IPToUint32(ipStr string) (uint32, error) {
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
 		return 0, fmt.Errorf("invalid IP address: %s", ipStr)
@@ -19,7 +18,7 @@ func ipToUint32(ipStr string) (uint32, error) {
 	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3]), nil
 }
 
-// uint32ToIP converts a uint32 IP representation back to a string.
-func uint32ToIP(ipUint uint32) string {
+// Uint32ToIP converts a uint32 IP representation back to a string.
+func Uint32ToIP(ipUint uint32) string {
 	return fmt.Sprintf("%d.%d.%d.%d", byte(ipUint>>24), byte(ipUint>>16), byte(ipUint>>8), byte(ipUint))
 }
@@ -0,0 +1,111 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsHostAlive pings ip and, if searchHidden is set and the ping went unanswered, tries a TCP
+// connection to each of hiddenPorts. It returns the round-trip time of the first answer,
+// where a refused connection counts as an answer.
+func (s *Scanner) IsHostAlive(ctx context.Context, ip string, searchHidden bool, hiddenPorts []int) (time.Duration, bool) {
+	rtt, err := s.Prober.Ping(ctx, ip, s.Timing.PingTimeout)
+	if err == nil {
+		s.log.Debug(fmt.Sprintf("Ping success: %s", ip))
+		return rtt, true
+	}
+	if !errors.Is(err, ErrNoReply) {
+		s.log.Debug(fmt.Sprintf("Ping error: %v", err))
+	}
+	if !searchHidden {
+		return -1, false
+	}
+	for _, port := range hiddenPorts {
+		if rtt, answered := s.TCPPing(ctx, ip, port, s.Timing.TCPPingTimeout); answered {
+			return rtt, true
+		}
+	}
+	return -1, false
+}
+
+// TCPPing reports whether ip answers on port within timeout, either by accepting the
+// connection or by refusing it, and how long the answer took.
+func (s *Scanner) TCPPing(ctx context.Context, ip string, port int, timeout time.Duration) (time.Duration, bool) {
+	startTime := time.Now()
+	conn, err := s.Prober.DialTCP(ctx, ip, port, timeout)
+	duration := time.Since(startTime)
+
+	if err == nil {
+		conn.Close()
+		return duration, true
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return -1, false
+	}
+	if IsRefused(err) {
+		return duration, true
+	}
+	return -1, false
+}
+
+// ScanPorts checks the given ports concurrently and returns those found open, in ascending order.
+func (s *Scanner) ScanPorts(ctx context.Context, ip string, ports []int) []int {
+	open := make([]bool, len(ports))
+	var portWg sync.WaitGroup
+	for i, port := range ports {
+		portWg.Add(1)
+		go func(i, p int) {
+			defer portWg.Done()
+			open[i] = s.scanPort(ctx, ip, p)
+		}(i, port)
+	}
+	portWg.Wait()
+
+	var openPorts []int
+	for i, p := range ports {
+		if open[i] {
+			openPorts = append(openPorts, p)
+		}
+	}
+	return openPorts
+}
+
+// scanPort checks if a specific port is open on the target IP.
+func (s *Scanner) scanPort(ctx context.Context, targetIP string, port int) bool {
+	conn, err := s.Prober.DialTCP(ctx, targetIP, port, s.Timing.PortTimeout)
+	if err != nil {
+		return false // Port is closed or filtered
+	}
+	conn.Close()
+	return true // Port is open
+}
+
+// ResolveHostname returns the name of an IP address from reverse DNS, or "" if it has none.
+func (s *Scanner) ResolveHostname(ctx context.Context, ipAddress string) string {
+	names, err := s.Prober.LookupAddr(ctx, ipAddress)
+	if err == nil && len(names) > 0 {
+		return strings.TrimSuffix(names[0], ".")
+	}
+	return ""
+}
+
+// macAddress looks up the MAC address of ipAddress in the neighbor table and hands every MAC
+// found to OnARPLookup.
+func (s *Scanner) macAddress(ctx context.Context, ipAddress string) string {
+	macs, err := s.Prober.Neighbors(ctx, ipAddress)
+	if err != nil {
+		s.log.Debug(fmt.Sprintf("Neighbor table lookup for %s failed: %v", ipAddress, err))
+	}
+	if s.OnARPLookup != nil && len(macs) > 0 {
+		s.OnARPLookup(ipAddress, macs)
+	}
+	if len(macs) == 0 {
+		return ""
+	}
+	return macs[0]
+}
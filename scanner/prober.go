@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os/exec"
+	"regexp"
+	runtime_go "runtime" // To pick the ping mode and arp command for the OS
+	"slices"
+	"strconv"
+	"strings"
+	"syscall" // For syscall.ECONNREFUSED
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+// Prober is the scanner's only access to the network and the OS. SystemProber is the real
+// thing; scannertest.Network is a programmable fake for tests.
+type Prober interface {
+	// Ping sends a single ICMP echo request and returns the round-trip time of the reply.
+	// It returns ErrNoReply if nothing answered within timeout.
+	Ping(ctx context.Context, ip string, timeout time.Duration) (time.Duration, error)
+	// DialTCP connects to ip:port. A refused connection is reported by an error for which
+	// IsRefused is true, a filtered port by a net.Error whose Timeout() is true.
+	DialTCP(ctx context.Context, ip string, port int, timeout time.Duration) (net.Conn, error)
+	// UDPExchange sends payload to ip:port and returns the first datagram received in reply.
+	// Silence, like a dropped datagram, is reported by a net.Error whose Timeout() is true.
+	UDPExchange(ctx context.Context, ip string, port int, payload []byte, timeout time.Duration) ([]byte, error)
+	// LookupAddr returns the reverse DNS names of ip.
+	LookupAddr(ctx context.Context, ip string) ([]string, error)
+	// Neighbors returns the distinct MAC addresses the neighbor (ARP) table lists for ip, in
+	// upper case. More than one means several devices answer for the IP.
+	Neighbors(ctx context.Context, ip string) ([]string, error)
+}
+
+// ErrNoReply is returned by Prober.Ping when the echo request went unanswered.
+var ErrNoReply = errors.New("no reply")
+
+// IsRefused reports whether err is a refused TCP connection, which proves the host is up.
+func IsRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(strings.ToLower(err.Error()), "connection refused")
+}
+
+const arpTimeout = 2 * time.Second // Timeout for ARP command execution
+
+// SystemProber probes the real network: ICMP through pro-bing, the system resolver, and the
+// arp command for the neighbor table.
+type SystemProber struct{}
+
+// Ping implements Prober.
+func (SystemProber) Ping(ctx context.Context, ip string, timeout time.Duration) (time.Duration, error) {
+	pinger, err := ping.NewPinger(ip)
+	if err != nil {
+		return 0, err
+	}
+	pinger.Count = 1
+	pinger.Timeout = timeout
+	// Windows needs raw sockets; elsewhere unprivileged UDP pings work without root
+	pinger.SetPrivileged(runtime_go.GOOS == "windows")
+	if err := pinger.RunWithContext(ctx); err != nil {
+		return 0, err
+	}
+	stats := pinger.Statistics()
+	if stats.PacketsRecv == 0 {
+		return 0, ErrNoReply
+	}
+	return stats.AvgRtt, nil
+}
+
+// DialTCP implements Prober.
+func (SystemProber) DialTCP(ctx context.Context, ip string, port int, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+}
+
+// UDPExchange implements Prober.
+func (SystemProber) UDPExchange(ctx context.Context, ip string, port int, payload []byte, timeout time.Duration) ([]byte, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(payload); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// LookupAddr implements Prober.
+func (SystemProber) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	return net.DefaultResolver.LookupAddr(ctx, ip)
+}
+
+// Neighbors implements Prober by reading the output of the arp command.
+func (SystemProber) Neighbors(ctx context.Context, ip string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, arpTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch runtime_go.GOOS {
+	case "linux", "darwin":
+		cmd = exec.CommandContext(ctx, "arp", "-n", ip)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "arp", "-a", ip)
+	default:
+		return nil, nil
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseARPOutput(string(output), ip), nil
+}
+
+var macRegex = regexp.MustCompile(`([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})`)
+
+// parseARPOutput returns the distinct MAC addresses listed for ip in arp output.
+func parseARPOutput(output, ip string) []string {
+	var macs []string
+	lines := bufio.NewScanner(strings.NewReader(output))
+	for lines.Scan() {
+		line := lines.Text()
+		if !lineHasIP(line, ip) {
+			continue
+		}
+		match := strings.ToUpper(macRegex.FindString(line))
+		if match != "" && !slices.Contains(macs, match) {
+			macs = append(macs, match)
+		}
+	}
+	return macs
+}
+
+// lineHasIP reports whether a line of arp output refers to exactly ipAddress. A plain substring
+// match would let 192.168.1.10 match the entry for 192.168.1.100.
+func lineHasIP(line, ipAddress string) bool {
+	for _, field := range strings.Fields(line) {
+		if strings.Trim(field, "()") == ipAddress {
+			return true
+		}
+	}
+	return false
+}
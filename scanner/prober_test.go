@@ -0,0 +1,40 @@
+package scanner
+
+import "testing"
+
+func TestParseARPOutput(t *testing.T) {
+	const linux = `Address                  HWtype  HWaddress           Flags Mask            Iface
+192.168.1.10             ether   aa:bb:cc:dd:ee:01   C                     eth0
+192.168.1.100            ether   aa:bb:cc:dd:ee:02   C                     eth0
+`
+	const windows = `
+Interface: 192.168.1.5 --- 0xb
+  Internet Address      Physical Address      Type
+  192.168.1.10          aa-bb-cc-dd-ee-01     dynamic
+`
+	const darwin = `? (192.168.1.10) at aa:bb:cc:dd:ee:1 on en0 ifscope [ethernet]
+? (192.168.1.10) at aa:bb:cc:dd:ee:03 on en0 ifscope [ethernet]
+? (192.168.1.10) at aa:bb:cc:dd:ee:04 on en1 ifscope [ethernet]
+`
+	for _, tc := range []struct {
+		name, output string
+		want         []string
+	}{
+		{"linux, exact address only", linux, []string{"AA:BB:CC:DD:EE:01"}},
+		{"windows", windows, []string{"AA-BB-CC-DD-EE-01"}},
+		{"darwin, several MACs", darwin, []string{"AA:BB:CC:DD:EE:03", "AA:BB:CC:DD:EE:04"}},
+		{"no entry", "192.168.1.10 (192.168.1.10) -- no entry\n", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseARPOutput(tc.output, "192.168.1.10")
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Package scanner discovers hosts on IPv4 ranges: liveness probes, open service ports,
+// banners, TLS certificates, hostnames, MAC addresses and a device type heuristic. It reports
+// through an events.Sink and events.Logger, so it runs the same in the desktop app, on the
+// command line and in tests.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"netview/events"
+)
+
+// Host struct matching TypeScript Host type
+type Host struct {
+	IPAddress  string        `json:"ipAddress"`
+	Hostname   string        `json:"hostname,omitempty"`
+	MACAddress string        `json:"macAddress,omitempty"`
+	Vendor     string        `json:"vendor,omitempty"` // Vendor of the MAC address (OUI lookup)
+	OS         string        `json:"os,omitempty"`     // Note: Real OS detection is complex and not implemented here
+	OpenPorts  []int         `json:"openPorts,omitempty"`
+	Services   []ServiceInfo `json:"services,omitempty"` // Service identified on each open port, from its banner
+	DeviceType string        `json:"deviceType,omitempty"`
+}
+
+// ScanRange struct for custom IP range scanning, now also includes ports and hidden host options
+type ScanRange struct {
+	StartIP           string `json:"startIp"`
+	EndIP             string `json:"endIp"`
+	Ports             []int  `json:"ports,omitempty"`            // Ports to scan for services
+	SearchHiddenHosts bool   `json:"searchHiddenHosts"`          // Flag to enable scanning for hidden hosts
+	HiddenHostsPorts  []int  `json:"hiddenHostsPorts,omitempty"` // Specific ports to probe for hidden host liveness
+}
+
+// DefaultPorts are the service ports scanned when a ScanRange does not list any.
+var DefaultPorts = []int{22, 80, 443, 8080, 445}
+
+// Range checks the start and end addresses and returns them as a Range.
+func (p ScanRange) Range() (Range, error) {
+	if p.StartIP == "" || p.EndIP == "" {
+		return Range{}, fmt.Errorf("a scan requires a valid start and end IP address")
+	}
+	start, errStart := IPToUint32(p.StartIP)
+	end, errEnd := IPToUint32(p.EndIP)
+	if errStart != nil || errEnd != nil {
+		return Range{}, fmt.Errorf("invalid IP range: StartIP parse error: %v, EndIP parse error: %v", errStart, errEnd)
+	}
+	if start > end {
+		return Range{}, fmt.Errorf("start IP cannot be greater than end IP")
+	}
+	return Range{Start: start, End: end}, nil
+}
+
+// ServicePorts returns the ports to check for services on live hosts.
+func (p ScanRange) ServicePorts() []int {
+	if len(p.Ports) > 0 {
+		return p.Ports
+	}
+	return DefaultPorts
+}
+
+// Range is an inclusive range of IPv4 addresses.
+type Range struct {
+	Start, End uint32
+}
+
+// Size returns the number of addresses in the range.
+func (r Range) Size() int {
+	return int(r.End-r.Start) + 1
+}
+
+// Timing controls how fast and how patiently hosts are probed.
+type Timing struct {
+	Concurrency    int           // Addresses probed at once
+	PingTimeout    time.Duration // ICMP echo timeout
+	TCPPingTimeout time.Duration // Per-port timeout of the hidden-host TCP probes
+	PortTimeout    time.Duration // Per-port timeout of the service port scan
+}
+
+// DefaultTiming is used by the GUI, the background sweep and monitoring.
+var DefaultTiming = Timing{Concurrency: 100, PingTimeout: time.Second, TCPPingTimeout: 200 * time.Millisecond, PortTimeout: 500 * time.Millisecond}
+
+// Observer receives the hosts of a scan as they are found. It is called concurrently.
+type Observer interface {
+	HostFound(host Host)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(host Host)
+
+// HostFound calls f(host).
+func (f ObserverFunc) HostFound(host Host) { f(host) }
+
+// VendorLookup resolves MAC addresses to vendor names; *ouidb.OuiDb implements it.
+type VendorLookup interface {
+	VendorLookup(mac string) (string, error)
+}
+
+// Completion describes how a scan started with Start ended.
+type Completion struct {
+	Params    ScanRange
+	StartedAt time.Time
+	Addresses int    // Addresses in the range
+	Hosts     []Host // Live hosts, in the order they were found
+	Cancelled bool   // ctx was cancelled before the whole range was probed
+}
+
+// Scanner probes hosts. Its exported fields may be set before the first scan.
+type Scanner struct {
+	Timing  Timing
+	Prober  Prober       // Network and OS access; SystemProber unless replaced, e.g. by a fake in tests
+	Vendors VendorLookup // Nil leaves vendors empty
+
+	// OnARPLookup, if set, receives the MAC addresses the neighbor table lists for each live
+	// host, so the ARP watch sees every scan.
+	OnARPLookup func(ip string, macs []string)
+
+	events events.Sink
+	log    events.Logger
+}
+
+// New returns a Scanner with the default timing.
+func New(sink events.Sink, log events.Logger) *Scanner {
+	return &Scanner{Timing: DefaultTiming, Prober: SystemProber{}, events: sink, log: log}
+}
+
+// Start validates params and scans the range in the background. Every live host is published
+// as a hostFound event and passed to observer (which may be nil). When the scan ends, also if
+// ctx was cancelled, done is called (if not nil) and then scanComplete is published. An
+// invalid range publishes scanError and scanComplete(false) and is returned. The events are
+// attributed to the scan ID attached to ctx with events.WithScanID, if any.
+func (s *Scanner) Start(ctx context.Context, params ScanRange, observer Observer, done func(Completion)) error {
+	scanID := events.ScanID(ctx)
+	target, err := params.Range()
+	if err != nil {
+		events.EmitScan(s.events, scanID, events.ScanError, err.Error())
+		events.EmitScan(s.events, scanID, events.ScanComplete, false)
+		return err
+	}
+	s.log.Debug(fmt.Sprintf("Scan starting for range %s - %s. SearchHidden: %t, HiddenPorts: %v, ServicePorts: %v",
+		params.StartIP, params.EndIP, params.SearchHiddenHosts, params.HiddenHostsPorts, params.ServicePorts()))
+
+	startedAt := time.Now()
+	publish := ObserverFunc(func(host Host) {
+		events.EmitScan(s.events, scanID, events.HostFound, host)
+		if observer != nil {
+			observer.HostFound(host)
+		}
+	})
+	go func() {
+		defer func() {
+			s.log.Debug("Scan goroutine finished. Emitting scanComplete.")
+			events.EmitScan(s.events, scanID, events.ScanComplete, true)
+		}()
+
+		hosts, completed := s.Run(ctx, []Range{target}, params, publish)
+		if !completed {
+			s.log.Debug("Scan cancelled via context.")
+		}
+		if done != nil {
+			done(Completion{Params: params, StartedAt: startedAt, Addresses: target.Size(), Hosts: hosts, Cancelled: !completed})
+		}
+	}()
+	return nil
+}
+
+// Run probes every address in targets, reporting live hosts to the observer as they are
+// found, and returns them once all probes have finished. It reports false if ctx was
+// cancelled before every address was probed. It publishes no events of its own, so it
+// serves the GUI, the background sweep and the command line alike.
+func (s *Scanner) Run(ctx context.Context, targets []Range, params ScanRange, observer Observer) ([]Host, bool) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, max(s.Timing.Concurrency, 1))
+	servicePorts := params.ServicePorts()
+	var foundHosts []Host
+	var foundMutex sync.Mutex
+
+	for _, target := range targets {
+		for n := target.Start; ; n++ {
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return foundHosts, false
+			default:
+			}
+
+			wg.Add(1)
+			semaphore <- struct{}{}
+
+			go func(ipToScan string) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				host, alive := s.Probe(ctx, ipToScan, servicePorts, params.SearchHiddenHosts, params.HiddenHostsPorts)
+				if !alive {
+					return
+				}
+				foundMutex.Lock()
+				foundHosts = append(foundHosts, host)
+				foundMutex.Unlock()
+				observer.HostFound(host)
+			}(Uint32ToIP(n))
+
+			if n == target.End { // Avoid wrap-around at 255.255.255.255
+				break
+			}
+		}
+	}
+	wg.Wait()
+	return foundHosts, true
+}
+
+// Probe checks whether ip is alive and, if so, gathers its open service ports, services,
+// hostname, MAC address and device type.
+func (s *Scanner) Probe(ctx context.Context, ip string, servicePorts []int, searchHidden bool, hiddenPorts []int) (Host, bool) {
+	if _, alive := s.IsHostAlive(ctx, ip, searchHidden, hiddenPorts); !alive {
+		return Host{}, false
+	}
+
+	openPorts := s.ScanPorts(ctx, ip, servicePorts)
+	hostname := s.ResolveHostname(ctx, ip)
+	macAddress := s.macAddress(ctx, ip)
+	vendor := s.vendor(macAddress)
+
+	return Host{
+		IPAddress:  ip,
+		Hostname:   hostname,
+		MACAddress: macAddress,
+		Vendor:     vendor,
+		OpenPorts:  openPorts, // These are the service ports found open
+		Services:   s.DetectServices(ctx, ip, openPorts),
+		DeviceType: ClassifyDevice(ip, hostname, vendor, openPorts),
+	}, true
+}
+
+// vendor returns the OUI vendor of a MAC address, or "" if unknown.
+func (s *Scanner) vendor(macAddress string) string {
+	if macAddress == "" || s.Vendors == nil {
+		return ""
+	}
+	vendor, err := s.Vendors.VendorLookup(macAddress)
+	if err != nil {
+		return ""
+	}
+	return vendor
+}
@@ -0,0 +1,337 @@
+package scanner_test
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"netview/events"
+	"netview/scanner"
+	"netview/scanner/scannertest"
+)
+
+// newTestScanner returns a scanner on the fake network with short timeouts.
+func newTestScanner(network *scannertest.Network) (*scanner.Scanner, *scannertest.Recorder) {
+	rec := &scannertest.Recorder{}
+	s := scanner.New(rec, rec)
+	s.Prober = network
+	s.Timing = scanner.Timing{Concurrency: 8, PingTimeout: 50 * time.Millisecond, TCPPingTimeout: 20 * time.Millisecond, PortTimeout: 20 * time.Millisecond}
+	s.Vendors = scannertest.Vendors{"B8:27:EB": "Raspberry Pi Foundation"}
+	return s, rec
+}
+
+// mustRange parses a ScanRange's addresses.
+func mustRange(t *testing.T, params scanner.ScanRange) scanner.Range {
+	t.Helper()
+	r, err := params.Range()
+	if err != nil {
+		t.Fatalf("Range(%+v): %v", params, err)
+	}
+	return r
+}
+
+// byIP indexes hosts by address.
+func byIP(hosts []scanner.Host) map[string]scanner.Host {
+	m := make(map[string]scanner.Host, len(hosts))
+	for _, h := range hosts {
+		m[h.IPAddress] = h
+	}
+	return m
+}
+
+func TestRunFindsLiveHosts(t *testing.T) {
+	network := scannertest.New(
+		scannertest.Host{IP: "10.0.0.2", MAC: "b8:27:eb:01:02:03", Names: []string{"pi.lan."}, Ports: map[int]scannertest.Handler{80: nil}},
+		scannertest.Host{IP: "10.0.0.3", Names: []string{"laserprinter.lan."}, Ports: map[int]scannertest.Handler{9100: nil}},
+		scannertest.Host{IP: "10.0.0.4", Down: true, Ports: map[int]scannertest.Handler{80: nil}},
+	)
+	s, _ := newTestScanner(network)
+	params := scanner.ScanRange{StartIP: "10.0.0.1", EndIP: "10.0.0.10", Ports: []int{22, 80, 9100}}
+
+	var observed []string
+	observer := scanner.ObserverFunc(func(h scanner.Host) { observed = append(observed, h.IPAddress) })
+	s.Timing.Concurrency = 1 // The observer above is not synchronised
+	hosts, completed := s.Run(context.Background(), []scanner.Range{mustRange(t, params)}, params, observer)
+
+	if !completed {
+		t.Fatal("Run reported an incomplete scan")
+	}
+	found := byIP(hosts)
+	if len(found) != 2 {
+		t.Fatalf("found %d hosts, want 2: %+v", len(found), hosts)
+	}
+	sort.Strings(observed)
+	if !slices.Equal(observed, []string{"10.0.0.2", "10.0.0.3"}) {
+		t.Errorf("observer saw %v", observed)
+	}
+
+	pi := found["10.0.0.2"]
+	if pi.Hostname != "pi.lan" || pi.MACAddress != "B8:27:EB:01:02:03" || pi.Vendor != "Raspberry Pi Foundation" {
+		t.Errorf("pi = %+v", pi)
+	}
+	if !slices.Equal(pi.OpenPorts, []int{80}) {
+		t.Errorf("pi open ports = %v, want [80]", pi.OpenPorts)
+	}
+	if pi.DeviceType != "raspberry_pi" {
+		t.Errorf("pi device type = %q", pi.DeviceType)
+	}
+	if printer := found["10.0.0.3"]; printer.DeviceType != "printer" || printer.MACAddress != "" {
+		t.Errorf("printer = %+v", printer)
+	}
+	if stats := network.Stats(); stats.Pings != 10 {
+		t.Errorf("sent %d pings, want one per address (10)", stats.Pings)
+	}
+}
+
+func TestRunHiddenHosts(t *testing.T) {
+	network := scannertest.New(
+		// Ignores pings but refuses connections: the refusal proves it is up
+		scannertest.Host{IP: "10.0.1.1", NoPing: true},
+		// Ignores pings and silently drops connections to closed ports, but has SSH open
+		scannertest.Host{IP: "10.0.1.2", NoPing: true, Filtered: true, Ports: map[int]scannertest.Handler{22: nil}},
+		// Ignores pings and drops everything on the hidden-host ports
+		scannertest.Host{IP: "10.0.1.3", NoPing: true, Filtered: true, Ports: map[int]scannertest.Handler{8443: nil}},
+	)
+	s, _ := newTestScanner(network)
+
+	for _, tc := range []struct {
+		name         string
+		searchHidden bool
+		want         []string
+	}{
+		{"ping only", false, nil},
+		{"hidden host search", true, []string{"10.0.1.1", "10.0.1.2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := scanner.ScanRange{StartIP: "10.0.1.1", EndIP: "10.0.1.3", Ports: []int{22}, SearchHiddenHosts: tc.searchHidden, HiddenHostsPorts: []int{22, 3389}}
+			hosts, _ := s.Run(context.Background(), []scanner.Range{mustRange(t, params)}, params, scanner.ObserverFunc(func(scanner.Host) {}))
+			var got []string
+			for _, h := range hosts {
+				got = append(got, h.IPAddress)
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("found %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunLatencyAndLoss(t *testing.T) {
+	network := scannertest.New(
+		scannertest.Host{IP: "10.0.2.1", Latency: 5 * time.Millisecond},
+		scannertest.Host{IP: "10.0.2.2", Latency: time.Second}, // Slower than the ping timeout
+		scannertest.Host{IP: "10.0.2.3", Loss: 1},              // Drops every probe
+	)
+	s, _ := newTestScanner(network)
+	params := scanner.ScanRange{StartIP: "10.0.2.1", EndIP: "10.0.2.3"}
+
+	hosts, _ := s.Run(context.Background(), []scanner.Range{mustRange(t, params)}, params, scanner.ObserverFunc(func(scanner.Host) {}))
+	if len(hosts) != 1 || hosts[0].IPAddress != "10.0.2.1" {
+		t.Errorf("found %+v, want only 10.0.2.1", hosts)
+	}
+
+	rtt, alive := s.IsHostAlive(context.Background(), "10.0.2.1", false, nil)
+	if !alive || rtt != 5*time.Millisecond {
+		t.Errorf("IsHostAlive(10.0.2.1) = %v, %t; want 5ms, true", rtt, alive)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	s, _ := newTestScanner(scannertest.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	params := scanner.ScanRange{StartIP: "10.1.0.0", EndIP: "10.1.255.255"}
+	_, completed := s.Run(ctx, []scanner.Range{mustRange(t, params)}, params, scanner.ObserverFunc(func(scanner.Host) {}))
+	if completed {
+		t.Error("a cancelled scan reported completion")
+	}
+}
+
+func TestRunSeveralRanges(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.0.1"}, scannertest.Host{IP: "10.0.9.9"}, scannertest.Host{IP: "10.0.5.5"})
+	s, _ := newTestScanner(network)
+
+	targets := []scanner.Range{
+		mustRange(t, scanner.ScanRange{StartIP: "10.0.0.1", EndIP: "10.0.0.1"}),
+		mustRange(t, scanner.ScanRange{StartIP: "10.0.9.9", EndIP: "10.0.9.9"}),
+	}
+	hosts, _ := s.Run(context.Background(), targets, scanner.ScanRange{}, scanner.ObserverFunc(func(scanner.Host) {}))
+	if len(hosts) != 2 {
+		t.Errorf("found %+v, want the two targeted hosts", hosts)
+	}
+	if got := network.Stats().Pings; got != 2 {
+		t.Errorf("sent %d pings, want 2", got)
+	}
+}
+
+func TestStartPublishesEvents(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "192.168.5.10"}, scannertest.Host{IP: "192.168.5.20"})
+	s, rec := newTestScanner(network)
+
+	done := make(chan scanner.Completion, 1)
+	params := scanner.ScanRange{StartIP: "192.168.5.1", EndIP: "192.168.5.30"}
+	if err := s.Start(events.WithScanID(context.Background(), "scan-1"), params, nil, func(c scanner.Completion) { done <- c }); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	var completion scanner.Completion
+	select {
+	case completion = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scan did not complete")
+	}
+	if completion.Addresses != 30 || len(completion.Hosts) != 2 || completion.Params.StartIP != params.StartIP || completion.Cancelled {
+		t.Errorf("completion = %+v", completion)
+	}
+
+	// scanComplete is published after done returns
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.Named(events.ScanComplete)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	evs := rec.Events()
+	if len(evs) != 3 {
+		t.Fatalf("events = %+v, want two hostFound and scanComplete", evs)
+	}
+	for _, e := range evs[:2] {
+		if e.Name != events.HostFound {
+			t.Errorf("event %q, want %q", e.Name, events.HostFound)
+		}
+	}
+	for _, e := range evs {
+		if e.ScanID != "scan-1" {
+			t.Errorf("event %q attributed to scan %q, want scan-1", e.Name, e.ScanID)
+		}
+	}
+	if last := evs[2]; last.Name != events.ScanComplete || last.Data != true {
+		t.Errorf("last event = %+v, want scanComplete(true)", last)
+	}
+}
+
+func TestStartReportsCancellation(t *testing.T) {
+	s, rec := newTestScanner(scannertest.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan scanner.Completion, 1)
+	if err := s.Start(ctx, scanner.ScanRange{StartIP: "10.1.0.0", EndIP: "10.1.255.255"}, nil, func(c scanner.Completion) { done <- c }); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case c := <-done:
+		if !c.Cancelled {
+			t.Errorf("completion = %+v, want cancelled", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("done was not called for a cancelled scan")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.Named(events.ScanComplete)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := rec.Named(events.ScanComplete); len(got) != 1 || got[0] != true {
+		t.Errorf("scanComplete events = %v, want one scanComplete(true)", got)
+	}
+}
+
+func TestStartRejectsInvalidRange(t *testing.T) {
+	for _, params := range []scanner.ScanRange{
+		{},
+		{StartIP: "10.0.0.9", EndIP: "10.0.0.1"},
+		{StartIP: "10.0.0.1", EndIP: "not-an-ip"},
+	} {
+		s, rec := newTestScanner(scannertest.New())
+		err := s.Start(context.Background(), params, nil, func(scanner.Completion) { t.Error("done called for an invalid range") })
+		if err == nil {
+			t.Errorf("Start(%+v) succeeded", params)
+			continue
+		}
+		evs := rec.Events()
+		if len(evs) != 2 || evs[0].Name != events.ScanError || evs[1].Name != events.ScanComplete || evs[1].Data != false {
+			t.Errorf("Start(%+v) events = %+v, want scanError and scanComplete(false)", params, evs)
+		}
+	}
+}
+
+func TestDetectServices(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.3.1", Ports: map[int]scannertest.Handler{
+		22:   scannertest.Greeting("SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13"),
+		25:   scannertest.Greeting("220 mail.example.com ESMTP Postfix"),
+		80:   scannertest.HTTPServer("nginx/1.24.0"),
+		5900: nil, // Silent
+	}})
+	s, _ := newTestScanner(network)
+
+	services := s.DetectServices(context.Background(), "10.0.3.1", []int{22, 25, 80, 5900})
+	want := []scanner.ServiceInfo{
+		{Port: 22, Name: "ssh", Version: "OpenSSH_9.6p1", Banner: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13"},
+		{Port: 25, Name: "smtp", Version: "mail.example.com ESMTP Postfix", Banner: "220 mail.example.com ESMTP Postfix"},
+		{Port: 80, Name: "http", Version: "nginx/1.24.0"},
+		{Port: 5900, Name: "vnc"},
+	}
+	if len(services) != len(want) {
+		t.Fatalf("services = %+v", services)
+	}
+	for i := range want {
+		if services[i] != want[i] {
+			t.Errorf("service %d = %+v, want %+v", i, services[i], want[i])
+		}
+	}
+}
+
+func TestResolveHostname(t *testing.T) {
+	network := scannertest.New(
+		scannertest.Host{IP: "10.0.4.1", Names: []string{"files.example.com.", "alias.example.com."}},
+		scannertest.Host{IP: "10.0.4.2"},
+	)
+	s, _ := newTestScanner(network)
+
+	for ip, want := range map[string]string{"10.0.4.1": "files.example.com", "10.0.4.2": "", "10.0.4.3": ""} {
+		if got := s.ResolveHostname(context.Background(), ip); got != want {
+			t.Errorf("ResolveHostname(%s) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestOnARPLookup(t *testing.T) {
+	network := scannertest.New(scannertest.Host{IP: "10.0.6.1", MAC: "aa:bb:cc:dd:ee:ff"})
+	s, _ := newTestScanner(network)
+	seen := map[string][]string{}
+	s.OnARPLookup = func(ip string, macs []string) { seen[ip] = macs }
+
+	host, alive := s.Probe(context.Background(), "10.0.6.1", nil, false, nil)
+	if !alive || host.MACAddress != "AA:BB:CC:DD:EE:FF" {
+		t.Fatalf("Probe = %+v, %t", host, alive)
+	}
+	if !slices.Equal(seen["10.0.6.1"], []string{"AA:BB:CC:DD:EE:FF"}) {
+		t.Errorf("OnARPLookup saw %v", seen)
+	}
+}
+
+func TestTCPPing(t *testing.T) {
+	network := scannertest.New(
+		scannertest.Host{IP: "10.0.7.1", Ports: map[int]scannertest.Handler{443: nil}},
+		scannertest.Host{IP: "10.0.7.2", Filtered: true},
+	)
+	s, _ := newTestScanner(network)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		ip   string
+		port int
+		want bool
+	}{
+		{"10.0.7.1", 443, true}, // Open
+		{"10.0.7.1", 444, true}, // Refused
+		{"10.0.7.2", 443, false},
+		{"10.0.7.9", 443, false}, // No such host
+	} {
+		if _, got := s.TCPPing(ctx, tc.ip, tc.port, 20*time.Millisecond); got != tc.want {
+			t.Errorf("TCPPing(%s, %d) = %t, want %t", tc.ip, tc.port, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package scannertest provides a programmable in-memory network for testing the scanner and
+// the packages built on it. Tests declare hosts with their open ports, services, names,
+// latencies and packet loss, and hand the Network to a scanner.Scanner as its Prober.
+package scannertest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"netview/scanner"
+)
+
+// Handler serves one accepted TCP connection, e.g. by writing a greeting. The connection is
+// closed when it returns.
+type Handler func(conn net.Conn)
+
+// UDPHandler answers one datagram. A nil reply means the host stays silent.
+type UDPHandler func(payload []byte) []byte
+
+// Host is a simulated host.
+type Host struct {
+	IP       string
+	MAC      string             // Listed in the neighbor table while the host is up; empty for none
+	Names    []string           // Reverse DNS names
+	Latency  time.Duration      // Added to every answer; answers slower than the probe timeout are lost
+	Loss     float64            // Fraction of probes dropped, from 0 to 1
+	NoPing   bool               // ICMP is filtered
+	Filtered bool               // Closed TCP ports time out instead of refusing the connection
+	Down     bool               // The host does not answer at all
+	Ports    map[int]Handler    // Open TCP ports; a nil Handler accepts and closes the connection
+	UDP      map[int]UDPHandler // UDP services; other UDP ports stay silent
+}
+
+// Stats counts the probes sent to a Network.
+type Stats struct {
+	Pings           int
+	Dials           int
+	UDPExchanges    int
+	Lookups         int
+	NeighborLookups int
+}
+
+// Network is a scanner.Prober over simulated hosts. Addresses without a host behave like
+// unused addresses: nothing answers. Its methods are safe for concurrent use.
+type Network struct {
+	mu    sync.Mutex
+	hosts map[string]*Host
+	rand  *rand.Rand // Decides packet loss; seeded so runs are repeatable
+	stats Stats
+}
+
+var _ scanner.Prober = (*Network)(nil)
+
+// New returns a Network with the given hosts.
+func New(hosts ...Host) *Network {
+	n := &Network{hosts: make(map[string]*Host), rand: rand.New(rand.NewSource(1))}
+	for _, h := range hosts {
+		n.Add(h)
+	}
+	return n
+}
+
+// Add adds a host, replacing any host with the same IP.
+func (n *Network) Add(h Host) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.hosts[h.IP] = &h
+}
+
+// Remove removes a host; its address stops answering.
+func (n *Network) Remove(ip string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.hosts, ip)
+}
+
+// SetDown takes a host off the network or brings it back.
+func (n *Network) SetDown(ip string, down bool) {
+	n.Update(ip, func(h *Host) { h.Down = down })
+}
+
+// Update changes a host in place. It panics if there is no host with that IP, which is
+// always a mistake in the test.
+func (n *Network) Update(ip string, change func(h *Host)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	h, ok := n.hosts[ip]
+	if !ok {
+		panic(fmt.Sprintf("scannertest: no host %s", ip))
+	}
+	change(h)
+}
+
+// Stats returns the probe counts so far.
+func (n *Network) Stats() Stats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats
+}
+
+// reachableLocked returns a copy of the host at ip if it is up and the probe was not dropped.
+// The caller must hold n.mu.
+func (n *Network) reachableLocked(ip string) (Host, bool) {
+	h, ok := n.hosts[ip]
+	if !ok || h.Down {
+		return Host{}, false
+	}
+	if h.Loss > 0 && n.rand.Float64() < h.Loss {
+		return Host{}, false
+	}
+	return *h, true
+}
+
+// Ping implements scanner.Prober.
+func (n *Network) Ping(ctx context.Context, ip string, timeout time.Duration) (time.Duration, error) {
+	n.mu.Lock()
+	n.stats.Pings++
+	h, ok := n.reachableLocked(ip)
+	n.mu.Unlock()
+
+	if !ok || h.NoPing || h.Latency > timeout {
+		return 0, scanner.ErrNoReply
+	}
+	if err := sleep(ctx, h.Latency); err != nil {
+		return 0, err
+	}
+	return h.Latency, nil
+}
+
+// DialTCP implements scanner.Prober. Connections to open ports are in-memory pipes served
+// by the port's Handler.
+func (n *Network) DialTCP(ctx context.Context, ip string, port int, timeout time.Duration) (net.Conn, error) {
+	n.mu.Lock()
+	n.stats.Dials++
+	h, ok := n.reachableLocked(ip)
+	n.mu.Unlock()
+
+	handler, open := h.Ports[port]
+	if !ok || h.Latency > timeout || (!open && h.Filtered) {
+		return nil, dialError(os.ErrDeadlineExceeded)
+	}
+	if err := sleep(ctx, h.Latency); err != nil {
+		return nil, dialError(err)
+	}
+	if !open {
+		return nil, dialError(os.NewSyscallError("connect", syscall.ECONNREFUSED))
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if handler != nil {
+			handler(server)
+		}
+	}()
+	return client, nil
+}
+
+// UDPExchange implements scanner.Prober.
+func (n *Network) UDPExchange(ctx context.Context, ip string, port int, payload []byte, timeout time.Duration) ([]byte, error) {
+	n.mu.Lock()
+	n.stats.UDPExchanges++
+	h, ok := n.reachableLocked(ip)
+	n.mu.Unlock()
+
+	handler := h.UDP[port]
+	silence := &net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded}
+	if !ok || handler == nil || h.Latency > timeout {
+		return nil, silence
+	}
+	if err := sleep(ctx, h.Latency); err != nil {
+		return nil, err
+	}
+	reply := handler(payload)
+	if reply == nil {
+		return nil, silence
+	}
+	return reply, nil
+}
+
+// LookupAddr implements scanner.Prober. Names resolve whether or not the host is up, as
+// they would from a DNS server.
+func (n *Network) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stats.Lookups++
+	if h, ok := n.hosts[ip]; ok && len(h.Names) > 0 {
+		return append([]string(nil), h.Names...), nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: ip, IsNotFound: true}
+}
+
+// Neighbors implements scanner.Prober.
+func (n *Network) Neighbors(ctx context.Context, ip string) ([]string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stats.NeighborLookups++
+	if h, ok := n.hosts[ip]; ok && !h.Down && h.MAC != "" {
+		return []string{strings.ToUpper(h.MAC)}, nil
+	}
+	return nil, nil
+}
+
+// dialError wraps err the way the net package reports failed dials.
+func dialError(err error) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: err}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
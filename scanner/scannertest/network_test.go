@@ -0,0 +1,68 @@
+package scannertest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// echoUpper answers with the payload upper-cased, and ignores empty datagrams.
+func echoUpper(payload []byte) []byte {
+	if len(payload) == 0 {
+		return nil
+	}
+	reply := make([]byte, len(payload))
+	for i, b := range payload {
+		if 'a' <= b && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		reply[i] = b
+	}
+	return reply
+}
+
+func TestUDPExchange(t *testing.T) {
+	n := New(
+		Host{IP: "10.0.0.1", Latency: 5 * time.Millisecond, UDP: map[int]UDPHandler{7: echoUpper}},
+		Host{IP: "10.0.0.2", Down: true, UDP: map[int]UDPHandler{7: echoUpper}},
+		Host{IP: "10.0.0.3", Loss: 1, UDP: map[int]UDPHandler{7: echoUpper}},
+		Host{IP: "10.0.0.4", Latency: time.Second, UDP: map[int]UDPHandler{7: echoUpper}},
+	)
+	ctx := context.Background()
+
+	reply, err := n.UDPExchange(ctx, "10.0.0.1", 7, []byte("ping"), time.Second)
+	if err != nil || string(reply) != "PING" {
+		t.Errorf("reply = %q, %v", reply, err)
+	}
+
+	for _, c := range []struct {
+		name    string
+		ip      string
+		port    int
+		payload string
+	}{
+		{"handler stays silent", "10.0.0.1", 7, ""},
+		{"no service on the port", "10.0.0.1", 137, "ping"},
+		{"host down", "10.0.0.2", 7, "ping"},
+		{"datagram dropped", "10.0.0.3", 7, "ping"},
+		{"reply after the timeout", "10.0.0.4", 7, "ping"},
+		{"no such host", "10.0.0.9", 7, "ping"},
+	} {
+		reply, err := n.UDPExchange(ctx, c.ip, c.port, []byte(c.payload), 100*time.Millisecond)
+		var netErr net.Error
+		if reply != nil || !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("%s: %q, %v; want a timeout", c.name, reply, err)
+		}
+	}
+	if got := n.Stats().UDPExchanges; got != 7 {
+		t.Errorf("%d exchanges counted, want 7", got)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := n.UDPExchange(cancelled, "10.0.0.1", 7, []byte("ping"), time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled exchange: %v", err)
+	}
+}
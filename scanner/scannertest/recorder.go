@@ -0,0 +1,87 @@
+package scannertest
+
+import (
+	"strings"
+	"sync"
+)
+
+// Event is an event captured by a Recorder.
+type Event struct {
+	Name   string
+	ScanID string // Set for events emitted with EmitScan
+	Data   any
+}
+
+// Recorder is an events.ScanSink and events.Logger that keeps everything it receives.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+	logs   []string
+}
+
+// Emit implements events.Sink.
+func (r *Recorder) Emit(name string, data any) {
+	r.EmitScan("", name, data)
+}
+
+// EmitScan implements events.ScanSink.
+func (r *Recorder) EmitScan(scanID, name string, data any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, Event{Name: name, ScanID: scanID, Data: data})
+}
+
+func (r *Recorder) Debug(message string)   { r.log("DEBUG", message) }
+func (r *Recorder) Info(message string)    { r.log("INFO", message) }
+func (r *Recorder) Warning(message string) { r.log("WARNING", message) }
+func (r *Recorder) Error(message string)   { r.log("ERROR", message) }
+
+func (r *Recorder) log(level, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, level+" "+message)
+}
+
+// Events returns the events received so far, in order.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Named returns the data of the events with the given name, in order.
+func (r *Recorder) Named(name string) []any {
+	var data []any
+	for _, e := range r.Events() {
+		if e.Name == name {
+			data = append(data, e.Data)
+		}
+	}
+	return data
+}
+
+// Logged reports whether a log message containing text was received.
+func (r *Recorder) Logged(text string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, l := range r.logs {
+		if strings.Contains(l, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Vendors is a scanner.VendorLookup over a fixed MAC prefix -> vendor table. Prefixes are
+// the first three octets in upper case, e.g. "B8:27:EB".
+type Vendors map[string]string
+
+// VendorLookup implements scanner.VendorLookup.
+func (v Vendors) VendorLookup(mac string) (string, error) {
+	if len(mac) >= 8 {
+		if vendor, ok := v[strings.ToUpper(mac[:8])]; ok {
+			return vendor, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,28 @@
+package scannertest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Greeting returns a Handler that sends line, like an SSH, FTP or SMTP server announcing itself.
+func Greeting(line string) Handler {
+	return func(conn net.Conn) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+}
+
+// HTTPServer returns a Handler that answers one HTTP request with an empty 200 response
+// carrying the given Server header.
+func HTTPServer(server string) Handler {
+	return func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		req.Body.Close()
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nServer: %s\r\nContent-Length: 0\r\nConnection: close\r\n\r\n", server)
+	}
+}
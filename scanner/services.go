@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceInfo describes the service found on an open port, identified from its banner.
+type ServiceInfo struct {
+	Port    int      `json:"port"`
+	Name    string   `json:"name,omitempty"`    // Service name, e.g. "ssh" or "http"
+	Version string   `json:"version,omitempty"` // Product and version as announced, e.g. "OpenSSH_9.6p1" or "nginx/1.24.0"
+	Banner  string   `json:"banner,omitempty"`  // First line the service sent, truncated
+	TLS     *TLSInfo `json:"tls,omitempty"`     // Handshake and certificate, for TLS services
+}
+
+const (
+	bannerTimeout   = 1500 * time.Millisecond // Per-port budget for connecting and reading a banner
+	maxBannerLength = 200
+)
+
+// WellKnownPortNames names the services on common ports, mostly ones worth noticing when they appear.
+var WellKnownPortNames = map[int]string{
+	21: "ftp", 22: "ssh", 23: "telnet", 25: "smtp", 53: "dns", 80: "http", 135: "msrpc",
+	139: "netbios", 443: "https", 445: "smb", 1433: "mssql", 3306: "mysql", 3389: "rdp",
+	5432: "postgres", 5900: "vnc", 8080: "http-alt",
+}
+
+// Ports where the client speaks first with HTTP; everything else is read for a greeting.
+var httpPorts = map[int]bool{80: true, 81: true, 8000: true, 8008: true, 8080: true, 8888: true, 3000: true, 5000: true}
+var httpsPorts = map[int]bool{443: true, 8443: true, 5001: true, 9443: true}
+
+// DetectServices grabs banners from the open ports concurrently. Ports that yield nothing
+// identifiable are listed with their well-known name only.
+func (s *Scanner) DetectServices(ctx context.Context, ipAddress string, openPorts []int) []ServiceInfo {
+	if len(openPorts) == 0 {
+		return nil
+	}
+	services := make([]ServiceInfo, len(openPorts))
+	var wg sync.WaitGroup
+	for i, port := range openPorts {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			services[i] = s.grabServiceInfo(ctx, ipAddress, port)
+		}(i, port)
+	}
+	wg.Wait()
+	return services
+}
+
+// grabServiceInfo identifies the service on one port.
+func (s *Scanner) grabServiceInfo(ctx context.Context, ipAddress string, port int) ServiceInfo {
+	info := ServiceInfo{Port: port, Name: WellKnownPortNames[port]}
+	address := net.JoinHostPort(ipAddress, strconv.Itoa(port))
+
+	switch {
+	case httpPorts[port]:
+		if info.Name == "" {
+			info.Name = "http"
+		}
+		info.Version, _ = s.httpServerHeader(ctx, "http://"+address)
+	case httpsPorts[port]:
+		if info.Name == "" {
+			info.Name = "https"
+		}
+		info.Version, info.TLS = s.httpServerHeader(ctx, "https://"+address)
+	case tlsPorts[port] != "":
+		if info.Name == "" {
+			info.Name = tlsPorts[port]
+		}
+		info.TLS = s.grabTLSInfo(ctx, ipAddress, port)
+	default:
+		info.Banner = s.readGreeting(ctx, ipAddress, port)
+		if info.Banner != "" {
+			info.Name, info.Version = parseGreeting(info.Banner, info.Name)
+		}
+	}
+	return info
+}
+
+// readGreeting connects and returns the first line the service sends unprompted (SSH, FTP,
+// SMTP, POP3, IMAP, ...), or "" if it stays silent.
+func (s *Scanner) readGreeting(ctx context.Context, ipAddress string, port int) string {
+	conn, err := s.Prober.DialTCP(ctx, ipAddress, port, bannerTimeout)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(bannerTimeout))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return ""
+	}
+	line = strings.TrimSpace(strings.ToValidUTF8(line, ""))
+	if len(line) > maxBannerLength {
+		line = line[:maxBannerLength]
+	}
+	return line
+}
+
+// parseGreeting derives the service name and version from a greeting line.
+func parseGreeting(banner, name string) (string, string) {
+	switch {
+	case strings.HasPrefix(banner, "SSH-"):
+		// "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13" -> "OpenSSH_9.6p1"
+		parts := strings.SplitN(banner, "-", 3)
+		if len(parts) == 3 {
+			if fields := strings.Fields(parts[2]); len(fields) > 0 {
+				return "ssh", fields[0]
+			}
+		}
+		return "ssh", ""
+	case strings.HasPrefix(banner, "220"):
+		// FTP and SMTP: "220 mail.example.com ESMTP Postfix (Ubuntu)"
+		text := strings.TrimSpace(strings.TrimLeft(banner[3:], " -"))
+		if name == "" {
+			name = "ftp"
+			if strings.Contains(strings.ToUpper(text), "SMTP") {
+				name = "smtp"
+			}
+		}
+		return name, text
+	case strings.HasPrefix(banner, "+OK"):
+		return "pop3", strings.TrimSpace(banner[3:])
+	case strings.HasPrefix(banner, "* OK"):
+		return "imap", strings.TrimSpace(banner[4:])
+	}
+	return name, ""
+}
+
+// httpServerHeader sends a HEAD request and returns the Server header, e.g. "nginx/1.24.0",
+// and for HTTPS the TLS handshake details.
+func (s *Scanner) httpServerHeader(ctx context.Context, url string) (string, *TLSInfo) {
+	client := &http.Client{
+		Timeout: bannerTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, address string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(address)
+				if err != nil {
+					return nil, err
+				}
+				portNumber, err := strconv.Atoi(port)
+				if err != nil {
+					return nil, err
+				}
+				return s.Prober.DialTCP(ctx, host, portNumber, bannerTimeout)
+			},
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10}, // Identifying the service, not trusting it
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", nil
+	}
+	req.Header.Set("User-Agent", "NetView")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil
+	}
+	resp.Body.Close()
+	var tlsInfo *TLSInfo
+	if resp.TLS != nil {
+		tlsInfo = describeTLS(*resp.TLS)
+	}
+	return strings.TrimSpace(resp.Header.Get("Server")), tlsInfo
+}
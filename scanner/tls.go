@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TLSInfo describes the TLS handshake and leaf certificate of a service.
+type TLSInfo struct {
+	Version            string    `json:"version"` // Negotiated protocol, e.g. "TLS 1.3"
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	DNSNames           []string  `json:"dnsNames,omitempty"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	SelfSigned         bool      `json:"selfSigned"`
+	KeyType            string    `json:"keyType"` // RSA, ECDSA or Ed25519
+	KeyBits            int       `json:"keyBits"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+}
+
+// TLSFinding is a problem with a service's TLS setup.
+type TLSFinding struct {
+	Severity string `json:"severity"` // "high" or "medium"
+	Message  string `json:"message"`
+}
+
+// Ports that speak TLS from the first byte but are not HTTPS, with their service names.
+var tlsPorts = map[int]string{465: "smtps", 636: "ldaps", 853: "dns-over-tls", 993: "imaps", 995: "pop3s", 8883: "mqtts"}
+
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// grabTLSInfo performs a TLS handshake (without verifying the certificate) and describes it.
+func (s *Scanner) grabTLSInfo(ctx context.Context, ipAddress string, port int) *TLSInfo {
+	raw, err := s.Prober.DialTCP(ctx, ipAddress, port, bannerTimeout)
+	if err != nil {
+		return nil
+	}
+	conn := tls.Client(raw, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10}) // Inspecting the certificate, not trusting it; old versions are allowed so they can be reported
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(bannerTimeout))
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return nil
+	}
+	return describeTLS(conn.ConnectionState())
+}
+
+// describeTLS extracts the TLSInfo from a completed handshake.
+func describeTLS(state tls.ConnectionState) *TLSInfo {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	info := &TLSInfo{
+		Version:            tls.VersionName(state.Version),
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		DNSNames:           cert.DNSNames,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		SelfSigned:         isSelfSigned(cert),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType, info.KeyBits = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType, info.KeyBits = "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		info.KeyType, info.KeyBits = "Ed25519", 256
+	}
+	return info
+}
+
+// isSelfSigned reports whether a certificate is signed by its own key.
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !strings.EqualFold(cert.Subject.String(), cert.Issuer.String()) {
+		return false
+	}
+	return cert.CheckSignatureFrom(cert) == nil
+}
+
+// TLSFindings lists the problems with a service's TLS setup at the given time.
+func TLSFindings(info *TLSInfo, now time.Time) []TLSFinding {
+	if info == nil {
+		return nil
+	}
+	var findings []TLSFinding
+	add := func(severity, format string, args ...any) {
+		findings = append(findings, TLSFinding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case now.After(info.NotAfter):
+		add("high", "Certificate expired on %s", info.NotAfter.Format("2006-01-02"))
+	case now.Before(info.NotBefore):
+		add("high", "Certificate is not valid until %s", info.NotBefore.Format("2006-01-02"))
+	case info.NotAfter.Sub(now) < certExpiryWarning:
+		add("medium", "Certificate expires in %d days (%s)", int(info.NotAfter.Sub(now).Hours()/24), info.NotAfter.Format("2006-01-02"))
+	}
+	if info.SelfSigned {
+		add("medium", "Certificate is self-signed")
+	}
+	switch info.Version {
+	case "SSLv3", "TLS 1.0", "TLS 1.1":
+		add("high", "Deprecated protocol %s negotiated", info.Version)
+	}
+	if info.KeyType == "RSA" && info.KeyBits < 2048 {
+		add("high", "Weak %d-bit RSA key", info.KeyBits)
+	}
+	if strings.Contains(info.SignatureAlgorithm, "SHA1") || strings.Contains(info.SignatureAlgorithm, "MD5") {
+		add("medium", "Weak signature algorithm %s", info.SignatureAlgorithm)
+	}
+	return findings
+}
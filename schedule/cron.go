@@ -0,0 +1,167 @@
+// Package schedule parses cron-like expressions used for recurring maintenance windows.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression: minute hour day-of-month month day-of-week.
+// Fields accept "*", numbers, ranges ("1-5"), lists ("1,15") and steps ("*/15", "0-30/10").
+// Day-of-week uses 0-6 with 0 = Sunday (7 is also accepted for Sunday). As in classic cron,
+// when both day fields are restricted a time matches if either of them matches. A day field
+// starting with "*" (such as "*/2") does not count as restricted: "0 3 */2 * 1" fires on odd
+// days that are Mondays.
+type Cron struct {
+	expr    string
+	minute  [60]bool
+	hour    [24]bool
+	dom     [32]bool
+	month   [13]bool
+	dow     [7]bool
+	domStar bool
+	dowStar bool
+}
+
+// fieldSpec describes the valid range of a cron field.
+type fieldSpec struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]fieldSpec{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses a five-field cron expression.
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &Cron{expr: strings.Join(fields, " ")}
+	for i, field := range fields {
+		values, err := parseField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		for _, v := range values {
+			switch i {
+			case 0:
+				c.minute[v] = true
+			case 1:
+				c.hour[v] = true
+			case 2:
+				c.dom[v] = true
+			case 3:
+				c.month[v] = true
+			case 4:
+				c.dow[v%7] = true
+			}
+		}
+	}
+	// As in Vixie cron, a day field starting with "*" (including steps such as "*/2") makes
+	// the day fields combine with AND rather than OR
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseField expands one comma-separated field into its values.
+func parseField(field string, spec fieldSpec) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("%s: invalid step %q", spec.name, stepPart)
+			}
+			step = s
+		}
+
+		lo, hi := spec.min, spec.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil || lo > hi {
+				return nil, fmt.Errorf("%s: invalid range %q", spec.name, rangePart)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid value %q", spec.name, rangePart)
+			}
+			lo = v
+			if hasStep {
+				hi = spec.max // "5/15" means every 15 starting at 5
+			} else {
+				hi = v
+			}
+		}
+		if lo < spec.min || hi > spec.max {
+			return nil, fmt.Errorf("%s: %q out of range %d-%d", spec.name, rangePart, spec.min, spec.max)
+		}
+		for v := lo; v <= hi; v += step {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// String returns the normalised expression.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Matches reports whether t (to the minute) is a time described by the expression.
+func (c *Cron) Matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+	domMatch := c.dom[t.Day()]
+	dowMatch := c.dow[int(t.Weekday())]
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// LastStartWithin returns the most recent time in (t-d, t] matched by the expression, if any.
+// It answers "did a window of length d that starts on this schedule begin recently enough to
+// still be open at t?".
+func (c *Cron) LastStartWithin(t time.Time, d time.Duration) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	for elapsed := time.Duration(0); elapsed < d; elapsed += time.Minute {
+		candidate := t.Add(-elapsed)
+		if c.Matches(candidate) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Next returns the first time after t matched by the expression, searching up to a year ahead.
+func (c *Cron) Next(t time.Time) (time.Time, bool) {
+	candidate := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(1, 0, 0)
+	for candidate.Before(limit) {
+		if c.Matches(candidate) {
+			return candidate, true
+		}
+		candidate = candidate.Add(time.Minute)
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, c := range []struct {
+		expr    string
+		wantErr string // Substring of the error; empty if the expression is valid
+	}{
+		{"* * * * *", ""},
+		{"0 3 * * 0", ""},
+		{"  0   3 * *   7 ", ""},
+		{"*/15 9-17 1,15 1-12/3 1-5", ""},
+		{"5/20 * * * *", ""},
+		{"0 0 31 12 6", ""},
+		{"* * * *", "expected 5 fields"},
+		{"* * * * * *", "expected 5 fields"},
+		{"60 * * * *", "minute"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day of month"},
+		{"* * 32 * *", "day of month"},
+		{"* * * 13 *", "month"},
+		{"* * * * 8", "day of week"},
+		{"*/0 * * * *", "invalid step"},
+		{"*/x * * * *", "invalid step"},
+		{"10-5 * * * *", "invalid range"},
+		{"1-x * * * *", "invalid range"},
+		{"a * * * *", "invalid value"},
+		{"1,,2 * * * *", "invalid value"},
+		{"* * * jan *", "invalid value"},
+	} {
+		cron, err := ParseCron(c.expr)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("ParseCron(%q) = %v", c.expr, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("ParseCron(%q) error = %v, want one mentioning %q", c.expr, err, c.wantErr)
+		case err == nil && cron.String() != strings.Join(strings.Fields(c.expr), " "):
+			t.Errorf("ParseCron(%q).String() = %q", c.expr, cron.String())
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Sunday 1 March 2026, 10:07:30
+	from := time.Date(2026, 3, 1, 10, 7, 30, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	for _, c := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", at(3, 1, 10, 8)},
+		{"7 10 * * *", at(3, 2, 10, 7)}, // The current minute is not "after" from
+		{"*/15 * * * *", at(3, 1, 10, 15)},
+		{"5/20 * * * *", at(3, 1, 10, 25)},
+		{"0-30/10 11 * * *", at(3, 1, 11, 0)},
+		{"0 3 * * 0", at(3, 8, 3, 0)},
+		{"0 3 * * 7", at(3, 8, 3, 0)}, // 7 is Sunday too
+		{"0 9-17 * * 1-5", at(3, 2, 9, 0)},
+		{"0 0 1,15 * *", at(3, 15, 0, 0)},
+		{"0 0 1 */4 *", at(5, 1, 0, 0)},
+
+		// Both day fields restricted: either may match
+		{"0 12 15 * 3", at(3, 4, 12, 0)},   // Wednesday the 4th comes before the 15th
+		{"0 12 3 * 6", at(3, 3, 12, 0)},    // Tuesday the 3rd comes before Saturday the 7th
+		{"0 12 10 * 1,5", at(3, 2, 12, 0)}, // Monday the 2nd
+
+		// A day field starting with "*" combines with the other one using AND
+		{"0 12 */2 * 1", at(3, 9, 12, 0)}, // Mondays on odd days: the 2nd is even, the 9th is odd
+		{"0 12 * * */2", at(3, 1, 12, 0)}, // Sunday counts as day 0
+		{"0 12 5 * */3", at(4, 5, 12, 0)}, // Thursday 5 March is skipped for Sunday 5 April
+		{"0 12 1-7 * *", at(3, 1, 12, 0)},
+	} {
+		cron, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", c.expr, err)
+		}
+		got, ok := cron.Next(from)
+		if !ok || !got.Equal(c.want) {
+			t.Errorf("%q: Next = %v, %v; want %v", c.expr, got, ok, c.want)
+		}
+	}
+
+	// Nothing within a year
+	cron, _ := ParseCron("0 0 30 2 *")
+	if next, ok := cron.Next(from); ok {
+		t.Errorf("30 February: Next = %v", next)
+	}
+}
+
+func TestCronLastStartWithin(t *testing.T) {
+	cron, _ := ParseCron("0 3 * * 0") // Sundays at 03:00
+	start := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		at   time.Time
+		open bool
+	}{
+		{start.Add(-time.Minute), false},
+		{start, true},
+		{start.Add(59*time.Minute + 59*time.Second), true},
+		{start.Add(time.Hour), false},
+	} {
+		got, open := cron.LastStartWithin(c.at, time.Hour)
+		if open != c.open || (open && !got.Equal(start)) {
+			t.Errorf("LastStartWithin(%v, 1h) = %v, %v; want open %v", c.at, got, open, c.open)
+		}
+	}
+}
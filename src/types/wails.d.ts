@@ -8,6 +8,7 @@ export interface ScanHistoryItem {
   startIp: string;
   endIp: string;
   timestamp: string; // ISO string date, e.g., "2023-10-27T10:30:00Z"
+  lastScanId?: string; // ID of the latest stored result for this range, see GetScanResult
 }
 
 // This interface must match the Go struct ScanRange in scan.go
@@ -22,6 +23,8 @@ export interface WailsScanParameters {
 export interface HostStatusUpdate {
   ipAddress: string;
   isOnline: boolean;
+  status: 'online' | 'offline' | 'unreachable'; // 'unreachable': a parent host is down
+  maintenanceWindow?: string; // Name of the open maintenance window; alerts are suppressed
 }
 
 // This type should align with the main.Host struct in Go (scan.go)
@@ -30,11 +33,21 @@ export interface Host {
     ipAddress: string;
     hostname?: string;
     macAddress?: string;
+    vendor?: string;
     os?: string;
     openPorts?: number[];
+    services?: ServiceInfo[];
     deviceType?: string;
 }
 
+// Mirrors ServiceInfo in services.go: the service identified on an open port from its banner
+export interface ServiceInfo {
+    port: number;
+    name?: string;
+    version?: string;
+    banner?: string;
+}
+
 
 declare global {
   interface Window {
@@ -0,0 +1,628 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// On-disk layout: the store directory holds numbered segment files ("000001.seg", ...) and a
+// MANIFEST naming the first valid segment. Each segment is a sequence of records:
+//
+//	crc32 (4) | payload length (4) | payload
+//	payload: op (1) | timestamp ns (8) | bucket length (2) | key length (2) | value length (4) | bucket | key | value
+//
+// All integers are little-endian and the CRC covers the payload. A put is superseded by any
+// later put or delete of the same key; compaction copies only the latest puts forward.
+const (
+	segmentExt        = ".seg"
+	manifestFile      = "MANIFEST"
+	recordHeaderSize  = 8
+	payloadHeaderSize = 17
+
+	opPut    byte = 1
+	opDelete byte = 2
+
+	defaultSegmentSize = 4 << 20 // Segments roll over at 4 MiB
+	minCompactGarbage  = 1 << 20 // Compact automatically once this much is reclaimable...
+	compactGarbageRate = 0.5     // ...and it is at least half of the log
+	maxKeyLength       = 1<<16 - 1
+)
+
+// Options tune a LogStore.
+type Options struct {
+	SegmentSize int64 // Roll over to a new segment after this many bytes (default 4 MiB)
+	AutoCompact bool  // Compact automatically when at least half of the log is garbage
+}
+
+// location is where the current value of a key lives.
+type location struct {
+	segment    int
+	offset     int64 // Offset of the value within the segment file
+	valueLen   uint32
+	recordSize int64
+	timestamp  int64
+}
+
+// LogStore is the append-only, segmented implementation of Store.
+type LogStore struct {
+	mu        sync.Mutex
+	dir       string
+	opts      Options
+	index     map[string]map[string]location // bucket -> key -> location
+	retention map[string]Retention
+	segments  []int            // Segment numbers in order
+	sizes     map[int]int64    // Segment number -> file size
+	readers   map[int]*os.File // Open segment files
+	active    *os.File         // Segment being appended to
+	activeNum int
+	liveBytes int64
+	skipped   int64
+	closed    bool
+	now       func() time.Time
+}
+
+var _ Store = (*LogStore)(nil)
+
+// Open opens (or creates) the store in dir, replaying its segments to rebuild the index.
+// A torn record at the end of the last segment, left by a crash mid-write, is truncated away.
+func Open(dir string, opts Options) (*LogStore, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	s := &LogStore{
+		dir:       dir,
+		opts:      opts,
+		index:     make(map[string]map[string]location),
+		retention: make(map[string]Retention),
+		sizes:     make(map[int]int64),
+		readers:   make(map[int]*os.File),
+		now:       time.Now,
+	}
+
+	first, err := s.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(name, segmentExt))
+		if err != nil {
+			continue
+		}
+		if n < first {
+			_ = os.Remove(filepath.Join(dir, name)) // Left behind by an interrupted compaction
+			continue
+		}
+		s.segments = append(s.segments, n)
+	}
+	sort.Ints(s.segments)
+
+	for i, n := range s.segments {
+		if err := s.replay(n, i == len(s.segments)-1); err != nil {
+			s.closeFiles()
+			return nil, err
+		}
+	}
+	if len(s.segments) == 0 {
+		s.segments = []int{max(first, 1)}
+	}
+	if err := s.openActive(s.segments[len(s.segments)-1]); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	return s, nil
+}
+
+func segmentName(n int) string {
+	return fmt.Sprintf("%06d%s", n, segmentExt)
+}
+
+// readManifest returns the first valid segment number (1 if there is no manifest yet).
+func (s *LogStore) readManifest() (int, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("storage: invalid manifest: %w", err)
+	}
+	return n, nil
+}
+
+// writeManifest atomically records the first valid segment.
+func (s *LogStore) writeManifest(first int) error {
+	path := filepath.Join(s.dir, manifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(first)+"\n"), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// replay reads one segment into the index. In the last segment a bad record ends the log and
+// the file is truncated there; in earlier segments the rest of the segment is skipped.
+func (s *LogStore) replay(n int, last bool) error {
+	path := filepath.Join(s.dir, segmentName(n))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	for offset < int64(len(data)) {
+		rec, size, ok := decodeRecord(data[offset:])
+		if !ok {
+			s.skipped += int64(len(data)) - offset
+			if last {
+				if err := os.Truncate(path, offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		s.apply(rec, location{
+			segment:    n,
+			offset:     offset + recordHeaderSize + payloadHeaderSize + int64(len(rec.bucket)+len(rec.key)),
+			valueLen:   uint32(len(rec.value)),
+			recordSize: size,
+			timestamp:  rec.timestamp,
+		})
+		offset += size
+	}
+	s.sizes[n] = offset
+	return nil
+}
+
+// record is a decoded log record.
+type record struct {
+	op        byte
+	timestamp int64
+	bucket    string
+	key       string
+	value     []byte
+}
+
+// encodeRecord serialises a record.
+func encodeRecord(r record) []byte {
+	payloadLen := payloadHeaderSize + len(r.bucket) + len(r.key) + len(r.value)
+	buf := make([]byte, recordHeaderSize+payloadLen)
+	p := buf[recordHeaderSize:]
+	p[0] = r.op
+	binary.LittleEndian.PutUint64(p[1:9], uint64(r.timestamp))
+	binary.LittleEndian.PutUint16(p[9:11], uint16(len(r.bucket)))
+	binary.LittleEndian.PutUint16(p[11:13], uint16(len(r.key)))
+	binary.LittleEndian.PutUint32(p[13:17], uint32(len(r.value)))
+	n := copy(p[payloadHeaderSize:], r.bucket)
+	n += copy(p[payloadHeaderSize+n:], r.key)
+	copy(p[payloadHeaderSize+n:], r.value)
+
+	binary.LittleEndian.PutUint32(buf[0:4], crc32.ChecksumIEEE(p))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(payloadLen))
+	return buf
+}
+
+// decodeRecord parses the record at the start of data, returning its total size. It reports
+// false for a truncated or corrupt record.
+func decodeRecord(data []byte) (record, int64, bool) {
+	if len(data) < recordHeaderSize+payloadHeaderSize {
+		return record{}, 0, false
+	}
+	payloadLen := int(binary.LittleEndian.Uint32(data[4:8]))
+	if payloadLen < payloadHeaderSize || len(data)-recordHeaderSize < payloadLen {
+		return record{}, 0, false
+	}
+	p := data[recordHeaderSize : recordHeaderSize+payloadLen]
+	if crc32.ChecksumIEEE(p) != binary.LittleEndian.Uint32(data[0:4]) {
+		return record{}, 0, false
+	}
+	bucketLen := int(binary.LittleEndian.Uint16(p[9:11]))
+	keyLen := int(binary.LittleEndian.Uint16(p[11:13]))
+	valueLen := int(binary.LittleEndian.Uint32(p[13:17]))
+	if payloadHeaderSize+bucketLen+keyLen+valueLen != payloadLen {
+		return record{}, 0, false
+	}
+	rest := p[payloadHeaderSize:]
+	return record{
+		op:        p[0],
+		timestamp: int64(binary.LittleEndian.Uint64(p[1:9])),
+		bucket:    string(rest[:bucketLen]),
+		key:       string(rest[bucketLen : bucketLen+keyLen]),
+		value:     rest[bucketLen+keyLen:],
+	}, int64(recordHeaderSize + payloadLen), true
+}
+
+// apply updates the index for a record written at loc.
+func (s *LogStore) apply(r record, loc location) {
+	keys := s.index[r.bucket]
+	if old, ok := keys[r.key]; ok {
+		s.liveBytes -= old.recordSize
+	}
+	if r.op == opDelete {
+		if keys != nil {
+			delete(keys, r.key)
+			if len(keys) == 0 {
+				delete(s.index, r.bucket)
+			}
+		}
+		return
+	}
+	if keys == nil {
+		keys = make(map[string]location)
+		s.index[r.bucket] = keys
+	}
+	keys[r.key] = loc
+	s.liveBytes += loc.recordSize
+}
+
+// openActive opens segment n for appending.
+func (s *LogStore) openActive(n int) error {
+	f, err := os.OpenFile(filepath.Join(s.dir, segmentName(n)), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if old, ok := s.readers[n]; ok {
+		old.Close()
+	}
+	s.active = f
+	s.activeNum = n
+	s.readers[n] = f
+	s.sizes[n] = size
+	if len(s.segments) == 0 || s.segments[len(s.segments)-1] != n {
+		s.segments = append(s.segments, n)
+	}
+	return nil
+}
+
+// appendRecord writes a record to the active segment, rolling over first if it is full.
+func (s *LogStore) appendRecord(r record) (location, error) {
+	if s.sizes[s.activeNum] >= s.opts.SegmentSize {
+		if err := s.active.Sync(); err != nil {
+			return location{}, err
+		}
+		if err := s.openActive(s.activeNum + 1); err != nil {
+			return location{}, err
+		}
+	}
+	buf := encodeRecord(r)
+	offset := s.sizes[s.activeNum]
+	if _, err := s.active.WriteAt(buf, offset); err != nil {
+		return location{}, err
+	}
+	s.sizes[s.activeNum] = offset + int64(len(buf))
+	return location{
+		segment:    s.activeNum,
+		offset:     offset + recordHeaderSize + payloadHeaderSize + int64(len(r.bucket)+len(r.key)),
+		valueLen:   uint32(len(r.value)),
+		recordSize: int64(len(buf)),
+		timestamp:  r.timestamp,
+	}, nil
+}
+
+// Put implements Store.
+func (s *LogStore) Put(bucket, key string, value []byte) error {
+	if len(bucket) > maxKeyLength || len(key) > maxKeyLength {
+		return fmt.Errorf("storage: bucket or key too long")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	r := record{op: opPut, timestamp: s.now().UnixNano(), bucket: bucket, key: key, value: value}
+	loc, err := s.appendRecord(r)
+	if err != nil {
+		return err
+	}
+	s.apply(r, loc)
+	if err := s.enforceRetentionLocked(bucket); err != nil {
+		return err
+	}
+	return s.maybeCompactLocked()
+}
+
+// Get implements Store.
+func (s *LogStore) Get(bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	loc, ok := s.index[bucket][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.readValueLocked(loc)
+}
+
+// readValueLocked reads the value at loc. The caller must hold s.mu.
+func (s *LogStore) readValueLocked(loc location) ([]byte, error) {
+	f, ok := s.readers[loc.segment]
+	if !ok {
+		var err error
+		f, err = os.Open(filepath.Join(s.dir, segmentName(loc.segment)))
+		if err != nil {
+			return nil, err
+		}
+		s.readers[loc.segment] = f
+	}
+	value := make([]byte, loc.valueLen)
+	if _, err := f.ReadAt(value, loc.offset); err != nil {
+		return nil, fmt.Errorf("storage: reading segment %d: %w", loc.segment, err)
+	}
+	return value, nil
+}
+
+// Delete implements Store.
+func (s *LogStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	return s.deleteLocked(bucket, key)
+}
+
+// deleteLocked writes a tombstone for an existing key. The caller must hold s.mu.
+func (s *LogStore) deleteLocked(bucket, key string) error {
+	if _, ok := s.index[bucket][key]; !ok {
+		return nil
+	}
+	r := record{op: opDelete, timestamp: s.now().UnixNano(), bucket: bucket, key: key}
+	loc, err := s.appendRecord(r)
+	if err != nil {
+		return err
+	}
+	s.apply(r, loc)
+	return nil
+}
+
+// Keys implements Store.
+func (s *LogStore) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keysLocked(bucket)
+}
+
+func (s *LogStore) keysLocked(bucket string) []string {
+	keys := make([]string, 0, len(s.index[bucket]))
+	for k := range s.index[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ForEach implements Store. The store is locked for the duration, so fn must not call back into it.
+func (s *LogStore) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	for _, k := range s.keysLocked(bucket) {
+		value, err := s.readValueLocked(s.index[bucket][k])
+		if err != nil {
+			return err
+		}
+		if err := fn(k, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRetention implements Store.
+func (s *LogStore) SetRetention(bucket string, policy Retention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.retention[bucket] = policy
+	return s.enforceRetentionLocked(bucket)
+}
+
+// enforceRetentionLocked deletes the oldest entries of a bucket beyond its policy.
+// The caller must hold s.mu.
+func (s *LogStore) enforceRetentionLocked(bucket string) error {
+	policy, ok := s.retention[bucket]
+	if !ok || (policy.MaxEntries <= 0 && policy.MaxAge <= 0) {
+		return nil
+	}
+	keys := s.index[bucket]
+	var expired []string
+	if policy.MaxAge > 0 {
+		cutoff := s.now().Add(-policy.MaxAge).UnixNano()
+		for k, loc := range keys {
+			if loc.timestamp < cutoff {
+				expired = append(expired, k)
+			}
+		}
+	}
+	if policy.MaxEntries > 0 && len(keys)-len(expired) > policy.MaxEntries {
+		byAge := make([]string, 0, len(keys))
+		for k := range keys {
+			byAge = append(byAge, k)
+		}
+		sort.Slice(byAge, func(i, j int) bool { return keys[byAge[i]].timestamp < keys[byAge[j]].timestamp })
+		expired = byAge[:len(byAge)-policy.MaxEntries] // Includes any aged-out keys, which are oldest
+	}
+	for _, k := range expired {
+		if err := s.deleteLocked(bucket, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maybeCompactLocked compacts when auto-compaction is on and enough of the log is garbage.
+// The caller must hold s.mu.
+func (s *LogStore) maybeCompactLocked() error {
+	if !s.opts.AutoCompact {
+		return nil
+	}
+	total := s.totalBytesLocked()
+	garbage := total - s.liveBytes
+	if garbage < minCompactGarbage || float64(garbage) < compactGarbageRate*float64(total) {
+		return nil
+	}
+	return s.compactLocked()
+}
+
+func (s *LogStore) totalBytesLocked() int64 {
+	var total int64
+	for _, size := range s.sizes {
+		total += size
+	}
+	return total
+}
+
+// Compact implements Store.
+func (s *LogStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	return s.compactLocked()
+}
+
+// compactLocked copies the live records into new segments after the current ones, then
+// moves the manifest past the old segments and deletes them. A crash before the manifest is
+// written leaves the old segments authoritative (the copies only repeat them); a crash after
+// it leaves the new ones. The caller must hold s.mu.
+func (s *LogStore) compactLocked() error {
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	oldSegments := append([]int(nil), s.segments...)
+	first := s.activeNum + 1
+
+	type live struct {
+		bucket, key string
+		loc         location
+	}
+	var records []live
+	for bucket, keys := range s.index {
+		for key, loc := range keys {
+			records = append(records, live{bucket, key, loc})
+		}
+	}
+	// Copy in write order so retention ages stay meaningful
+	sort.Slice(records, func(i, j int) bool { return records[i].loc.timestamp < records[j].loc.timestamp })
+
+	if err := s.openActive(first); err != nil {
+		return err
+	}
+	newIndex := make(map[string]map[string]location, len(s.index))
+	var liveBytes int64
+	for _, r := range records {
+		value, err := s.readValueLocked(r.loc)
+		if err != nil {
+			return err
+		}
+		loc, err := s.appendRecord(record{op: opPut, timestamp: r.loc.timestamp, bucket: r.bucket, key: r.key, value: value})
+		if err != nil {
+			return err
+		}
+		if newIndex[r.bucket] == nil {
+			newIndex[r.bucket] = make(map[string]location)
+		}
+		newIndex[r.bucket][r.key] = loc
+		liveBytes += loc.recordSize
+	}
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	if err := s.writeManifest(first); err != nil {
+		return err
+	}
+
+	for _, n := range oldSegments {
+		if f, ok := s.readers[n]; ok {
+			f.Close()
+			delete(s.readers, n)
+		}
+		delete(s.sizes, n)
+		_ = os.Remove(filepath.Join(s.dir, segmentName(n)))
+	}
+	var segments []int
+	for _, n := range s.segments {
+		if n >= first {
+			segments = append(segments, n)
+		}
+	}
+	s.segments = segments
+	s.index = newIndex
+	s.liveBytes = liveBytes
+	return nil
+}
+
+// Stats implements Store.
+func (s *LogStore) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{
+		Segments:     len(s.segments),
+		TotalBytes:   s.totalBytesLocked(),
+		LiveBytes:    s.liveBytes,
+		Buckets:      make(map[string]int, len(s.index)),
+		SkippedBytes: s.skipped,
+	}
+	for bucket, keys := range s.index {
+		stats.Buckets[bucket] = len(keys)
+	}
+	return stats
+}
+
+// Close implements Store.
+func (s *LogStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.active != nil {
+		err = s.active.Sync()
+	}
+	s.closeFiles()
+	return err
+}
+
+func (s *LogStore) closeFiles() {
+	for n, f := range s.readers {
+		f.Close()
+		delete(s.readers, n)
+	}
+	s.active = nil
+}
@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// openTestStore opens a store in dir whose clock advances by a second on every write, so
+// retention ages are deterministic.
+func openTestStore(t *testing.T, dir string, opts Options) *LogStore {
+	t.Helper()
+	s, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func mustPut(t *testing.T, s Store, bucket, key, value string) {
+	t.Helper()
+	if err := s.Put(bucket, key, []byte(value)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wantValue checks bucket/key holds value; an empty value means the key must not exist.
+func wantValue(t *testing.T, s Store, bucket, key, value string) {
+	t.Helper()
+	got, err := s.Get(bucket, key)
+	switch {
+	case value == "" && !errors.Is(err, ErrNotFound):
+		t.Errorf("%s/%s = %q, %v; want not found", bucket, key, got, err)
+	case value != "" && (err != nil || string(got) != value):
+		t.Errorf("%s/%s = %q, %v; want %q", bucket, key, got, err, value)
+	}
+}
+
+// segmentFiles returns the segment file names in dir.
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	return names
+}
+
+func TestPutGetReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{})
+	mustPut(t, s, "scans", "b", "second")
+	mustPut(t, s, "scans", "a", "first")
+	mustPut(t, s, "scans", "a", "first, updated")
+	mustPut(t, s, "settings", "api", `{"enabled":true}`)
+	if err := s.Put("scans", string(make([]byte, maxKeyLength+1)), nil); err == nil {
+		t.Error("over-long key accepted")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("scans", "c", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close = %v, want ErrClosed", err)
+	}
+
+	s = openTestStore(t, dir, Options{})
+	wantValue(t, s, "scans", "a", "first, updated")
+	wantValue(t, s, "scans", "b", "second")
+	wantValue(t, s, "settings", "api", `{"enabled":true}`)
+	wantValue(t, s, "settings", "mqtt", "")
+	if keys := s.Keys("scans"); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("keys = %v", keys)
+	}
+	var seen []string
+	s.ForEach("scans", func(key string, value []byte) error {
+		seen = append(seen, key+"="+string(value))
+		return nil
+	})
+	if !slices.Equal(seen, []string{"a=first, updated", "b=second"}) {
+		t.Errorf("ForEach saw %v", seen)
+	}
+	if stats := s.Stats(); stats.SkippedBytes != 0 || stats.Buckets["scans"] != 2 || stats.Buckets["settings"] != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDeleteSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{})
+	mustPut(t, s, "inventory", "aa", "nas")
+	mustPut(t, s, "inventory", "bb", "printer")
+	if err := s.Delete("inventory", "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("inventory", "missing"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	wantValue(t, s, "inventory", "aa", "")
+	s.Close()
+
+	s = openTestStore(t, dir, Options{})
+	wantValue(t, s, "inventory", "aa", "")
+	wantValue(t, s, "inventory", "bb", "printer")
+	if err := s.Delete("inventory", "bb"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openTestStore(t, dir, Options{})
+	if keys := s.Keys("inventory"); len(keys) != 0 {
+		t.Errorf("keys after deleting everything = %v", keys)
+	}
+	if _, ok := s.Stats().Buckets["inventory"]; ok {
+		t.Error("empty bucket still listed")
+	}
+	// A deleted key can be written again
+	mustPut(t, s, "inventory", "aa", "nas again")
+	s.Close()
+	s = openTestStore(t, dir, Options{})
+	wantValue(t, s, "inventory", "aa", "nas again")
+}
+
+func TestReopenAfterTornOrCorruptTail(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		damage func(data []byte, lastRecord int) []byte // lastRecord is the offset of the last record
+	}{
+		{"torn record", func(data []byte, last int) []byte { return data[:len(data)-5] }},
+		{"torn header", func(data []byte, last int) []byte { return data[:last+3] }},
+		{"corrupt payload", func(data []byte, last int) []byte { data[len(data)-1] ^= 0xff; return data }},
+		{"corrupt length", func(data []byte, last int) []byte { data[last+4] = 0xff; return data }},
+		{"trailing garbage", func(data []byte, last int) []byte { return append(data, "garbage!garbage!garbage!garbage!"...) }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestStore(t, dir, Options{})
+			mustPut(t, s, "scans", "a", "kept")
+			mustPut(t, s, "scans", "b", "kept too")
+			mustPut(t, s, "scans", "c", "written while crashing")
+			s.Close()
+
+			path := filepath.Join(dir, segmentName(1))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lastRecord := len(data) - len(encodeRecord(record{op: opPut, bucket: "scans", key: "c", value: []byte("written while crashing")}))
+			if c.name == "trailing garbage" {
+				lastRecord = len(data) // Nothing of the log is lost
+			}
+			damaged := c.damage(data, lastRecord)
+			if err := os.WriteFile(path, damaged, 0640); err != nil {
+				t.Fatal(err)
+			}
+
+			s = openTestStore(t, dir, Options{})
+			if skipped := s.Stats().SkippedBytes; skipped != int64(len(damaged)-lastRecord) {
+				t.Errorf("skipped %d bytes, want %d", skipped, len(damaged)-lastRecord)
+			}
+			wantValue(t, s, "scans", "a", "kept")
+			wantValue(t, s, "scans", "b", "kept too")
+			if c.name == "trailing garbage" {
+				wantValue(t, s, "scans", "c", "written while crashing")
+			} else {
+				wantValue(t, s, "scans", "c", "")
+			}
+			if info, err := os.Stat(path); err != nil || info.Size() != int64(lastRecord) {
+				t.Errorf("segment not truncated to %d bytes: %v, %v", lastRecord, info.Size(), err)
+			}
+
+			// Writes after recovery land after the last good record and survive a clean reopen
+			mustPut(t, s, "scans", "d", "after recovery")
+			s.Close()
+			s = openTestStore(t, dir, Options{})
+			if skipped := s.Stats().SkippedBytes; skipped != 0 {
+				t.Errorf("skipped %d bytes after recovery", skipped)
+			}
+			wantValue(t, s, "scans", "b", "kept too")
+			wantValue(t, s, "scans", "d", "after recovery")
+		})
+	}
+}
+
+func TestCorruptEarlierSegment(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{SegmentSize: 1})
+	for _, key := range []string{"a", "b", "c"} {
+		mustPut(t, s, "scans", key, "value "+key)
+	}
+	s.Close()
+	if files := segmentFiles(t, dir); len(files) != 3 {
+		t.Fatalf("segments = %v, want one per record", files)
+	}
+
+	// Damage the middle segment: its record is lost, the later one is not
+	path := filepath.Join(dir, segmentName(2))
+	data, _ := os.ReadFile(path)
+	data[len(data)/2] ^= 0xff
+	os.WriteFile(path, data, 0640)
+
+	s = openTestStore(t, dir, Options{SegmentSize: 1})
+	if skipped := s.Stats().SkippedBytes; skipped != int64(len(data)) {
+		t.Errorf("skipped %d bytes, want %d", skipped, len(data))
+	}
+	wantValue(t, s, "scans", "a", "value a")
+	wantValue(t, s, "scans", "b", "")
+	wantValue(t, s, "scans", "c", "value c")
+}
+
+func TestCompactKeepsOnlyLiveRecords(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{SegmentSize: 256})
+	for i := 0; i < 20; i++ {
+		mustPut(t, s, "scans", fmt.Sprintf("scan%02d", i%5), fmt.Sprintf("version %d", i))
+	}
+	mustPut(t, s, "settings", "api", "on")
+	s.Delete("scans", "scan04")
+	before := s.Stats()
+	if before.LiveBytes >= before.TotalBytes || before.Segments < 2 {
+		t.Fatalf("nothing to compact: %+v", before)
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after := s.Stats()
+	if after.TotalBytes != after.LiveBytes || after.LiveBytes != before.LiveBytes {
+		t.Errorf("after compaction %d bytes of which %d live, want %d live bytes only", after.TotalBytes, after.LiveBytes, before.LiveBytes)
+	}
+	if after.Buckets["scans"] != 4 || after.Buckets["settings"] != 1 {
+		t.Errorf("buckets after compaction = %v", after.Buckets)
+	}
+	for _, name := range segmentFiles(t, dir) {
+		var n int
+		fmt.Sscanf(name, "%d", &n)
+		if n < 2 {
+			t.Errorf("old segment %s left behind (segments %v)", name, segmentFiles(t, dir))
+		}
+	}
+	check := func(s Store) {
+		t.Helper()
+		for i := 0; i < 4; i++ {
+			wantValue(t, s, "scans", fmt.Sprintf("scan%02d", i), fmt.Sprintf("version %d", 15+i))
+		}
+		wantValue(t, s, "scans", "scan04", "")
+		wantValue(t, s, "settings", "api", "on")
+	}
+	check(s)
+
+	// The compacted store reopens to the same contents, and keeps working
+	s.Close()
+	s = openTestStore(t, dir, Options{SegmentSize: 256})
+	check(s)
+	if stats := s.Stats(); stats.SkippedBytes != 0 || stats.TotalBytes != stats.LiveBytes {
+		t.Errorf("stats after reopening = %+v", stats)
+	}
+	mustPut(t, s, "scans", "scan04", "back")
+	wantValue(t, s, "scans", "scan04", "back")
+}
+
+func TestInterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{})
+	mustPut(t, s, "scans", "a", "old")
+	mustPut(t, s, "scans", "a", "new")
+	s.Close()
+
+	// A compaction that wrote its copy but crashed before updating the manifest leaves a
+	// later segment repeating the live records: reopening must give the same contents
+	copyRecord := encodeRecord(record{op: opPut, timestamp: 1, bucket: "scans", key: "a", value: []byte("new")})
+	os.WriteFile(filepath.Join(dir, segmentName(2)), copyRecord, 0640)
+	s = openTestStore(t, dir, Options{})
+	wantValue(t, s, "scans", "a", "new")
+	s.Close()
+
+	// Once the manifest moved on, the old segments are ignored and removed
+	os.WriteFile(filepath.Join(dir, manifestFile), []byte("2\n"), 0640)
+	s = openTestStore(t, dir, Options{})
+	wantValue(t, s, "scans", "a", "new")
+	if files := segmentFiles(t, dir); !slices.Equal(files, []string{segmentName(2)}) {
+		t.Errorf("segments = %v", files)
+	}
+}
+
+func TestAutoCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{AutoCompact: true, SegmentSize: 64 << 10})
+	value := make([]byte, 32<<10)
+	for i := 0; i < 80; i++ {
+		if err := s.Put("scans", "big", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := s.Stats(); stats.TotalBytes > 2*minCompactGarbage+stats.LiveBytes {
+		t.Errorf("log grew to %d bytes for %d live bytes", stats.TotalBytes, stats.LiveBytes)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir, Options{})
+	for i := 1; i <= 5; i++ {
+		mustPut(t, s, "scans", fmt.Sprintf("scan%d", i), "result")
+	}
+	mustPut(t, s, "scans", "scan1", "rewritten") // Now the most recently written
+
+	if err := s.SetRetention("scans", Retention{MaxEntries: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if keys := s.Keys("scans"); !slices.Equal(keys, []string{"scan1", "scan4", "scan5"}) {
+		t.Errorf("keys after applying MaxEntries 3 = %v", keys)
+	}
+	mustPut(t, s, "scans", "scan6", "result")
+	if keys := s.Keys("scans"); !slices.Equal(keys, []string{"scan1", "scan5", "scan6"}) {
+		t.Errorf("keys after another put = %v", keys)
+	}
+	mustPut(t, s, "other", "x", "unaffected")
+	mustPut(t, s, "other", "y", "unaffected")
+	if n := len(s.Keys("other")); n != 2 {
+		t.Errorf("retention applied to another bucket: %d keys", n)
+	}
+
+	// The dropped entries stay dropped after a reopen, without the policy being set again
+	s.Close()
+	s = openTestStore(t, dir, Options{})
+	if keys := s.Keys("scans"); !slices.Equal(keys, []string{"scan1", "scan5", "scan6"}) {
+		t.Errorf("keys after reopening = %v", keys)
+	}
+}
+
+func TestRetentionMaxAge(t *testing.T) {
+	s := openTestStore(t, t.TempDir(), Options{})
+	for i := 1; i <= 4; i++ {
+		mustPut(t, s, "events", fmt.Sprintf("e%d", i), "event") // One second apart
+	}
+	// The clock ticks once more for the check itself: e1 and e2 are over 2.5s old by then
+	if err := s.SetRetention("events", Retention{MaxAge: 2500 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if keys := s.Keys("events"); !slices.Equal(keys, []string{"e3", "e4"}) {
+		t.Errorf("keys after applying MaxAge = %v", keys)
+	}
+}
@@ -0,0 +1,131 @@
+// Package storage provides NetView's embedded key/value store. Values are grouped in buckets
+// and kept in an append-only, segmented log with an in-memory index, so writing one record
+// never rewrites the others. Old record versions are reclaimed by compaction, and per-bucket
+// retention policies bound how much history is kept.
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a key that does not exist.
+var ErrNotFound = errors.New("storage: key not found")
+
+// ErrClosed is returned by operations on a closed store.
+var ErrClosed = errors.New("storage: store is closed")
+
+// Store is the storage interface used by the application.
+type Store interface {
+	// Put stores value under bucket/key, replacing any previous value.
+	Put(bucket, key string, value []byte) error
+	// Get returns the value stored under bucket/key, or ErrNotFound.
+	Get(bucket, key string) ([]byte, error)
+	// Delete removes bucket/key. Deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// Keys returns the keys in a bucket in ascending order.
+	Keys(bucket string) []string
+	// ForEach calls fn for every key in a bucket in ascending order, stopping at the first error.
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+	// SetRetention sets the retention policy of a bucket and applies it.
+	SetRetention(bucket string, policy Retention) error
+	// Compact rewrites the live records into fresh segments and removes the old ones.
+	Compact() error
+	// Stats reports the size of the store.
+	Stats() Stats
+	// Close flushes and closes the store.
+	Close() error
+}
+
+// PutJSON stores v as JSON under bucket/key.
+func PutJSON(s Store, bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling %s/%s: %w", bucket, key, err)
+	}
+	return s.Put(bucket, key, data)
+}
+
+// GetJSON loads bucket/key into v.
+func GetJSON(s Store, bucket, key string, v any) error {
+	data, err := s.Get(bucket, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// NewID returns a random identifier for a new record (scans, history items, windows, ...).
+func NewID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Retention bounds the contents of a bucket. Zero values mean no limit. Entries are aged by
+// the time they were last written.
+type Retention struct {
+	MaxEntries int           `json:"maxEntries"`
+	MaxAge     time.Duration `json:"maxAge"`
+}
+
+// Stats describes the on-disk state of a store.
+type Stats struct {
+	Segments     int            `json:"segments"`
+	TotalBytes   int64          `json:"totalBytes"`   // Size of all segment files
+	LiveBytes    int64          `json:"liveBytes"`    // Bytes used by current record versions
+	Buckets      map[string]int `json:"buckets"`      // Bucket -> number of keys
+	SkippedBytes int64          `json:"skippedBytes"` // Corrupt data skipped while opening
+}
+
+// metaBucket holds the store's own bookkeeping, such as the schema version.
+const metaBucket = "_meta"
+const schemaVersionKey = "schema_version"
+
+// Migration upgrades the stored data to Version. Migrations run in order, once each.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(s Store) error
+}
+
+// SchemaVersion returns the version of the last migration applied to the store (0 if none).
+func SchemaVersion(s Store) (int, error) {
+	value, err := s.Get(metaBucket, schemaVersionKey)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(value))
+}
+
+// Migrate applies the migrations newer than the store's schema version, recording the version
+// after each one so an interrupted upgrade resumes where it stopped. It returns the versions
+// before and after.
+func Migrate(s Store, migrations []Migration) (from, to int, err error) {
+	from, err = SchemaVersion(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	to = from
+	for _, m := range migrations {
+		if m.Version <= to {
+			continue
+		}
+		if err := m.Apply(s); err != nil {
+			return from, to, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		if err := s.Put(metaBucket, schemaVersionKey, []byte(strconv.Itoa(m.Version))); err != nil {
+			return from, to, fmt.Errorf("recording schema version %d: %w", m.Version, err)
+		}
+		to = m.Version
+	}
+	return from, to, nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// testMigrations appends "v<version>" to the applied log in the "test" bucket, so a test can
+// see which migrations ran and in what order. fail makes the migration with that version fail.
+func testMigrations(fail int) []Migration {
+	var migrations []Migration
+	for version := 1; version <= 3; version++ {
+		migrations = append(migrations, Migration{
+			Version:     version,
+			Description: fmt.Sprintf("step %d", version),
+			Apply: func(s Store) error {
+				if version == fail {
+					return errors.New("disk full")
+				}
+				applied, _ := s.Get("test", "applied")
+				return s.Put("test", "applied", append(applied, fmt.Sprintf("v%d ", version)...))
+			},
+		})
+	}
+	return migrations
+}
+
+func TestMigrateFromEveryVersion(t *testing.T) {
+	for start := 0; start <= 3; start++ {
+		t.Run("from "+strconv.Itoa(start), func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestStore(t, dir, Options{})
+			if start > 0 {
+				mustPut(t, s, metaBucket, schemaVersionKey, strconv.Itoa(start))
+			}
+
+			from, to, err := Migrate(s, testMigrations(0))
+			if err != nil || from != start || to != 3 {
+				t.Fatalf("Migrate = %d, %d, %v; want %d, 3", from, to, err, start)
+			}
+			want := ""
+			for v := start + 1; v <= 3; v++ {
+				want += fmt.Sprintf("v%d ", v)
+			}
+			if applied, _ := s.Get("test", "applied"); string(applied) != want {
+				t.Errorf("applied %q, want %q", applied, want)
+			}
+
+			// Running again, before or after a reopen, changes nothing
+			for i := 0; i < 2; i++ {
+				from, to, err = Migrate(s, testMigrations(0))
+				if err != nil || from != 3 || to != 3 {
+					t.Errorf("second Migrate = %d, %d, %v", from, to, err)
+				}
+				if applied, _ := s.Get("test", "applied"); string(applied) != want {
+					t.Errorf("second Migrate applied %q, want %q", applied, want)
+				}
+				s.Close()
+				s = openTestStore(t, dir, Options{})
+			}
+			if version, err := SchemaVersion(s); err != nil || version != 3 {
+				t.Errorf("SchemaVersion = %d, %v", version, err)
+			}
+		})
+	}
+}
+
+func TestMigrateResumesAfterFailure(t *testing.T) {
+	s := openTestStore(t, t.TempDir(), Options{})
+	from, to, err := Migrate(s, testMigrations(2))
+	if err == nil || from != 0 || to != 1 {
+		t.Fatalf("Migrate = %d, %d, %v; want a failure after version 1", from, to, err)
+	}
+	if version, _ := SchemaVersion(s); version != 1 {
+		t.Errorf("schema version %d recorded after a failed migration", version)
+	}
+
+	from, to, err = Migrate(s, testMigrations(0))
+	if err != nil || from != 1 || to != 3 {
+		t.Errorf("resumed Migrate = %d, %d, %v", from, to, err)
+	}
+	if applied, _ := s.Get("test", "applied"); string(applied) != "v1 v2 v3 " {
+		t.Errorf("applied %q", applied)
+	}
+}
+
+func TestSchemaVersionInvalid(t *testing.T) {
+	s := openTestStore(t, t.TempDir(), Options{})
+	mustPut(t, s, metaBucket, schemaVersionKey, "two")
+	if _, _, err := Migrate(s, testMigrations(0)); err == nil {
+		t.Error("Migrate ran on an unreadable schema version")
+	}
+	if keys := s.Keys("test"); len(keys) != 0 {
+		t.Errorf("migrations applied: %v", keys)
+	}
+}
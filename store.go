@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"netview/history"
+	"netview/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Buckets of the application store.
+const (
+	scanResultsBucket   = "scans"          // Scan ID -> ScanResult
+	scanIndexBucket     = "scan_index"     // Scan ID -> ScanResultInfo
+	inventoryBucket     = "inventory"      // Device key -> KnownDevice
+	inventoryMetaBucket = "inventory_meta" // "settings" -> InventorySettings, "baselined" -> bool
+	settingsBucket      = "settings"       // Feature name -> settings, e.g. "export" -> ExportSettings
+)
+
+const storeDirName = "data"
+
+// storeMigrations returns the migrations that upgrade the store schema. The first imports the
+// scan history file written by earlier versions of NetView, which is then renamed with a
+// .migrated suffix. Problems are logged through ctx.
+func storeMigrations(ctx context.Context) []storage.Migration {
+	return []storage.Migration{
+		{Version: 1, Description: "import scan_history.json", Apply: func(store storage.Store) error {
+			return migrateHistoryFile(ctx, store)
+		}},
+	}
+}
+
+// openAppStore opens the application store, which holds scan history, stored scan results,
+// the device inventory and feature settings, and brings its schema up to date. Called on app
+// startup, before anything that reads from it. It returns nil if the store could not be
+// opened, in which case those features run without persistence.
+func openAppStore(ctx context.Context) storage.Store {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Data store unavailable, history and inventory will not be saved: %v", err))
+		return nil
+	}
+	store, err := storage.Open(filepath.Join(appDataDir, storeDirName), storage.Options{AutoCompact: true})
+	if err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error opening data store, history and inventory will not be saved: %v", err))
+		return nil
+	}
+	if stats := store.Stats(); stats.SkippedBytes > 0 {
+		runtime.LogWarning(ctx, fmt.Sprintf("Data store: skipped %d bytes of corrupt data while opening.", stats.SkippedBytes))
+	}
+
+	from, to, err := storage.Migrate(store, storeMigrations(ctx))
+	if err != nil {
+		// A half-migrated store would hide the data not imported yet; retry on the next launch
+		runtime.LogError(ctx, fmt.Sprintf("Data store migration failed at version %d, history and inventory will not be saved: %v", to, err))
+		if err := store.Close(); err != nil {
+			runtime.LogError(ctx, fmt.Sprintf("Error closing data store: %v", err))
+		}
+		return nil
+	}
+	if from != to {
+		runtime.LogInfo(ctx, fmt.Sprintf("Data store migrated from schema version %d to %d.", from, to))
+	}
+
+	for _, bucket := range []string{scanResultsBucket, scanIndexBucket} {
+		if err := store.SetRetention(bucket, storage.Retention{MaxEntries: maxStoredScanResults}); err != nil {
+			runtime.LogWarning(ctx, fmt.Sprintf("Could not apply retention to %s: %v", bucket, err))
+		}
+	}
+	return store
+}
+
+// closeStore flushes and closes the store on shutdown.
+func (a *App) closeStore(ctx context.Context) {
+	if a.store == nil {
+		return
+	}
+	if err := a.store.Close(); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("Error closing data store: %v", err))
+	}
+}
+
+// readLegacyFile reads a JSON file from the app data directory, returning nil data if it does
+// not exist.
+func readLegacyFile(name string) (string, []byte, error) {
+	appDataDir, err := resolveAppDataDir()
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(appDataDir, name)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return path, nil, nil
+	}
+	return path, data, err
+}
+
+// retireLegacyFile renames an imported file so it is not imported again, but kept for downgrades.
+func retireLegacyFile(path string) error {
+	return os.Rename(path, path+".migrated")
+}
+
+// migrateHistoryFile imports scan_history.json. A corrupt file is skipped (and kept) rather
+// than blocking the upgrade, matching how the file was treated when it was loaded directly.
+func migrateHistoryFile(ctx context.Context, store storage.Store) error {
+	path, data, err := readLegacyFile(historyFilename)
+	if err != nil || data == nil {
+		return err
+	}
+	var items []ScanHistoryItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		runtime.LogWarning(ctx, fmt.Sprintf("Skipping corrupt scan history file '%s': %v", path, err))
+		return nil
+	}
+	for _, item := range items {
+		if item.ID == "" {
+			item.ID = legacyHistoryID(item) // Stable, so an interrupted import can be repeated
+		}
+		if err := storage.PutJSON(store, history.Bucket, item.ID, item); err != nil {
+			return err
+		}
+	}
+	return retireLegacyFile(path)
+}
+
+// legacyHistoryID derives the ID of a history item from the file written by earlier versions,
+// which did not have IDs, in the format of storage.NewID.
+func legacyHistoryID(item ScanHistoryItem) string {
+	sum := sha256.Sum256([]byte(item.StartIP + "|" + item.EndIP + "|" + item.Timestamp.Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetStorageStats reports the size of the data store, for the settings panel.
+func (a *App) GetStorageStats() (storage.Stats, error) {
+	if a.store == nil {
+		return storage.Stats{}, fmt.Errorf("data store is not available")
+	}
+	return a.store.Stats(), nil
+}
+
+// CompactStorage rewrites the data store without superseded and deleted records.
+func (a *App) CompactStorage() (storage.Stats, error) {
+	if a.store == nil {
+		return storage.Stats{}, fmt.Errorf("data store is not available")
+	}
+	if err := a.store.Compact(); err != nil {
+		return storage.Stats{}, err
+	}
+	stats := a.store.Stats()
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Data store compacted to %d bytes in %d segments.", stats.TotalBytes, stats.Segments))
+	return stats, nil
+}